@@ -1,13 +1,19 @@
 package confidential
 
 import (
+	"bytes"
+	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"github.com/Evanesco-Labs/ristretto255"
 )
 
 type Account struct {
-	sk          *ristretto255.Scalar
+	sk          *ristretto255.Scalar //view key, decrypts the balance and incoming transfers
 	Pk          *ristretto255.Element
+	spendSk     *ristretto255.Scalar //spend key, signs SpendAuthorization
+	SpendPk     *ristretto255.Element
+	viewOnly    bool
 	basePoint   *ristretto255.Element
 	xof         XofExpend
 	Comm        *Commitment
@@ -15,27 +21,91 @@ type Account struct {
 	GList       []*ristretto255.Element
 	HList       []*ristretto255.Element
 	rangeProver *RangeProver
+	//constantTime selects constant-time scalar multiplication and decryption for secret values
+	constantTime bool
+	upper        uint64
+	//last decrypted balance and the commitment it belongs to
+	cachedComm    []byte
+	cachedBalance uint64
 }
 
 func (acc *Account) Init(seed [32]byte) {
 	acc.xof = NewXofExpend(64, seed)
 	buf := make([]byte, 64)
 	acc.xof.Read(buf)
-	randSeed := sha256.Sum256([]byte(rangeProverXofSeed))
-	acc.rangeProver, _ = NewRangeProver(32, randSeed)
+	acc.setKey(new(ristretto255.Scalar).FromUniformBytes(buf))
+	acc.setSpendKey(deriveSpendKey(seed[:]))
+	zero := new(ristretto255.Scalar).Zero()
+	_, comm := acc.Commit(zero)
+	acc.Comm = &comm
+	acc.PubBalance = uint64(0)
+}
+
+//setKey draws the seed of the range prover from acc.xof, which has to be seeded first. A seed shared by
+//all accounts would let anyone replay the blinding vectors of a range proof and read the amount from it.
+func (acc *Account) setKey(sk *ristretto255.Scalar) {
+	var randSeed [32]byte
+	acc.xof.Read(randSeed[:])
+	acc.rangeProver, _ = NewRangeProver(RANGEBITS, randSeed)
 	acc.basePoint = DeepCopyElement(acc.rangeProver.G)
-	acc.sk = new(ristretto255.Scalar).FromUniformBytes(buf)
-	acc.Pk = new(ristretto255.Element).ScalarMultWnaf(acc.sk, acc.basePoint)
+	acc.sk = sk
+	acc.Pk = new(ristretto255.Element).ScalarMult(acc.sk, acc.basePoint)
+	acc.spendSk, acc.SpendPk = nil, nil
+	acc.viewOnly = false
+	acc.upper = Upper
+}
+
+//ExportSk returns the canonical encoding of the secret key
+func (acc *Account) ExportSk() [32]byte {
+	return ScalarToBytes(acc.sk)
+}
+
+//ImportSk recreates an account from an exported secret key. The nonce generator is seeded from the key
+//and fresh system randomness, so nonces of the previous session are never reused. Comm is reset to a
+//commitment of zero and should be replaced with the ledger state.
+func (acc *Account) ImportSk(b [32]byte) error {
+	sk, err := ScalarFromBytes(b)
+	if err != nil {
+		return err
+	}
+	if sk.Equal(new(ristretto255.Scalar).Zero()) == 1 {
+		return errors.New("secret key is zero")
+	}
+	entropy := make([]byte, 32)
+	if _, err := rand.Read(entropy); err != nil {
+		return err
+	}
+	acc.xof = NewXofExpend(64, sha256.Sum256(append(b[:], entropy...)))
+	acc.setKey(sk)
 	zero := new(ristretto255.Scalar).Zero()
 	_, comm := acc.Commit(zero)
 	acc.Comm = &comm
 	acc.PubBalance = uint64(0)
+	return nil
 }
 
 func (acc *Account) GetSk() *ristretto255.Scalar {
 	return acc.sk
 }
 
+//SetConstantTime switches the account into side-channel-hardened mode. Every scalar multiplication on
+//secret keys, blinding values and amounts then runs in constant time, and balance decryption scans all
+//values below the decryption bound instead of stopping at the secret balance, so its cost grows linearly
+//with the bound, see SetDecryptionBound.
+func (acc *Account) SetConstantTime(enable bool) {
+	acc.constantTime = enable
+	acc.rangeProver.ConstantTime = enable
+}
+
+//SetDecryptionBound sets the exclusive upper bound of decrypted balances and amounts, Upper by default
+func (acc *Account) SetDecryptionBound(upper uint64) {
+	acc.upper = upper
+}
+
+func (acc *Account) mult(s *ristretto255.Scalar, p *ristretto255.Element) *ristretto255.Element {
+	return ScalarMultSelect(acc.constantTime, s, p)
+}
+
 func (acc *Account) Deposit(amount uint64) {
 	v, _ := InttoScalar(amount)
 	_, comm := acc.Commit(v)
@@ -47,9 +117,9 @@ func (acc *Account) Commit(v *ristretto255.Scalar) (*ristretto255.Scalar, Commit
 	randomBytes := make([]byte, 64)
 	acc.xof.Read(randomBytes)
 	r := new(ristretto255.Scalar).FromUniformBytes(randomBytes)
-	cl := new(ristretto255.Element).Add(new(ristretto255.Element).ScalarMultWnaf(v, acc.basePoint),
-		new(ristretto255.Element).ScalarMultWnaf(r, acc.Pk))
-	cr := new(ristretto255.Element).ScalarMultWnaf(r, acc.basePoint)
+	cl := new(ristretto255.Element).Add(acc.mult(v, acc.basePoint),
+		acc.mult(r, acc.Pk))
+	cr := acc.mult(r, acc.basePoint)
 	comm := Commitment{
 		Cl: cl,
 		Cr: cr,
@@ -58,15 +128,30 @@ func (acc *Account) Commit(v *ristretto255.Scalar) (*ristretto255.Scalar, Commit
 }
 
 func (acc *Account) GetCommitmentBalance() *ristretto255.Scalar {
+	commText := acc.Comm.Encode()
+	if acc.cachedComm != nil && bytes.Equal(commText, acc.cachedComm) {
+		v, _ := InttoScalar(acc.cachedBalance)
+		return v
+	}
 	vEncrypt := new(ristretto255.Element).Add(acc.Comm.Cl,
-		new(ristretto255.Element).Negate(new(ristretto255.Element).ScalarMultWnaf(acc.sk, acc.Comm.Cr)))
-	return GuessValue(vEncrypt, acc.basePoint, Upper)
+		new(ristretto255.Element).Negate(acc.mult(acc.sk, acc.Comm.Cr)))
+	var v *ristretto255.Scalar
+	if acc.constantTime {
+		v = GuessValueConstantTime(vEncrypt, acc.basePoint, acc.upper)
+	} else {
+		v = GuessValue(vEncrypt, acc.basePoint, acc.upper)
+	}
+	if v != nil {
+		acc.cachedComm = commText
+		acc.cachedBalance = ScalartoInt(v)
+	}
+	return v
 }
 
 func (acc *Account) GenDepositProof(v uint64, comm Commitment) CommitmentProof {
 	ksk := acc.RandScalar()
-	Ay := new(ristretto255.Element).ScalarMultWnaf(ksk, acc.basePoint)
-	Acr := new(ristretto255.Element).ScalarMultWnaf(ksk, comm.Cr)
+	Ay := acc.mult(ksk, acc.basePoint)
+	Acr := acc.mult(ksk, comm.Cr)
 	AyBytes := Ay.Encode(nil)
 	AcrBytes := Acr.Encode(nil)
 
@@ -89,8 +174,8 @@ func (acc *Account) GenDepositProof(v uint64, comm Commitment) CommitmentProof {
 
 func (acc *Account) GenBurnProof() CommitmentProof {
 	ksk := acc.RandScalar()
-	Ay := new(ristretto255.Element).ScalarMultWnaf(ksk, acc.basePoint)
-	Acr := new(ristretto255.Element).ScalarMultWnaf(ksk, acc.Comm.Cr)
+	Ay := acc.mult(ksk, acc.basePoint)
+	Acr := acc.mult(ksk, acc.Comm.Cr)
 	AyBytes := Ay.Encode(nil)
 	AcrBytes := Acr.Encode(nil)
 
@@ -116,7 +201,20 @@ func (acc *Account) RandScalar() *ristretto255.Scalar {
 	return new(ristretto255.Scalar).FromUniformBytes(buf)
 }
 
-func (acc *Account) GenTransferProof(trans [64]byte, amount uint64, yPrime *ristretto255.Element) (*TransferProof, error) {
+//GenTransferProof transfers amount to yPrime, the amount is also encrypted under every auditor public key
+func (acc *Account) GenTransferProof(trans [64]byte, amount uint64, yPrime *ristretto255.Element, auditors ...*ristretto255.Element) (*TransferProof, error) {
+	if acc.viewOnly {
+		return nil, ErrViewOnly
+	}
+	if len(auditors) > MaxAuditors {
+		return nil, ErrTooManyAuditors
+	}
+	return acc.genTransferProof(trans, amount, yPrime, acc.Comm, ScalartoInt(acc.GetCommitmentBalance()), auditors)
+}
+
+//genTransferProof proves the transfer of amount out of comm, which encrypts accBalance under acc.Pk
+func (acc *Account) genTransferProof(trans [64]byte, amount uint64, yPrime *ristretto255.Element, comm *Commitment,
+	accBalance uint64, auditors []*ristretto255.Element) (*TransferProof, error) {
 	b, err := InttoScalar(amount)
 	if err != nil {
 		return nil, err
@@ -124,15 +222,14 @@ func (acc *Account) GenTransferProof(trans [64]byte, amount uint64, yPrime *rist
 	r, cComm := acc.Commit(b)
 	c := cComm.Cl
 	d := cComm.Cr
-	cPrime := new(ristretto255.Element).Add(new(ristretto255.Element).ScalarMultWnaf(b, acc.basePoint),
-		new(ristretto255.Element).ScalarMultWnaf(r, yPrime))
+	cPrime := new(ristretto255.Element).Add(acc.mult(b, acc.basePoint),
+		acc.mult(r, yPrime))
 	cPrimeCommiment := Commitment{
 		Cl: cPrime,
 		Cr: cComm.Cr,
 	}
-	clNew := new(ristretto255.Element).Add(acc.Comm.Cl, new(ristretto255.Element).Negate(c))
-	crNew := new(ristretto255.Element).Add(acc.Comm.Cr, new(ristretto255.Element).Negate(d))
-	accBalance := ScalartoInt(acc.GetCommitmentBalance())
+	clNew := new(ristretto255.Element).Add(comm.Cl, new(ristretto255.Element).Negate(c))
+	crNew := new(ristretto255.Element).Add(comm.Cr, new(ristretto255.Element).Negate(d))
 	bPrime, err := InttoScalar(accBalance - amount)
 	if err != nil {
 		return nil, err
@@ -163,8 +260,8 @@ func (acc *Account) GenTransferProof(trans [64]byte, amount uint64, yPrime *rist
 	kb := acc.RandScalar()
 	ktau := acc.RandScalar()
 
-	ay := new(ristretto255.Element).ScalarMultWnaf(ksk, acc.basePoint)
-	ad := new(ristretto255.Element).ScalarMultWnaf(kr, acc.basePoint)
+	ay := acc.mult(ksk, acc.basePoint)
+	ad := acc.mult(kr, acc.basePoint)
 	zz := new(ristretto255.Scalar).Multiply(z, z)
 	zzz := new(ristretto255.Scalar).Multiply(zz, z)
 	kskzz := new(ristretto255.Scalar).Multiply(ksk, zz)
@@ -172,15 +269,27 @@ func (acc *Account) GenTransferProof(trans [64]byte, amount uint64, yPrime *rist
 	//ab := SumElements(new(ristretto255.Element).ScalarMultWnaf(kb, acc.basePoint),
 	//	new(ristretto255.Element).ScalarMultWnaf(new(ristretto255.Scalar).Negate(kskzz), d),
 	//	new(ristretto255.Element).ScalarMultWnaf(new(ristretto255.Scalar).Negate(kskzzz), crNew))
-	ab := SumElements(new(ristretto255.Element).ScalarMultWnaf(kb, acc.basePoint),
-		new(ristretto255.Element).ScalarMultWnaf(kskzz, d),
-		new(ristretto255.Element).ScalarMultWnaf(kskzzz, crNew))
-	ayPrime := new(ristretto255.Element).ScalarMultWnaf(kr,
+	ab := SumElements(acc.mult(kb, acc.basePoint),
+		acc.mult(kskzz, d),
+		acc.mult(kskzzz, crNew))
+	ayPrime := acc.mult(kr,
 		new(ristretto255.Element).Add(acc.Pk, new(ristretto255.Element).Negate(yPrime)))
 	at := new(ristretto255.Element).Add(
-		new(ristretto255.Element).ScalarMultWnaf(new(ristretto255.Scalar).Negate(kb), acc.basePoint),
-		new(ristretto255.Element).ScalarMultWnaf(ktau, acc.rangeProver.H))
-	trans, challenge := UpdateTranscript(trans, ay, ad, ab, ayPrime, at)
+		acc.mult(new(ristretto255.Scalar).Negate(kb), acc.basePoint),
+		acc.mult(ktau, acc.rangeProver.H))
+	auditorCiphertexts := make([]AuditorCiphertext, len(auditors))
+	for i, auditor := range auditors {
+		auditorCiphertexts[i] = AuditorCiphertext{
+			Auditor: auditor,
+			Cl:      new(ristretto255.Element).Add(acc.mult(b, acc.basePoint), acc.mult(r, auditor)),
+			ae:      acc.mult(kr, new(ristretto255.Element).Add(acc.Pk, new(ristretto255.Element).Negate(auditor))),
+		}
+	}
+	if len(auditors) == 0 {
+		auditorCiphertexts = nil
+	}
+	trans, challenge := UpdateTranscript(trans, append([]*ristretto255.Element{ay, ad, ab, ayPrime, at},
+		auditorTranscript(auditorCiphertexts)...)...)
 	ssk := new(ristretto255.Scalar).Add(ksk, Mul(challenge, acc.sk))
 	sr := new(ristretto255.Scalar).Add(kr, Mul(challenge, r))
 	sb := new(ristretto255.Scalar).Add(kb, Mul(challenge, SumScalars(Mul(b, zz), Mul(bPrime, zzz))))
@@ -198,11 +307,15 @@ func (acc *Account) GenTransferProof(trans [64]byte, amount uint64, yPrime *rist
 		stau:            stau,
 		CComm:           cComm,
 		CPrimeComm:      cPrimeCommiment,
+		Auditors:        auditorCiphertexts,
 	}, nil
 
 }
 
 func (acc *Account) GenWithdrawProof(trans [64]byte, amount uint64) (*WithdrawProof, error) {
+	if acc.viewOnly {
+		return nil, ErrViewOnly
+	}
 	balance := acc.GetCommitmentBalance()
 	b, _ := InttoScalar(amount)
 	bNew := new(ristretto255.Scalar).Add(balance, new(ristretto255.Scalar).Negate(b))
@@ -224,9 +337,9 @@ func (acc *Account) GenWithdrawProof(trans [64]byte, amount uint64) (*WithdrawPr
 	kr := acc.RandScalar()
 	ksk := acc.RandScalar()
 
-	ay := new(ristretto255.Element).ScalarMultWnaf(kr, acc.Pk)
-	ad := new(ristretto255.Element).ScalarMultWnaf(ksk, commWD.Cr)
-	ag := new(ristretto255.Element).ScalarMultWnaf(kr, acc.basePoint)
+	ay := acc.mult(kr, acc.Pk)
+	ad := acc.mult(ksk, commWD.Cr)
+	ag := acc.mult(kr, acc.basePoint)
 
 	trans, challenge := UpdateTranscript(trans, ad, ay, ag)
 
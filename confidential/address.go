@@ -0,0 +1,225 @@
+package confidential
+
+import (
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+	"strings"
+)
+
+//Addresses are bech32m strings (BIP-350). The human-readable part names the network and the kind of
+//key, the data part is the 32 bytes encoding of the account public key, or ViewPk||SpendPk for a
+//stealth address. Stealth addresses are longer than the 90 characters of BIP-173, the limit is raised
+//to maxAddressLength. Decoding checks the checksum and that every point is canonical and not the identity.
+
+type Network string
+
+const (
+	Mainnet Network = "xv"
+	Testnet Network = "txv"
+)
+
+//DefaultNetwork is the network of the addresses written to JSON
+var DefaultNetwork = Mainnet
+
+//stealth addresses append stealthHRPSuffix to the network prefix
+const stealthHRPSuffix = "s"
+
+const maxAddressLength = 128
+
+var (
+	ErrAddressChecksum = errors.New("address checksum is invalid")
+	ErrAddressFormat   = errors.New("address is not a valid bech32m string")
+	ErrAddressNetwork  = errors.New("address belongs to an unknown network")
+	ErrAddressKind     = errors.New("address is of another kind")
+	ErrWrongNetwork    = errors.New("address belongs to another network")
+	ErrIdentityAddress = errors.New("address encodes the identity")
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const bech32mConst = 0x2bc830a3
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+func bech32mChecksum(hrp string, data []byte) []byte {
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ bech32mConst
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(polymod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+//convertBits regroups 8 bit bytes into 5 bit groups and back, decoding rejects non-zero padding
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<to - 1
+	var out []byte
+	for _, v := range data {
+		if uint32(v)>>from != 0 {
+			return nil, ErrAddressFormat
+		}
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, ErrAddressFormat
+	}
+	return out, nil
+}
+
+func encodeBech32m(hrp string, payload []byte) string {
+	data, _ := convertBits(payload, 8, 5, true)
+	data = append(data, bech32mChecksum(hrp, data)...)
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, d := range data {
+		sb.WriteByte(bech32Charset[d])
+	}
+	return sb.String()
+}
+
+func decodeBech32m(s string) (string, []byte, error) {
+	if len(s) > maxAddressLength || (strings.ToLower(s) != s && strings.ToUpper(s) != s) {
+		return "", nil, ErrAddressFormat
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, ErrAddressFormat
+	}
+	hrp := s[:pos]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, ErrAddressFormat
+		}
+	}
+	data := make([]byte, 0, len(s)-pos-1)
+	for i := pos + 1; i < len(s); i++ {
+		d := strings.IndexByte(bech32Charset, s[i])
+		if d < 0 {
+			return "", nil, ErrAddressFormat
+		}
+		data = append(data, byte(d))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), data...)) != bech32mConst {
+		return "", nil, ErrAddressChecksum
+	}
+	payload, err := convertBits(data[:len(data)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, payload, nil
+}
+
+func parseHRP(hrp string) (network Network, stealth bool, err error) {
+	for _, n := range []Network{Mainnet, Testnet} {
+		switch hrp {
+		case string(n):
+			return n, false, nil
+		case string(n) + stealthHRPSuffix:
+			return n, true, nil
+		}
+	}
+	return "", false, ErrAddressNetwork
+}
+
+//decodeAddress returns the network and the points of an address, stealth selects the kind
+func decodeAddress(s string, stealth bool, points int) (Network, []*ristretto255.Element, error) {
+	hrp, payload, err := decodeBech32m(s)
+	if err != nil {
+		return "", nil, err
+	}
+	network, isStealth, err := parseHRP(hrp)
+	if err != nil {
+		return "", nil, err
+	}
+	if isStealth != stealth {
+		return "", nil, ErrAddressKind
+	}
+	if len(payload) != 32*points {
+		return "", nil, ErrAddressFormat
+	}
+	identity := new(ristretto255.Element).Zero()
+	elements := make([]*ristretto255.Element, points)
+	for i := range elements {
+		var buf [32]byte
+		copy(buf[:], payload[32*i:])
+		if elements[i], err = ElementFromBytes(buf); err != nil {
+			return "", nil, err
+		}
+		if elements[i].Equal(identity) == 1 {
+			return "", nil, ErrIdentityAddress
+		}
+	}
+	return network, elements, nil
+}
+
+//EncodeAddress returns the address of an account public key
+func EncodeAddress(network Network, pk *ristretto255.Element) string {
+	return encodeBech32m(string(network), pk.Encode(nil))
+}
+
+//DecodeAddress returns the account public key of an address and its network
+func DecodeAddress(s string) (Network, *ristretto255.Element, error) {
+	network, elements, err := decodeAddress(s, false, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	return network, elements[0], nil
+}
+
+//Address returns the address of the account on network
+func (acc *Account) Address(network Network) string {
+	return EncodeAddress(network, acc.Pk)
+}
+
+//Encode returns the bech32m form of the stealth address
+func (addr *StealthAddress) Encode(network Network) string {
+	payload := append(addr.ViewPk.Encode(nil), addr.SpendPk.Encode(nil)...)
+	return encodeBech32m(string(network)+stealthHRPSuffix, payload)
+}
+
+//DecodeStealthAddress returns the stealth address and its network
+func DecodeStealthAddress(s string) (Network, *StealthAddress, error) {
+	network, elements, err := decodeAddress(s, true, 2)
+	if err != nil {
+		return "", nil, err
+	}
+	return network, &StealthAddress{ViewPk: elements[0], SpendPk: elements[1]}, nil
+}
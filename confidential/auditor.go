@@ -0,0 +1,147 @@
+package confidential
+
+import (
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Auditor ciphertexts. A transfer can encrypt its amount b under auditor public keys A with the
+//randomness r of CComm, so Cl = b*G + r*A and the Cr is CComm.Cr. Equality with CComm follows from
+//CComm.Cl - Cl = r*(y - A), proven with the same response sr as the ad = kr*G check of the transfer.
+
+//MaxAuditors bounds the auditor ciphertexts of one transfer
+const MaxAuditors = 8
+
+var ErrTooManyAuditors = errors.New("too many auditors")
+
+type AuditorCiphertext struct {
+	Auditor *ristretto255.Element //auditor public key
+	Cl      *ristretto255.Element
+	ae      *ristretto255.Element //kr*(y - Auditor)
+}
+
+func (ct *AuditorCiphertext) Serialization(sink *ZeroCopySink) {
+	sink.WriteFixedElement(ct.Auditor)
+	sink.WriteFixedElement(ct.Cl)
+	sink.WriteFixedElement(ct.ae)
+}
+
+func (ct *AuditorCiphertext) Deserialization(source *ZeroCopySource) error {
+	return nextFixedElements(source, &ct.Auditor, &ct.Cl, &ct.ae)
+}
+
+const auditorCiphertextSize = 3 * 32
+
+//serializeAuditors writes nothing without auditors, so unaudited transfers keep their encoding
+func serializeAuditors(sink *ZeroCopySink, auditors []AuditorCiphertext) {
+	if len(auditors) == 0 {
+		return
+	}
+	sink.WriteUint8(uint8(len(auditors)))
+	for i := range auditors {
+		auditors[i].Serialization(sink)
+	}
+}
+
+func deserializeAuditors(source *ZeroCopySource) ([]AuditorCiphertext, error) {
+	if source.Len() == 0 {
+		return nil, nil
+	}
+	n, eof := source.NextByte()
+	if eof {
+		return nil, ErrProofLength
+	}
+	if n == 0 || n > MaxAuditors {
+		return nil, ErrTooManyAuditors
+	}
+	if source.Len() < uint64(n)*auditorCiphertextSize {
+		return nil, ErrProofLength
+	}
+	auditors := make([]AuditorCiphertext, n)
+	for i := range auditors {
+		if err := auditors[i].Deserialization(source); err != nil {
+			return nil, err
+		}
+	}
+	return auditors, nil
+}
+
+//auditorTranscript lists the elements appended to the transfer transcript, it is empty without auditors
+func auditorTranscript(auditors []AuditorCiphertext) []*ristretto255.Element {
+	var elements []*ristretto255.Element
+	for _, ct := range auditors {
+		elements = append(elements, ct.Auditor, ct.Cl, ct.ae)
+	}
+	return elements
+}
+
+//verifyAuditors checks sr*(y - A) = ae + c*(CComm.Cl - Cl) for every auditor ciphertext
+func (sc *SmartContract) verifyAuditors(proof *TransferProof, y *ristretto255.Element, challenge *ristretto255.Scalar) bool {
+	if len(proof.Auditors) > MaxAuditors {
+		return false
+	}
+	identity := new(ristretto255.Element).Zero()
+	for _, ct := range proof.Auditors {
+		if ct.Auditor.Equal(identity) == 1 {
+			return false
+		}
+		left := new(ristretto255.Element).ScalarMultWnaf(proof.sr,
+			new(ristretto255.Element).Add(y, new(ristretto255.Element).Negate(ct.Auditor)))
+		tmp := new(ristretto255.Element).Add(proof.CComm.Cl, new(ristretto255.Element).Negate(ct.Cl))
+		right := new(ristretto255.Element).Add(ct.ae, new(ristretto255.Element).ScalarMultWnaf(challenge, tmp))
+		if left.Equal(right) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+//RegisterAuditor allows pk as auditor in VerifyAuditedTransferProof
+func (sc *SmartContract) RegisterAuditor(pk *ristretto255.Element) {
+	var key [32]byte
+	copy(key[:], pk.Encode(nil))
+	sc.AuditorMap[key] = true
+}
+
+func (sc *SmartContract) isAuditor(pk *ristretto255.Element) bool {
+	var key [32]byte
+	copy(key[:], pk.Encode(nil))
+	return sc.AuditorMap[key]
+}
+
+//VerifyAuditedTransferProof verifies a transfer that must carry ciphertexts for exactly the given
+//registered auditors, in order. auth is required if the sender registered a spend key and may be nil otherwise.
+func (sc *SmartContract) VerifyAuditedTransferProof(trans [64]byte, proof *TransferProof, auth *SpendAuthorization,
+	y, yPrime *ristretto255.Element, auditors []*ristretto255.Element) bool {
+	if proof == nil || len(proof.Auditors) != len(auditors) {
+		return false
+	}
+	for i, auditor := range auditors {
+		if !sc.isAuditor(auditor) || proof.Auditors[i].Auditor == nil || proof.Auditors[i].Auditor.Equal(auditor) != 1 {
+			return false
+		}
+	}
+	if sc.getSpendKey(y) != nil {
+		return sc.VerifyAuthorizedTransferProof(trans, proof, auth, y, yPrime)
+	}
+	return sc.VerifyTransferProof(trans, proof, y, yPrime)
+}
+
+//AuditorCommitment returns the ciphertext of the amount under auditor, as a Commitment for ThresholdKey.Combine
+func (proof *TransferProof) AuditorCommitment(auditor *ristretto255.Element) (*Commitment, error) {
+	for _, ct := range proof.Auditors {
+		if ct.Auditor.Equal(auditor) == 1 {
+			return &Commitment{Cl: ct.Cl, Cr: proof.CComm.Cr}, nil
+		}
+	}
+	return nil, errors.New("transfer has no ciphertext for this auditor")
+}
+
+//AuditTransfer decrypts the amount of a transfer audited by this account
+func (acc *Account) AuditTransfer(proof *TransferProof) (uint64, error) {
+	comm, err := proof.AuditorCommitment(acc.Pk)
+	if err != nil {
+		return 0, err
+	}
+	return acc.decryptAmount(comm.Cl, comm.Cr)
+}
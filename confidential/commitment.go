@@ -27,8 +27,16 @@ func (comm *Commitment) Decode(b []byte) error {
 	copy(clEncoded[:], b[:32])
 	copy(crEncoded[:], b[32:])
 
-	comm.Cl = ElementFromBytes(clEncoded)
-	comm.Cr = ElementFromBytes(crEncoded)
+	cl, err := ElementFromBytes(clEncoded)
+	if err != nil {
+		return err
+	}
+	cr, err := ElementFromBytes(crEncoded)
+	if err != nil {
+		return err
+	}
+	comm.Cl = cl
+	comm.Cr = cr
 	return nil
 }
 
@@ -67,6 +75,26 @@ func GuessValue(vEncrypt *ristretto255.Element, base *ristretto255.Element, uppe
 	return nil
 }
 
+//GuessValueConstantTime works like GuessValue but always walks through every candidate below upper,
+//so the running time does not depend on the decrypted value.
+func GuessValueConstantTime(vEncrypt *ristretto255.Element, base *ristretto255.Element, upper uint64) *ristretto255.Scalar {
+	vGuess := new(ristretto255.Element).Zero()
+	found := uint64(0)
+	value := uint64(0)
+	for i := uint64(0); i < upper; i++ {
+		eq := uint64(vGuess.Equal(vEncrypt))
+		mask := -(eq &^ found)
+		value = (value &^ mask) | (i & mask)
+		found |= eq
+		vGuess = new(ristretto255.Element).Add(vGuess, base)
+	}
+	if found == 0 {
+		return nil
+	}
+	v, _ := InttoScalar(value)
+	return v
+}
+
 //func GuessValue(vEncrypt *ristretto255.Element, base *ristretto255.Element, upper uint64) *ristretto255.Scalar {
 //
 //	for i := uint64(0); i < upper; i++ {
@@ -1,29 +1,121 @@
 package confidential
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"github.com/Evanesco-Labs/ristretto255"
 	"io"
+	"math/bits"
 )
 
+var (
+	ErrInvalidScalar  = errors.New("invalid scalar encoding")
+	ErrInvalidElement = errors.New("invalid element encoding")
+	ErrTrailingBytes  = errors.New("trailing bytes after proof")
+	ErrProofLength    = errors.New("proof length does not match the round count")
+)
+
+//MaxRangeBits is the largest N of a RangeProver, MaxAggregation the most values one range proof
+//aggregates, the balance and the amounts of a MultiTransferProof with MaxRecipients recipients
+const (
+	MaxRangeBits   = 64
+	MaxAggregation = 8
+)
+
+//log2 of the longest aggregated vector, MaxRangeBits*MaxAggregation bits
+var maxInnerProductRounds = bits.TrailingZeros64(MaxRangeBits * MaxAggregation)
+
+//Codec is the binary codec implemented by every proof type, Serialize and Deserialize wrap it
+//for a standalone byte slice.
+type Codec interface {
+	Serialization(sink *ZeroCopySink)
+	Deserialization(source *ZeroCopySource) error
+}
+
+func serialize(codec Codec) []byte {
+	sink := NewZeroCopySink(nil)
+	codec.Serialization(sink)
+	return sink.Bytes()
+}
+
+func deserialize(codec Codec, b []byte) error {
+	source := NewZeroCopySource(b)
+	if err := codec.Deserialization(source); err != nil {
+		return err
+	}
+	if source.Len() != 0 {
+		return ErrTrailingBytes
+	}
+	return nil
+}
+
 type InnerProductProof struct {
 	iteration int32
 	Ls, Rs    []*ristretto255.Element
 	a, b      *ristretto255.Scalar
 }
 
+// iteration||a||b||Ls||Rs
+func (self *InnerProductProof) Serialization(sink *ZeroCopySink) {
+	sink.WriteInt32(self.iteration)
+	self.serializeCompact(sink)
+}
+
+//Deserialization accepts any round count up to maxInnerProductRounds, the bytes for all
+//rounds have to be available before any point is decoded
+func (self *InnerProductProof) Deserialization(source *ZeroCopySource) error {
+	iteration, eof := source.NextInt32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if iteration < 0 || int(iteration) > maxInnerProductRounds {
+		return ErrProofLength
+	}
+	n := uint64(1) << uint(iteration)
+	if source.Len() < uint64(compactInnerProductSize(n)) {
+		return ErrProofLength
+	}
+	return self.deserializeCompact(source, n)
+}
+
 func (self *InnerProductProof) Serialize() []byte {
-	buf := new(bytes.Buffer)
-	writeProof(buf, self)
-	return buf.Bytes()
+	return serialize(self)
 }
 
 func (self *InnerProductProof) Deserialize(b []byte) error {
-	buf := new(bytes.Buffer)
-	buf.Write(b)
-	return readProof(buf, self)
+	return deserialize(self, b)
+}
+
+//DeserializeUpTo accepts a proof for any power of two length up to maxN, the round count it carries
+//has to match the length of b exactly. The parser does not know the N of the verifier, VerifyRangeProof
+//and VerifySigmaRangeProof reject a proof whose round count is not exactly log2 of their vector length.
+func (self *InnerProductProof) DeserializeUpTo(b []byte, maxN uint64) error {
+	maxRounds, err := InnerProductRounds(maxN)
+	if err != nil {
+		return err
+	}
+	source := NewZeroCopySource(b)
+	iteration, eof := source.NextInt32()
+	if eof || iteration < 0 || int(iteration) > maxRounds || len(b) != innerProductProofSize(int(iteration)) {
+		return ErrProofLength
+	}
+	return self.deserializeCompact(source, uint64(1)<<uint(iteration))
+}
+
+//InnerProductRounds returns the number of halving rounds for vectors of length n, n = RangeProver.N * aggregation size
+func InnerProductRounds(n uint64) (int, error) {
+	if n == 0 || n&(n-1) != 0 {
+		return 0, errors.New("inner product length must be a power of 2")
+	}
+	rounds := bits.TrailingZeros64(n)
+	if rounds > maxInnerProductRounds {
+		return 0, ErrProofLength
+	}
+	return rounds, nil
+}
+
+//iteration, a, b, then one L and one R per round
+func innerProductProofSize(rounds int) int {
+	return 4 + 32*2 + 32*2*rounds
 }
 
 func ScalarToBytes(i *ristretto255.Scalar) [32]byte {
@@ -33,13 +125,13 @@ func ScalarToBytes(i *ristretto255.Scalar) [32]byte {
 	return result
 }
 
-func ScalarFromBytes(b [32]byte) *ristretto255.Scalar {
+//ScalarFromBytes decodes a canonical little-endian scalar, non-reduced encodings are rejected
+func ScalarFromBytes(b [32]byte) (*ristretto255.Scalar, error) {
 	var s ristretto255.Scalar
-	err := s.Decode(b[:])
-	if err != nil {
-		panic(err)
+	if err := s.Decode(b[:]); err != nil {
+		return nil, ErrInvalidScalar
 	}
-	return &s
+	return &s, nil
 }
 
 func ElementToBytes(element *ristretto255.Element) [32]byte {
@@ -49,198 +141,13 @@ func ElementToBytes(element *ristretto255.Element) [32]byte {
 	return buf
 }
 
-func ElementFromBytes(buf [32]byte) *ristretto255.Element {
+//ElementFromBytes decodes a canonical ristretto255 encoding, invalid or non-canonical points are rejected
+func ElementFromBytes(buf [32]byte) (*ristretto255.Element, error) {
 	var element ristretto255.Element
-	element.Decode(buf[:])
-	return &element
-}
-
-// iteration||a||b||Ls||Rs
-func writeProof(w io.Writer, proof *InnerProductProof) error {
-	err := writeElements(w, proof.iteration, ScalarToBytes(proof.a), ScalarToBytes(proof.b))
-	if err != nil {
-		return err
+	if err := element.Decode(buf[:]); err != nil {
+		return nil, ErrInvalidElement
 	}
-	for _, l := range proof.Ls {
-		err = writeElement(w, ElementToBytes(l))
-	}
-	if err != nil {
-		return err
-	}
-	for _, r := range proof.Rs {
-		err = writeElement(w, ElementToBytes(r))
-	}
-	if err != nil {
-		return err
-	}
-	return nil
-
-}
-
-func readProof(r io.Reader, proof *InnerProductProof) error {
-	var bufa, bufb [32]byte
-	err := readElements(r, &proof.iteration, &bufa, &bufb)
-	if err != nil {
-		return err
-	}
-	proof.a = ScalarFromBytes(bufa)
-	proof.b = ScalarFromBytes(bufb)
-
-	for i := 0; i < int(proof.iteration); i++ {
-		var bufPoint [32]byte
-		err = readElement(r, &bufPoint)
-		if err != nil {
-			return err
-		}
-		l := ElementFromBytes(bufPoint)
-		proof.Ls = append(proof.Ls, l)
-	}
-
-	for i := 0; i < int(proof.iteration); i++ {
-		var bufPoint [32]byte
-		err = readElement(r, &bufPoint)
-		if err != nil {
-			return err
-		}
-		r := ElementFromBytes(bufPoint)
-		proof.Rs = append(proof.Rs, r)
-	}
-	return nil
-}
-
-func writeElements(w io.Writer, elements ...interface{}) error {
-	for _, element := range elements {
-		err := writeElement(w, element)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-func writeElement(w io.Writer, element interface{}) error {
-	var scratch [8]byte
-	// Attempt to write the element based on the concrete type via fast
-	// type assertions first.
-	switch e := element.(type) {
-	case int32:
-		b := scratch[0:4]
-		binary.LittleEndian.PutUint32(b, uint32(e))
-		_, err := w.Write(b)
-		if err != nil {
-			return err
-		}
-		return nil
-
-	case [32]byte:
-		_, err := w.Write(e[:])
-		if err != nil {
-			return err
-		}
-		return nil
-
-	// IP address.
-	case [33]byte:
-		_, err := w.Write(e[:])
-		if err != nil {
-			return err
-		}
-		return nil
-	case *ristretto255.Scalar:
-		b := ScalarToBytes(e)
-		_, err := w.Write(b[:])
-		if err != nil {
-			return err
-		}
-		return nil
-	case *ristretto255.Element:
-		b := ElementToBytes(e)
-		_, err := w.Write(b[:])
-		if err != nil {
-			return err
-		}
-		return nil
-	case InnerProductProof:
-		b := e.Serialize()
-		_, err := w.Write(b[:])
-		if err != nil {
-			return err
-		}
-		return nil
-	}
-
-	return errors.New("invalid element")
-}
-func readElements(r io.Reader, elements ...interface{}) error {
-	for _, element := range elements {
-		err := readElement(r, element)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// readElement reads the next sequence of bytes from r using little endian
-// depending on the concrete type of element pointed to.
-func readElement(r io.Reader, element interface{}) error {
-	var scratch [8]byte
-	// Attempt to read the element based on the concrete type via fast
-	// type assertions first.
-	switch e := element.(type) {
-	case *int32:
-		b := scratch[0:4]
-		_, err := io.ReadFull(r, b)
-		if err != nil {
-			return err
-		}
-		*e = int32(binary.LittleEndian.Uint32(b))
-		return nil
-
-	case *[32]byte:
-		_, err := io.ReadFull(r, e[:])
-		if err != nil {
-			return err
-		}
-		return nil
-
-	// IP address.
-	case *[33]byte:
-		_, err := io.ReadFull(r, e[:])
-		if err != nil {
-			return err
-		}
-		return nil
-	case *ristretto255.Element:
-		var b [32]byte
-		_, err := io.ReadFull(r, b[:])
-		if err != nil {
-			return err
-		}
-		e = ElementFromBytes(b)
-		return nil
-	case *ristretto255.Scalar:
-		var b [32]byte
-		_, err := io.ReadFull(r, b[:])
-		if err != nil {
-			return err
-		}
-		e = ScalarFromBytes(b)
-		return nil
-	case *InnerProductProof:
-		b := new(bytes.Buffer)
-		_, err := b.ReadFrom(r)
-		if err != nil {
-			return err
-		}
-		err = e.Deserialize(b.Bytes())
-		if err != nil {
-			return err
-		}
-	case Commitment:
-
-	}
-
-	return errors.New("invalid element")
+	return &element, nil
 }
 
 //type SigmaRangeProof struct {
@@ -254,24 +161,7 @@ func readElement(r io.Reader, element interface{}) error {
 //	InnerProof InnerProductProof
 //}
 
-//func (proof *SigmaRangeProof) Serialize() ([]byte, error) {
-//	buf := new(bytes.Buffer)
-//	err := writeElements(buf, proof.Taux, proof.Mu, proof.THat, proof.T1, proof.T2, proof.A, proof.S, proof.InnerProof)
-//	if err != nil {
-//		return nil, err
-//	}
-//	return buf.Bytes(), nil
-//}
-
-//func (proof *SigmaRangeProof) Deserialize(b []byte) error {
-//	buf := new(bytes.Buffer)
-//	buf.Write(b)
-//	err := readElements(buf, proof.Taux, proof.Mu, proof.THat, proof.T1, proof.T2, proof.A, proof.S, &proof.InnerProof)
-//	return err
-//}
-
-func (proof *SigmaRangeProof) Serialize() []byte {
-	sink := NewZeroCopySink(nil)
+func (proof *SigmaRangeProof) Serialization(sink *ZeroCopySink) {
 	sink.WriteScalar(proof.Taux)
 	sink.WriteScalar(proof.Mu)
 	sink.WriteScalar(proof.THat)
@@ -279,14 +169,11 @@ func (proof *SigmaRangeProof) Serialize() []byte {
 	sink.WriteElement(proof.T2)
 	sink.WriteElement(proof.A)
 	sink.WriteElement(proof.S)
-	text := proof.InnerProof.Serialize()
-	EncodeBytes(sink, text)
-	return sink.Bytes()
+	EncodeBytes(sink, proof.InnerProof.Serialize())
 }
 
-func (proof *SigmaRangeProof) Deserialize(b []byte) error {
+func (proof *SigmaRangeProof) Deserialization(source *ZeroCopySource) error {
 	var err error
-	source := NewZeroCopySource(b)
 	proof.Taux, err = source.NextScalar()
 	if err != nil {
 		return err
@@ -319,11 +206,15 @@ func (proof *SigmaRangeProof) Deserialize(b []byte) error {
 	if err != nil {
 		return err
 	}
-	err = proof.InnerProof.Deserialize(text)
-	if err != nil {
-		return err
-	}
-	return nil
+	return proof.InnerProof.DeserializeUpTo(text, MaxRangeBits*RANGEPROOFCOUNT)
+}
+
+func (proof *SigmaRangeProof) Serialize() []byte {
+	return serialize(proof)
+}
+
+func (proof *SigmaRangeProof) Deserialize(b []byte) error {
+	return deserialize(proof, b)
 }
 
 //type TransferProof struct {
@@ -333,8 +224,7 @@ func (proof *SigmaRangeProof) Deserialize(b []byte) error {
 //	cComm, cPrimeComm       Commitment
 //}
 
-func (proof *TransferProof) Serialize() []byte {
-	sink := NewZeroCopySink(nil)
+func (proof *TransferProof) Serialization(sink *ZeroCopySink) {
 	sink.WriteElement(proof.ay)
 	sink.WriteElement(proof.ad)
 	sink.WriteElement(proof.ab)
@@ -344,18 +234,14 @@ func (proof *TransferProof) Serialize() []byte {
 	sink.WriteScalar(proof.sr)
 	sink.WriteScalar(proof.sb)
 	sink.WriteScalar(proof.stau)
-	cComm := proof.CComm.Encode()
-	EncodeBytes(sink, cComm)
-	cPrimeComm := proof.CPrimeComm.Encode()
-	EncodeBytes(sink, cPrimeComm)
-	text := proof.sigmaRangeProof.Serialize()
-	EncodeBytes(sink, text)
-	return sink.Bytes()
+	EncodeBytes(sink, proof.CComm.Encode())
+	EncodeBytes(sink, proof.CPrimeComm.Encode())
+	EncodeBytes(sink, proof.sigmaRangeProof.Serialize())
+	serializeAuditors(sink, proof.Auditors)
 }
 
-func (proof *TransferProof) Deserialize(b []byte) error {
+func (proof *TransferProof) Deserialization(source *ZeroCopySource) error {
 	var err error
-	source := NewZeroCopySource(b)
 	proof.ay, err = source.NextElement()
 	if err != nil {
 		return err
@@ -418,7 +304,16 @@ func (proof *TransferProof) Deserialize(b []byte) error {
 		return err
 	}
 	proof.sigmaRangeProof = &sigmagRangeProof
-	return nil
+	proof.Auditors, err = deserializeAuditors(source)
+	return err
+}
+
+func (proof *TransferProof) Serialize() []byte {
+	return serialize(proof)
+}
+
+func (proof *TransferProof) Deserialize(b []byte) error {
+	return deserialize(proof, b)
 }
 
 //type BurnProof struct {
@@ -427,18 +322,15 @@ func (proof *TransferProof) Deserialize(b []byte) error {
 //	b       *ristretto255.Scalar
 //}
 
-func (proof *CommitmentProof) Serialize() []byte {
-	sink := NewZeroCopySink(nil)
+func (proof *CommitmentProof) Serialization(sink *ZeroCopySink) {
 	sink.WriteElement(proof.ay)
 	sink.WriteElement(proof.acr)
 	sink.WriteScalar(proof.ssk)
 	sink.WriteScalar(proof.B)
-	return sink.Bytes()
 }
 
-func (proof *CommitmentProof) Deserialize(b []byte) error {
+func (proof *CommitmentProof) Deserialization(source *ZeroCopySource) error {
 	var err error
-	source := NewZeroCopySource(b)
 	proof.ay, err = source.NextElement()
 	if err != nil {
 		return err
@@ -458,29 +350,33 @@ func (proof *CommitmentProof) Deserialize(b []byte) error {
 	return nil
 }
 
+func (proof *CommitmentProof) Serialize() []byte {
+	return serialize(proof)
+}
+
+func (proof *CommitmentProof) Deserialize(b []byte) error {
+	return deserialize(proof, b)
+}
+
 //type WithdrawProof struct {
 //	rangeProof *RangeProof
 //	commWD     Commitment
 //	ad, ay, ag *ristretto255.Element
 //	ssk, sr    *ristretto255.Scalar
 //}
-func (proof *WithdrawProof) Serialize() []byte {
-	sink := NewZeroCopySink(nil)
+
+func (proof *WithdrawProof) Serialization(sink *ZeroCopySink) {
 	sink.WriteElement(proof.ad)
 	sink.WriteElement(proof.ay)
 	sink.WriteElement(proof.ag)
 	sink.WriteScalar(proof.ssk)
 	sink.WriteScalar(proof.sr)
-	commWD := proof.CommWD.Encode()
-	EncodeBytes(sink, commWD)
-	rangeProof := proof.rangeProof.Serialize()
-	EncodeBytes(sink, rangeProof)
-	return sink.Bytes()
+	EncodeBytes(sink, proof.CommWD.Encode())
+	EncodeBytes(sink, proof.rangeProof.Serialize())
 }
 
-func (proof *WithdrawProof) Deserialize(b []byte) error {
+func (proof *WithdrawProof) Deserialization(source *ZeroCopySource) error {
 	var err error
-	source := NewZeroCopySource(b)
 	proof.ad, err = source.NextElement()
 	if err != nil {
 		return err
@@ -523,6 +419,14 @@ func (proof *WithdrawProof) Deserialize(b []byte) error {
 	return nil
 }
 
+func (proof *WithdrawProof) Serialize() []byte {
+	return serialize(proof)
+}
+
+func (proof *WithdrawProof) Deserialize(b []byte) error {
+	return deserialize(proof, b)
+}
+
 //type RangeProof struct {
 //	G, H       *ristretto255.Element
 //	Taux       *ristretto255.Scalar  // blinding factors in tHat
@@ -535,10 +439,19 @@ func (proof *WithdrawProof) Deserialize(b []byte) error {
 //	InnerProof InnerProductProof
 //}
 
-func (proof *RangeProof) Serialize() []byte {
-	sink := NewZeroCopySink(nil)
-	sink.WriteElement(proof.G)
-	sink.WriteElement(proof.H)
+//impliedGenerator stands for the G and H of a range proof decoded from the compact encoding, they are
+//restored by the verifier. No valid proof has the identity as generator.
+func impliedGenerator(e *ristretto255.Element) *ristretto255.Element {
+	if e == nil {
+		return ristretto255.NewElement()
+	}
+	return e
+}
+
+//G and H are written as the identity when the proof came from the compact encoding and read back as nil
+func (proof *RangeProof) Serialization(sink *ZeroCopySink) {
+	sink.WriteElement(impliedGenerator(proof.G))
+	sink.WriteElement(impliedGenerator(proof.H))
 	sink.WriteScalar(proof.Taux)
 	sink.WriteScalar(proof.Mu)
 	sink.WriteScalar(proof.THat)
@@ -546,14 +459,11 @@ func (proof *RangeProof) Serialize() []byte {
 	sink.WriteElement(proof.T2)
 	sink.WriteElement(proof.A)
 	sink.WriteElement(proof.S)
-	text := proof.InnerProof.Serialize()
-	EncodeBytes(sink, text)
-	return sink.Bytes()
+	EncodeBytes(sink, proof.InnerProof.Serialize())
 }
 
-func (proof *RangeProof) Deserialize(b []byte) error {
+func (proof *RangeProof) Deserialization(source *ZeroCopySource) error {
 	var err error
-	source := NewZeroCopySource(b)
 	proof.G, err = source.NextElement()
 	if err != nil {
 		return err
@@ -562,6 +472,10 @@ func (proof *RangeProof) Deserialize(b []byte) error {
 	if err != nil {
 		return err
 	}
+	identity := ristretto255.NewElement()
+	if proof.G.Equal(identity) == 1 || proof.H.Equal(identity) == 1 {
+		proof.G, proof.H = nil, nil
+	}
 	proof.Taux, err = source.NextScalar()
 	if err != nil {
 		return err
@@ -594,9 +508,13 @@ func (proof *RangeProof) Deserialize(b []byte) error {
 	if err != nil {
 		return err
 	}
-	err = proof.InnerProof.Deserialize(text)
-	if err != nil {
-		return err
-	}
-	return nil
+	return proof.InnerProof.DeserializeUpTo(text, MaxRangeBits)
+}
+
+func (proof *RangeProof) Serialize() []byte {
+	return serialize(proof)
+}
+
+func (proof *RangeProof) Deserialize(b []byte) error {
+	return deserialize(proof, b)
 }
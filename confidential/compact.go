@@ -0,0 +1,287 @@
+package confidential
+
+import (
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//The compact encoding drops everything a verifier can derive: length prefixes of fixed size fields,
+//the inner product round count (implied by the length of the proof, or by RANGEBITS where auditor
+//ciphertexts follow), the G and H generators of a RangeProof (G is the range prover base, H the Cr
+//of the updated sender commitment) and the Cr of TransferProof.CPrimeComm which always equals CComm.Cr.
+
+//raw sizes, checked before any curve decoding
+var (
+	compactCommitmentProofSize    = 4 * 32
+	compactRangeProofFixedSize    = 7 * 32
+	compactWithdrawProofFixedSize = 5*32 + 64 + compactRangeProofFixedSize
+	compactSigmaRangeProofSize    = 7*32 + compactInnerProductSize(RANGEBITS*RANGEPROOFCOUNT)
+	compactTransferProofSize      = 9*32 + 64 + 32 + compactSigmaRangeProofSize
+)
+
+func compactInnerProductSize(n uint64) int {
+	rounds, _ := InnerProductRounds(n)
+	return innerProductProofSize(rounds) - 4
+}
+
+//compactInnerProductN returns the vector length of a compact inner product proof of size bytes,
+//any power of two up to maxN
+func compactInnerProductN(size int, maxN uint64) (uint64, error) {
+	maxRounds, err := InnerProductRounds(maxN)
+	if err != nil {
+		return 0, err
+	}
+	rounds := (size - compactInnerProductSize(1)) / 64
+	if size < compactInnerProductSize(1) || rounds > maxRounds || compactInnerProductSize(uint64(1)<<uint(rounds)) != size {
+		return 0, ErrProofLength
+	}
+	return uint64(1) << uint(rounds), nil
+}
+
+func nextFixedElements(source *ZeroCopySource, elements ...**ristretto255.Element) error {
+	var err error
+	for _, e := range elements {
+		*e, err = source.NextFixedElement()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func nextFixedScalars(source *ZeroCopySource, scalars ...**ristretto255.Scalar) error {
+	var err error
+	for _, s := range scalars {
+		*s, err = source.NextFixedScalar()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// a||b||Ls||Rs
+func (self *InnerProductProof) serializeCompact(sink *ZeroCopySink) {
+	sink.WriteFixedScalar(self.a)
+	sink.WriteFixedScalar(self.b)
+	for _, l := range self.Ls {
+		sink.WriteFixedElement(l)
+	}
+	for _, r := range self.Rs {
+		sink.WriteFixedElement(r)
+	}
+}
+
+func (self *InnerProductProof) deserializeCompact(source *ZeroCopySource, n uint64) error {
+	rounds, err := InnerProductRounds(n)
+	if err != nil {
+		return err
+	}
+	if source.Len() < uint64(compactInnerProductSize(n)) {
+		return ErrProofLength
+	}
+	if err := nextFixedScalars(source, &self.a, &self.b); err != nil {
+		return err
+	}
+	self.iteration = int32(rounds)
+	self.Ls = make([]*ristretto255.Element, rounds)
+	self.Rs = make([]*ristretto255.Element, rounds)
+	for i := range self.Ls {
+		if err := nextFixedElements(source, &self.Ls[i]); err != nil {
+			return err
+		}
+	}
+	for i := range self.Rs {
+		if err := nextFixedElements(source, &self.Rs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (proof *CommitmentProof) SerializeCompact() []byte {
+	sink := NewZeroCopySink(nil)
+	sink.WriteFixedElement(proof.ay)
+	sink.WriteFixedElement(proof.acr)
+	sink.WriteFixedScalar(proof.ssk)
+	sink.WriteFixedScalar(proof.B)
+	return sink.Bytes()
+}
+
+func (proof *CommitmentProof) DeserializeCompact(b []byte) error {
+	if len(b) != compactCommitmentProofSize {
+		return ErrProofLength
+	}
+	source := NewZeroCopySource(b)
+	if err := nextFixedElements(source, &proof.ay, &proof.acr); err != nil {
+		return err
+	}
+	return nextFixedScalars(source, &proof.ssk, &proof.B)
+}
+
+func (proof *RangeProof) serializeCompact(sink *ZeroCopySink) {
+	sink.WriteFixedScalar(proof.Taux)
+	sink.WriteFixedScalar(proof.Mu)
+	sink.WriteFixedScalar(proof.THat)
+	sink.WriteFixedElement(proof.T1)
+	sink.WriteFixedElement(proof.T2)
+	sink.WriteFixedElement(proof.A)
+	sink.WriteFixedElement(proof.S)
+	proof.InnerProof.serializeCompact(sink)
+}
+
+//deserializeCompact reads a range proof over n bits
+func (proof *RangeProof) deserializeCompact(source *ZeroCopySource, n uint64) error {
+	if err := nextFixedScalars(source, &proof.Taux, &proof.Mu, &proof.THat); err != nil {
+		return err
+	}
+	if err := nextFixedElements(source, &proof.T1, &proof.T2, &proof.A, &proof.S); err != nil {
+		return err
+	}
+	return proof.InnerProof.deserializeCompact(source, n)
+}
+
+//SerializeCompact omits G and H, they are restored by the verifier
+func (proof *RangeProof) SerializeCompact() []byte {
+	sink := NewZeroCopySink(nil)
+	proof.serializeCompact(sink)
+	return sink.Bytes()
+}
+
+//DeserializeCompact leaves G and H nil, VerifyWithDrawProof fills them from the ledger state.
+//Any N up to MaxRangeBits is accepted, the verifier checks it against its RangeProver.
+func (proof *RangeProof) DeserializeCompact(b []byte) error {
+	n, err := compactInnerProductN(len(b)-compactRangeProofFixedSize, MaxRangeBits)
+	if err != nil {
+		return err
+	}
+	return proof.deserializeCompact(NewZeroCopySource(b), n)
+}
+
+func (proof *SigmaRangeProof) serializeCompact(sink *ZeroCopySink) {
+	sink.WriteFixedScalar(proof.Taux)
+	sink.WriteFixedScalar(proof.Mu)
+	sink.WriteFixedScalar(proof.THat)
+	sink.WriteFixedElement(proof.T1)
+	sink.WriteFixedElement(proof.T2)
+	sink.WriteFixedElement(proof.A)
+	sink.WriteFixedElement(proof.S)
+	proof.InnerProof.serializeCompact(sink)
+}
+
+func (proof *SigmaRangeProof) deserializeCompact(source *ZeroCopySource) error {
+	return proof.deserializeCompactN(source, RANGEBITS*RANGEPROOFCOUNT)
+}
+
+//deserializeCompactN reads a sigma range proof over n bits
+func (proof *SigmaRangeProof) deserializeCompactN(source *ZeroCopySource, n uint64) error {
+	if err := nextFixedScalars(source, &proof.Taux, &proof.Mu, &proof.THat); err != nil {
+		return err
+	}
+	if err := nextFixedElements(source, &proof.T1, &proof.T2, &proof.A, &proof.S); err != nil {
+		return err
+	}
+	return proof.InnerProof.deserializeCompact(source, n)
+}
+
+func (proof *SigmaRangeProof) SerializeCompact() []byte {
+	sink := NewZeroCopySink(nil)
+	proof.serializeCompact(sink)
+	return sink.Bytes()
+}
+
+func (proof *SigmaRangeProof) DeserializeCompact(b []byte) error {
+	n, err := compactInnerProductN(len(b)-compactRangeProofFixedSize, MaxRangeBits*RANGEPROOFCOUNT)
+	if err != nil {
+		return err
+	}
+	return proof.deserializeCompactN(NewZeroCopySource(b), n)
+}
+
+func (proof *WithdrawProof) SerializeCompact() []byte {
+	sink := NewZeroCopySink(nil)
+	sink.WriteFixedElement(proof.ad)
+	sink.WriteFixedElement(proof.ay)
+	sink.WriteFixedElement(proof.ag)
+	sink.WriteFixedScalar(proof.ssk)
+	sink.WriteFixedScalar(proof.sr)
+	sink.WriteBytes(proof.CommWD.Encode())
+	proof.rangeProof.serializeCompact(sink)
+	return sink.Bytes()
+}
+
+func (proof *WithdrawProof) DeserializeCompact(b []byte) error {
+	n, err := compactInnerProductN(len(b)-compactWithdrawProofFixedSize, MaxRangeBits)
+	if err != nil {
+		return err
+	}
+	source := NewZeroCopySource(b)
+	if err := nextFixedElements(source, &proof.ad, &proof.ay, &proof.ag); err != nil {
+		return err
+	}
+	if err := nextFixedScalars(source, &proof.ssk, &proof.sr); err != nil {
+		return err
+	}
+	if err := nextFixedElements(source, &proof.CommWD.Cl, &proof.CommWD.Cr); err != nil {
+		return err
+	}
+	var rangeProof RangeProof
+	if err := rangeProof.deserializeCompact(source, n); err != nil {
+		return err
+	}
+	proof.rangeProof = &rangeProof
+	return nil
+}
+
+//SerializeCompact writes CPrimeComm.Cl only, its Cr is shared with CComm. Auditor ciphertexts follow
+//with a one byte count.
+func (proof *TransferProof) SerializeCompact() []byte {
+	sink := NewZeroCopySink(nil)
+	sink.WriteFixedElement(proof.ay)
+	sink.WriteFixedElement(proof.ad)
+	sink.WriteFixedElement(proof.ab)
+	sink.WriteFixedElement(proof.ayPrime)
+	sink.WriteFixedElement(proof.at)
+	sink.WriteFixedScalar(proof.ssk)
+	sink.WriteFixedScalar(proof.sr)
+	sink.WriteFixedScalar(proof.sb)
+	sink.WriteFixedScalar(proof.stau)
+	sink.WriteBytes(proof.CComm.Encode())
+	sink.WriteFixedElement(proof.CPrimeComm.Cl)
+	proof.sigmaRangeProof.serializeCompact(sink)
+	serializeAuditors(sink, proof.Auditors)
+	return sink.Bytes()
+}
+
+func (proof *TransferProof) DeserializeCompact(b []byte) error {
+	if len(b) < compactTransferProofSize {
+		return ErrProofLength
+	}
+	if len(b) > compactTransferProofSize {
+		n := int(b[compactTransferProofSize])
+		if n == 0 || n > MaxAuditors {
+			return ErrTooManyAuditors
+		}
+		if len(b) != compactTransferProofSize+1+n*auditorCiphertextSize {
+			return ErrProofLength
+		}
+	}
+	source := NewZeroCopySource(b)
+	if err := nextFixedElements(source, &proof.ay, &proof.ad, &proof.ab, &proof.ayPrime, &proof.at); err != nil {
+		return err
+	}
+	if err := nextFixedScalars(source, &proof.ssk, &proof.sr, &proof.sb, &proof.stau); err != nil {
+		return err
+	}
+	if err := nextFixedElements(source, &proof.CComm.Cl, &proof.CComm.Cr, &proof.CPrimeComm.Cl); err != nil {
+		return err
+	}
+	proof.CPrimeComm.Cr = DeepCopyElement(proof.CComm.Cr)
+	var sigmaRangeProof SigmaRangeProof
+	if err := sigmaRangeProof.deserializeCompact(source); err != nil {
+		return err
+	}
+	proof.sigmaRangeProof = &sigmaRangeProof
+	var err error
+	proof.Auditors, err = deserializeAuditors(source)
+	return err
+}
@@ -1,10 +1,25 @@
 package confidential
 
 import (
+	"bytes"
+	"confidential-account/confidential/pb"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"github.com/Evanesco-Labs/ristretto255"
 	"github.com/magiconair/properties/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -106,6 +121,2051 @@ func TestWithDrawProof(t *testing.T) {
 	fmt.Printf("VerifyWithdrawProof takes: %v\n", t2.Sub(t1))
 }
 
+func TestConstantTimeTransfer(t *testing.T) {
+	source := []byte("hello")
+	seed := sha256.Sum256(source)
+	var acc Account
+	acc.Init(seed)
+	acc.SetDecryptionBound(uint64(1) << 10)
+	acc.SetConstantTime(true)
+	assert.Equal(t, acc.upper, uint64(1)<<10)
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	acc.Deposit(uint64(100))
+	assert.Equal(t, ScalartoInt(acc.GetCommitmentBalance()), uint64(100))
+
+	var accRec Account
+	accRec.Init(sha256.Sum256([]byte("receiver")))
+	sc.Register(accRec.Pk, accRec.Comm)
+
+	trans := sha512.Sum512(source)
+	proof, err := acc.GenTransferProof(trans, uint64(10), accRec.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyTransferProof(trans, proof, acc.Pk, accRec.Pk), true)
+
+	base := sc.BasePoint
+	v, _ := InttoScalar(uint64(77))
+	vEncrypt := new(ristretto255.Element).ScalarMult(v, base)
+	assert.Equal(t, GuessValueConstantTime(vEncrypt, base, uint64(100)).Equal(v), 1)
+	assert.Equal(t, GuessValueConstantTime(vEncrypt, base, uint64(50)) == nil, true)
+
+	assert.Equal(t, GenBitVector(uint64(11), 6), []uint64{1, 1, 0, 1, 0, 0})
+	assert.Equal(t, GenBitVector(^uint64(0), 64)[63], uint64(1))
+}
+
+func TestStrictDecoding(t *testing.T) {
+	source := []byte("hello")
+	var acc Account
+	acc.Init(sha256.Sum256(source))
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	acc.Deposit(uint64(100))
+
+	trans := sha512.Sum512(source)
+	proof, err := acc.GenWithdrawProof(trans, uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := proof.Serialize()
+	var decoded WithdrawProof
+	if err := decoded.Deserialize(text); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyWithDrawProof(trans, acc.Pk, uint64(60), &decoded), true)
+	assert.Equal(t, decoded.Deserialize(append(text, 0)), ErrTrailingBytes)
+
+	var nonCanonical [32]byte
+	for i := range nonCanonical {
+		nonCanonical[i] = 0xff
+	}
+	_, err = ScalarFromBytes(nonCanonical)
+	assert.Equal(t, err, ErrInvalidScalar)
+	_, err = ElementFromBytes(nonCanonical)
+	assert.Equal(t, err, ErrInvalidElement)
+
+	var comm Commitment
+	assert.Equal(t, comm.Decode(append(nonCanonical[:], nonCanonical[:]...)), ErrInvalidElement)
+	commText := acc.Comm.Encode()
+	if err := comm.Decode(commText); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, comm.Cl.Equal(acc.Comm.Cl), 1)
+}
+
+func TestInnerProductProofBounds(t *testing.T) {
+	source := []byte("hello")
+	var acc Account
+	acc.Init(sha256.Sum256(source))
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	acc.Deposit(uint64(100))
+
+	proof, err := acc.GenWithdrawProof(sha512.Sum512(source), uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := proof.rangeProof.InnerProof.Serialize()
+	var inner InnerProductProof
+	if err := inner.DeserializeUpTo(text, RANGEBITS); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(inner.Ls), 5)
+	assert.Equal(t, inner.DeserializeUpTo(text, RANGEBITS/2), ErrProofLength)
+
+	forged := append([]byte{}, text...)
+	binary.LittleEndian.PutUint32(forged, uint32(1)<<31)
+	assert.Equal(t, inner.DeserializeUpTo(forged, RANGEBITS), ErrProofLength)
+	assert.Equal(t, inner.Deserialize(forged), ErrProofLength)
+	assert.Equal(t, inner.Deserialize(text[:len(text)-1]), ErrProofLength)
+
+	//the round count follows the N of the prover, a verifier with another N rejects the proof
+	prover16, _ := NewRangeProver(16, sha256.Sum256([]byte("prover16")))
+	v, _ := InttoScalar(uint64(1000))
+	gamma := prover16.RandScalar()
+	vCommit := prover16.Commit(v, gamma)
+	trans := sha512.Sum512([]byte("range16"))
+	_, rangeProof, err := prover16.GenRangeProof(trans, ElgamalCommitment{g: prover16.G, h: prover16.H, v: v, gamma: gamma, comm: vCommit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var legacy, compact RangeProof
+	assert.Equal(t, legacy.Deserialize(rangeProof.Serialize()), nil)
+	assert.Equal(t, compact.DeserializeCompact(rangeProof.SerializeCompact()), nil)
+	assert.Equal(t, len(compact.InnerProof.Ls), 4)
+	compact.G, compact.H = prover16.G, prover16.H
+	_, ok := prover16.VerifyRangeProof(trans, &legacy, vCommit)
+	assert.Equal(t, ok, true)
+	_, ok = prover16.VerifyRangeProof(trans, &compact, vCommit)
+	assert.Equal(t, ok, true)
+	_, ok = sc.rangeProver.VerifyRangeProof(trans, &legacy, vCommit)
+	assert.Equal(t, ok, false)
+	assert.Equal(t, compact.DeserializeCompact(rangeProof.SerializeCompact()[1:]), ErrProofLength)
+	assert.Equal(t, maxInnerProductRounds, 9)
+}
+
+func TestProofEnvelope(t *testing.T) {
+	source := []byte("hello")
+	var acc Account
+	acc.Init(sha256.Sum256(source))
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	acc.Deposit(uint64(100))
+
+	var accRec Account
+	accRec.Init(sha256.Sum256([]byte("receiver")))
+	sc.Register(accRec.Pk, accRec.Comm)
+
+	trans := sha512.Sum512(source)
+	transferProof, err := acc.GenTransferProof(trans, uint64(10), accRec.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := EncodeProof(transferProof)
+	header, err := DecodeProofHeader(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, header.Kind, KindTransferProof)
+	assert.Equal(t, header.ParamsID, DefaultParamsID)
+
+	proof, err := DecodeProof(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, ok := proof.(*TransferProof)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, sc.VerifyTransferProof(trans, decoded, acc.Pk, accRec.Pk), true)
+
+	burn := acc.GenBurnProof()
+	burnText := EncodeProof(&burn)
+	burnText[5] = uint8(KindWithdrawProof)
+	_, err = DecodeProof(burnText)
+	assert.Equal(t, err != nil, true)
+
+	//every kind is stamped with the params of the range proof it carries
+	header, _ = DecodeProofHeader(EncodeProof(&burn))
+	assert.Equal(t, header.ParamsID, ParamsID(0, 0))
+	withdrawProof, err := acc.GenWithdrawProof(trans, uint64(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withdrawText := EncodeProof(withdrawProof)
+	header, _ = DecodeProofHeader(withdrawText)
+	assert.Equal(t, header.ParamsID, ParamsID(RANGEBITS, 1))
+	_, err = DecodeProof(withdrawText)
+	assert.Equal(t, err, nil)
+	recipients := make([]*ristretto255.Element, 3)
+	for i := range recipients {
+		var rec Account
+		rec.Init(sha256.Sum256([]byte{byte(i)}))
+		recipients[i] = rec.Pk
+	}
+	multiProof, err := acc.GenMultiTransferProof(trans, recipients, []uint64{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	multiText := EncodeProof(multiProof)
+	header, _ = DecodeProofHeader(multiText)
+	assert.Equal(t, header.ParamsID, ParamsID(RANGEBITS, 4))
+	_, err = DecodeProof(multiText)
+	assert.Equal(t, err, nil)
+
+	//an id of another kind is rejected from the header, an id of the kind that does not match the payload after decoding
+	copy(text[6:14], header.ParamsID[:])
+	_, err = DecodeProof(text)
+	assert.Equal(t, err, ErrProofParamsID)
+	copy(text[6:14], DefaultParamsID[:])
+	copy(multiText[6:14], DefaultParamsID[:])
+	_, err = DecodeProof(multiText)
+	assert.Equal(t, err, ErrProofParamsID)
+
+	text[0] ^= 1
+	_, err = DecodeProof(text)
+	assert.Equal(t, err, ErrProofMagic)
+}
+
+func TestCompactProofEncoding(t *testing.T) {
+	source := []byte("hello")
+	var acc Account
+	acc.Init(sha256.Sum256(source))
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	acc.Deposit(uint64(100))
+
+	trans := sha512.Sum512(source)
+	proof, err := acc.GenWithdrawProof(trans, uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact := proof.SerializeCompact()
+	assert.Equal(t, len(compact) < len(proof.Serialize()), true)
+	var decoded WithdrawProof
+	if err := decoded.DeserializeCompact(compact); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyWithDrawProof(trans, acc.Pk, uint64(60), &decoded), true)
+	assert.Equal(t, decoded.DeserializeCompact(compact[1:]), ErrProofLength)
+
+	var accRec Account
+	accRec.Init(sha256.Sum256([]byte("receiver")))
+	sc.Register(accRec.Pk, accRec.Comm)
+	transferProof, err := acc.GenTransferProof(trans, uint64(10), accRec.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, err := MigrateProof(EncodeProofLegacy(transferProof))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, migrated, EncodeProof(transferProof))
+	decodedTransfer, err := DecodeProof(migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyTransferProof(trans, decodedTransfer.(*TransferProof), acc.Pk, accRec.Pk), true)
+
+	//a compact proof has no generators, it still migrates back to the legacy layout and binds the same nullifier
+	envelope, err := DecodeProof(EncodeProof(proof))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromCompact := envelope.(*WithdrawProof)
+	legacy, err := DecodeProof(EncodeProofLegacy(fromCompact))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, legacy.SerializeCompact(), compact)
+	assert.Equal(t, sc.VerifyWithDrawProof(trans, acc.Pk, uint64(60), legacy.(*WithdrawProof)), true)
+	assert.Equal(t, spendMessage(trans, fromCompact), spendMessage(trans, proof))
+	nullifier := acc.GenNullifier(trans, sc.Epoch, fromCompact)
+	assert.Equal(t, sc.ApplyWithdraw(trans, acc.Pk, uint64(60), fromCompact, nil, nullifier), true)
+}
+
+func TestJSONAndProtoEncoding(t *testing.T) {
+	source := []byte("hello")
+	var acc Account
+	acc.Init(sha256.Sum256(source))
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	acc.Deposit(uint64(100))
+
+	var accRec Account
+	accRec.Init(sha256.Sum256([]byte("receiver")))
+	sc.Register(accRec.Pk, accRec.Comm)
+
+	trans := sha512.Sum512(source)
+	transferProof, err := acc.GenTransferProof(trans, uint64(10), accRec.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withdrawProof, err := acc.GenWithdrawProof(trans, uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	burnProof := acc.GenBurnProof()
+
+	text, err := json.Marshal(transferProof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transferJSON TransferProof
+	if err := json.Unmarshal(text, &transferJSON); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, transferJSON.Serialize(), transferProof.Serialize())
+	var transferProto TransferProof
+	if err := transferProto.UnmarshalProto(transferProof.MarshalProto()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, transferProto.Serialize(), transferProof.Serialize())
+
+	text, err = json.Marshal(withdrawProof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var withdrawJSON WithdrawProof
+	if err := json.Unmarshal(text, &withdrawJSON); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, withdrawJSON.Serialize(), withdrawProof.Serialize())
+	var withdrawProto WithdrawProof
+	if err := withdrawProto.UnmarshalProto(withdrawProof.MarshalProto()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, withdrawProto.Serialize(), withdrawProof.Serialize())
+
+	text, err = json.Marshal(&burnProof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var burnJSON, burnProto CommitmentProof
+	if err := json.Unmarshal(text, &burnJSON); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, burnJSON.Serialize(), burnProof.Serialize())
+	if err := burnProto.UnmarshalProto(burnProof.MarshalProto()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, burnProto.Serialize(), burnProof.Serialize())
+
+	var comm Commitment
+	assert.Equal(t, json.Unmarshal([]byte(`{"cl":"00","cr":"00"}`), &comm) != nil, true)
+}
+
+//protoField is a field of proof.proto
+type protoField struct {
+	name     string
+	repeated bool
+}
+
+func readProtoSchema(t *testing.T) map[string]map[uint64]protoField {
+	text, err := ioutil.ReadFile("proof.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := make(map[string]map[uint64]protoField)
+	message := regexp.MustCompile(`(?s)message (\w+) \{(.*?)\}`)
+	field := regexp.MustCompile(`(repeated )?(\w+) (\w+) = (\d+);`)
+	for _, m := range message.FindAllStringSubmatch(string(text), -1) {
+		fields := make(map[uint64]protoField)
+		for _, f := range field.FindAllStringSubmatch(m[2], -1) {
+			number, _ := strconv.ParseUint(f[4], 10, 64)
+			fields[number] = protoField{name: f[3], repeated: f[1] != ""}
+		}
+		schema[m[1]] = fields
+	}
+	return schema
+}
+
+//checkProto walks a decoded message and records every field it carries in seen
+func checkProto(t *testing.T, m protoreflect.Message, seen map[string]bool) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		name := string(fd.ContainingMessage().Name()) + "." + string(fd.Name())
+		seen[name] = true
+		var values []protoreflect.Value
+		if fd.IsList() {
+			for i := 0; i < v.List().Len(); i++ {
+				values = append(values, v.List().Get(i))
+			}
+		} else {
+			values = append(values, v)
+		}
+		for _, value := range values {
+			switch fd.Kind() {
+			case protoreflect.MessageKind:
+				checkProto(t, value.Message(), seen)
+			case protoreflect.BytesKind:
+				if len(value.Bytes()) != 32 {
+					t.Fatalf("%s is not 32 bytes", name)
+				}
+			}
+		}
+		return true
+	})
+}
+
+func TestProtoSchema(t *testing.T) {
+	var acc, accRec, auditor Account
+	acc.Init(sha256.Sum256([]byte("hello")))
+	accRec.Init(sha256.Sum256([]byte("receiver")))
+	auditor.Init(sha256.Sum256([]byte("auditor")))
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	sc.Register(accRec.Pk, accRec.Comm)
+	sc.RegisterAuditor(auditor.Pk)
+	acc.Deposit(uint64(100))
+
+	trans := sha512.Sum512([]byte("proto"))
+	transferProof, err := acc.GenTransferProof(trans, uint64(10), accRec.Pk, auditor.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withdrawProof, err := acc.GenWithdrawProof(trans, uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeProof, err := acc.GenFeeTransferProof(trans, uint64(20), uint64(3), accRec.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	burn := acc.GenBurnProof()
+	addr, err := accRec.StealthAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := acc.GenStealthOutput(addr)
+
+	//the generated code has to match proof.proto field for field
+	schema := readProtoSchema(t)
+	messages := pb.File_proof_proto.Messages()
+	assert.Equal(t, messages.Len(), len(schema))
+	for message, fields := range schema {
+		desc := messages.ByName(protoreflect.Name(message))
+		if desc == nil {
+			t.Fatalf("message %s is not generated", message)
+		}
+		assert.Equal(t, desc.Fields().Len(), len(fields), message)
+		for number, field := range fields {
+			fd := desc.Fields().ByNumber(protoreflect.FieldNumber(number))
+			if fd == nil || string(fd.Name()) != field.name || fd.IsList() != field.repeated {
+				t.Fatalf("%s.%s does not match the generated code", message, field.name)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range []struct {
+		b []byte
+		m proto.Message
+	}{
+		{acc.Comm.MarshalProto(), &pb.Commitment{}},
+		{burn.MarshalProto(), &pb.CommitmentProof{}},
+		{withdrawProof.rangeProof.MarshalProto(), &pb.RangeProof{}},
+		{transferProof.sigmaRangeProof.MarshalProto(), &pb.SigmaRangeProof{}},
+		{withdrawProof.MarshalProto(), &pb.WithdrawProof{}},
+		{transferProof.MarshalProto(), &pb.TransferProof{}},
+		{feeProof.MarshalProto(), &pb.FeeTransferProof{}},
+		{MarshalPublicKeyProto(acc.Pk), &pb.PublicKey{}},
+		{addr.MarshalProto(), &pb.StealthAddress{}},
+		{out.MarshalProto(), &pb.StealthOutput{}},
+	} {
+		if err := proto.Unmarshal(c.b, c.m); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, len(c.m.ProtoReflect().GetUnknown()), 0)
+		checkProto(t, c.m.ProtoReflect(), seen)
+	}
+	for message, fields := range schema {
+		for _, field := range fields {
+			assert.Equal(t, seen[message+"."+field.name], true, message+"."+field.name)
+		}
+	}
+	var feeMessage pb.FeeTransferProof
+	assert.Equal(t, proto.Unmarshal(feeProof.MarshalProto(), &feeMessage), nil)
+	assert.Equal(t, feeMessage.GetFee(), uint64(3))
+
+	pk, err := UnmarshalPublicKeyProto(MarshalPublicKeyProto(acc.Pk))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, pk.Equal(acc.Pk), 1)
+	var decodedAddr StealthAddress
+	assert.Equal(t, decodedAddr.UnmarshalProto(addr.MarshalProto()), nil)
+	assert.Equal(t, decodedAddr.ViewPk.Equal(addr.ViewPk), 1)
+	assert.Equal(t, decodedAddr.SpendPk.Equal(addr.SpendPk), 1)
+	var decodedOut StealthOutput
+	assert.Equal(t, decodedOut.UnmarshalProto(out.MarshalProto()), nil)
+	assert.Equal(t, decodedOut.Serialize(), out.Serialize())
+	_, err = UnmarshalPublicKeyProto(nil)
+	assert.Equal(t, err != nil, true)
+
+	//the fee survives both encodings, the methods of the embedded TransferProof would drop it
+	var feeProto, feeJSON FeeTransferProof
+	assert.Equal(t, feeProto.UnmarshalProto(feeProof.MarshalProto()), nil)
+	assert.Equal(t, feeProto.Serialize(), feeProof.Serialize())
+	text, err := json.Marshal(feeProof)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(string(text), `"fee":3`), true)
+	assert.Equal(t, json.Unmarshal(text, &feeJSON), nil)
+	assert.Equal(t, feeJSON.Serialize(), feeProof.Serialize())
+	var plain TransferProof
+	assert.Equal(t, plain.UnmarshalProto(feeProof.MarshalProto()) != nil, true)
+}
+
+func TestCodecSerialization(t *testing.T) {
+	source := []byte("hello")
+	var acc Account
+	acc.Init(sha256.Sum256(source))
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	acc.Deposit(uint64(100))
+
+	proof, err := acc.GenWithdrawProof(sha512.Sum512(source), uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	burn := acc.GenBurnProof()
+	sink := NewZeroCopySink(nil)
+	proof.rangeProof.InnerProof.Serialization(sink)
+	proof.Serialization(sink)
+	burn.Serialization(sink)
+
+	reader := NewZeroCopySource(sink.Bytes())
+	var inner InnerProductProof
+	var withdraw WithdrawProof
+	var decodedBurn CommitmentProof
+	if err := inner.Deserialization(reader); err != nil {
+		t.Fatal(err)
+	}
+	if err := withdraw.Deserialization(reader); err != nil {
+		t.Fatal(err)
+	}
+	if err := decodedBurn.Deserialization(reader); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, reader.Len(), uint64(0))
+	assert.Equal(t, inner.Ls[0].Equal(proof.rangeProof.InnerProof.Ls[0]), 1)
+	assert.Equal(t, withdraw.Serialize(), proof.Serialize())
+	assert.Equal(t, decodedBurn.Serialize(), burn.Serialize())
+}
+
+func TestProofReader(t *testing.T) {
+	source := []byte("hello")
+	var acc Account
+	acc.Init(sha256.Sum256(source))
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	acc.Deposit(uint64(100))
+
+	trans := sha512.Sum512(source)
+	withdrawProof, err := acc.GenWithdrawProof(trans, uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	burnProof := acc.GenBurnProof()
+	var block []byte
+	block = append(block, EncodeProof(withdrawProof)...)
+	block = append(block, EncodeProofLegacy(&burnProof)...)
+	badOffset := uint64(len(block))
+	bad := EncodeProof(withdrawProof)
+	bad[len(bad)-1] ^= 0xff
+	block = append(block, bad...)
+
+	reader := NewProofReader(bytes.NewReader(block))
+	proof, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyWithDrawProof(trans, acc.Pk, uint64(60), proof.(*WithdrawProof)), true)
+	proof, err = reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, proof.Kind(), KindCommitmentProof)
+	_, err = reader.Next()
+	streamErr, ok := err.(*ProofStreamError)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, streamErr.Offset, badOffset)
+
+	reader = NewProofReader(bytes.NewReader(block[:badOffset]))
+	for i := 0; i < 2; i++ {
+		if _, err := reader.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = reader.Next()
+	assert.Equal(t, err, io.EOF)
+
+	reader = NewProofReader(bytes.NewReader(block[:badOffset-1]))
+	reader.Next()
+	_, err = reader.Next()
+	assert.Equal(t, err.(*ProofStreamError).Err, io.ErrUnexpectedEOF)
+}
+
+var updateVectors = flag.Bool("update-vectors", false, "rewrite testdata/vectors.json")
+
+func TestKnownAnswerVectors(t *testing.T) {
+	vectors, err := GenerateTestVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckTestVectors(vectors); err != nil {
+		t.Fatal(err)
+	}
+	text, err := json.MarshalIndent(vectors, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updateVectors {
+		if err := ioutil.WriteFile("testdata/vectors.json", append(text, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fixture, err := ioutil.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded []TestVector
+	if err := json.Unmarshal(fixture, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckTestVectors(recorded); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(fixture), string(text)+"\n")
+
+	//range prover nonces come from the account seed, two accounts proving the same remaining balance
+	//under the same transcript commit to the same bits with different blinding
+	var alice, bob Account
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	sc.Init()
+	alice.Deposit(uint64(100))
+	bob.Deposit(uint64(100))
+	trans := sha512.Sum512([]byte("nonces"))
+	aliceProof, err := alice.GenWithdrawProof(trans, uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobProof, err := bob.GenWithdrawProof(trans, uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, aliceProof.rangeProof.A.Equal(bobProof.rangeProof.A), 0)
+}
+
+func TestKeystore(t *testing.T) {
+	var acc Account
+	acc.Init(sha256.Sum256([]byte("hello")))
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	acc.Deposit(uint64(100))
+	acc.PubBalance = uint64(7)
+
+	var imported Account
+	if err := imported.ImportSk(acc.ExportSk()); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, imported.Pk.Equal(acc.Pk), 1)
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "account.json")
+	params := KDFParams{Time: 1, Memory: 1024, Threads: 1}
+	if err := acc.SaveKeystore(path, []byte("password"), params); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadKeystore(path, []byte("wrong"))
+	assert.Equal(t, err, ErrKeystorePassword)
+	restored, err := LoadKeystore(path, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, restored.Pk.Equal(acc.Pk), 1)
+	assert.Equal(t, restored.PubBalance, uint64(7))
+	assert.Equal(t, restored.Comm.Encode(), acc.Comm.Encode())
+	assert.Equal(t, ScalartoInt(restored.GetCommitmentBalance()), uint64(100))
+
+	trans := sha512.Sum512([]byte("hello"))
+	proof, err := restored.GenWithdrawProof(trans, uint64(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyWithDrawProof(trans, acc.Pk, uint64(60), proof), true)
+
+	//a file asking for more than the limits is rejected before argon2 runs
+	ks, err := acc.EncryptKeystore([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, params := range []KDFParams{
+		{Salt: ks.KDFParams.Salt, Time: 1, Memory: ^uint32(0), Threads: 1},
+		{Salt: ks.KDFParams.Salt, Time: ^uint32(0), Memory: 1024, Threads: 1},
+		{Salt: ks.KDFParams.Salt, Time: 1, Memory: 1024, Threads: 0},
+	} {
+		crafted := *ks
+		crafted.KDFParams = params
+		_, err = DecryptKeystore(&crafted, []byte("password"))
+		assert.Equal(t, err, ErrKeystoreKDFParams)
+	}
+	_, err = acc.EncryptKeystore([]byte("password"), KDFParams{Time: 1, Memory: MaxKDFMemory + 1, Threads: 1})
+	assert.Equal(t, err, ErrKeystoreKDFParams)
+}
+
+func TestHDDerivation(t *testing.T) {
+	mnemonic, err := NewMnemonic(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = MnemonicToSeed(mnemonic+" abandon", "")
+	assert.Equal(t, err != nil, true)
+	master, err := NewMasterKeyFromMnemonic(mnemonic, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//public derivation of non-hardened children matches the private derivation
+	parent, err := master.Derive("m/44'/7'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := parent.Derive("m/0/5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicChild, err := parent.Public().Derive("m/0/5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, publicChild.IsPrivate(), false)
+	assert.Equal(t, publicChild.Pk.Equal(child.Pk), 1)
+	assert.Equal(t, publicChild.ChainCode, child.ChainCode)
+	_, err = parent.Public().Child(HardenedIndex)
+	assert.Equal(t, err, ErrHardenedPublicDerivation)
+	for _, path := range []string{"", "0/1", "m/x", "m/2147483648", "m//1"} {
+		_, err = ParseDerivationPath(path)
+		assert.Equal(t, err, ErrInvalidDerivationPath)
+	}
+
+	var acc, again, sibling Account
+	assert.Equal(t, acc.InitFromKey(child), nil)
+	assert.Equal(t, again.InitFromKey(child), nil)
+	viewKey, _ := child.Child(ViewKeyIndex)
+	assert.Equal(t, acc.Pk.Equal(viewKey.Pk), 1)
+	assert.Equal(t, again.Pk.Equal(viewKey.Pk), 1)
+	assert.Equal(t, again.SpendPk.Equal(acc.SpendPk), 1)
+	//the view key and the extended public keys of the account key and of its parent do not give the spend key
+	viewSk := acc.ExportViewKey()
+	for _, chainCode := range [][32]byte{child.ChainCode, parent.ChainCode, viewKey.ChainCode} {
+		guess := acc.mult(deriveSpendKey(append(chainCode[:], viewSk[:]...)), acc.basePoint)
+		assert.Equal(t, guess.Equal(acc.SpendPk), 0)
+	}
+	var watcher Account
+	assert.Equal(t, watcher.ImportViewKey(viewSk), nil)
+	assert.Equal(t, watcher.Pk.Equal(acc.Pk), 1)
+	assert.Equal(t, watcher.CanSpend(), false)
+	//a restored account draws fresh nonces, the commitment randomness differs
+	assert.Equal(t, acc.Comm.Cr.Equal(again.Comm.Cr), 0)
+	siblingKey, _ := parent.Derive("m/0/6")
+	assert.Equal(t, sibling.InitFromKey(siblingKey), nil)
+	assert.Equal(t, sibling.Pk.Equal(acc.Pk), 0)
+
+	sc.Init()
+	sc.Register(acc.Pk, acc.Comm)
+	sc.Register(sibling.Pk, sibling.Comm)
+	acc.Deposit(uint64(100))
+	trans := sha512.Sum512([]byte("hd"))
+	proof, err := acc.GenTransferProof(trans, uint64(40), sibling.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyTransferProof(trans, proof, acc.Pk, sibling.Pk), true)
+
+	seed, err := MnemonicToInitSeed(mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var single Account
+	single.Init(seed)
+	assert.Equal(t, single.Pk.Equal(acc.Pk), 0)
+}
+
+func TestSpendAndViewKeys(t *testing.T) {
+	var alice, bob Account
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	sc.Init()
+	sc.Register(alice.Pk, alice.Comm)
+	sc.Register(bob.Pk, bob.Comm)
+	alice.Deposit(uint64(100))
+
+	//registering needs the account key and the spend key, a key of someone else is refused
+	var mallory Account
+	mallory.Init(sha256.Sum256([]byte("mallory")))
+	hijack, err := mallory.GenSpendKeyRegistration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.RegisterSpendKey(alice.Pk, hijack), ErrSpendKeyRegistration)
+	reg, err := alice.GenSpendKeyRegistration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	swapped := *reg
+	swapped.SpendPk, swapped.Spend = hijack.SpendPk, hijack.Spend
+	assert.Equal(t, sc.RegisterSpendKey(alice.Pk, &swapped), ErrSpendKeyRegistration)
+	assert.Equal(t, sc.RegisterSpendKey(alice.Pk, reg), nil)
+
+	var viewer Account
+	if err := viewer.ImportViewKey(alice.ExportViewKey()); err != nil {
+		t.Fatal(err)
+	}
+	viewer.Comm = alice.Comm
+	assert.Equal(t, ScalartoInt(viewer.GetCommitmentBalance()), uint64(100))
+	trans := sha512.Sum512([]byte("spend"))
+	_, err = viewer.GenTransferProof(trans, uint64(10), bob.Pk)
+	assert.Equal(t, err, ErrViewOnly)
+
+	//the registered spend key can only be replaced with its own signature, not with sk alone
+	_, err = viewer.GenSpendKeyRegistration()
+	assert.Equal(t, err, ErrViewOnly)
+	assert.Equal(t, sc.RegisterSpendKey(alice.Pk, reg), ErrSpendKeyRegistration)
+	var thief Account
+	thief.Init(sha256.Sum256([]byte("alice")))
+	thiefKey, _ := mallory.ExportSpendKey()
+	assert.Equal(t, thief.ImportSpendKey(thiefKey), nil)
+	takeover, err := thief.GenSpendKeyRegistration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.RegisterSpendKey(alice.Pk, takeover), ErrSpendKeyRegistration)
+	assert.Equal(t, sc.getSpendKey(alice.Pk).Equal(alice.SpendPk), 1)
+	newSpendKey := sha256.Sum256([]byte("alice rotated"))
+	newSpendKey[31] &= 0x0f
+	rotation, err := alice.GenSpendKeyRotation(newSpendKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.RegisterSpendKey(alice.Pk, rotation), nil)
+	assert.Equal(t, sc.RegisterSpendKey(alice.Pk, rotation), ErrSpendKeyRegistration)
+	assert.Equal(t, alice.ImportSpendKey(newSpendKey), nil)
+	assert.Equal(t, sc.getSpendKey(alice.Pk).Equal(alice.SpendPk), 1)
+	_, err = viewer.GenWithdrawProof(trans, uint64(10))
+	assert.Equal(t, err, ErrViewOnly)
+	_, err = viewer.ExportSpendKey()
+	assert.Equal(t, err, ErrViewOnly)
+
+	proof, err := alice.GenTransferProof(trans, uint64(30), bob.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the spend key is mandatory once registered
+	assert.Equal(t, sc.VerifyTransferProof(trans, proof, alice.Pk, bob.Pk), false)
+	auth, err := alice.AuthorizeSpend(trans, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded SpendAuthorization
+	assert.Equal(t, decoded.Deserialize(auth.Serialize()), nil)
+	assert.Equal(t, sc.VerifyAuthorizedTransferProof(trans, proof, &decoded, alice.Pk, bob.Pk), true)
+	otherTrans := sha512.Sum512([]byte("other"))
+	assert.Equal(t, sc.VerifyAuthorizedTransferProof(otherTrans, proof, auth, alice.Pk, bob.Pk), false)
+	forged, err := bob.AuthorizeSpend(trans, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyAuthorizedTransferProof(trans, proof, forged, alice.Pk, bob.Pk), false)
+
+	var bobViewer Account
+	if err := bobViewer.ImportViewKey(bob.ExportViewKey()); err != nil {
+		t.Fatal(err)
+	}
+	amount, err := bobViewer.DecryptTransfer(proof)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, amount, uint64(30))
+
+	withdrawProof, err := alice.GenWithdrawProof(trans, uint64(40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyWithDrawProof(trans, alice.Pk, uint64(40), withdrawProof), false)
+	auth, _ = alice.AuthorizeSpend(trans, withdrawProof)
+	assert.Equal(t, sc.VerifyAuthorizedWithdrawProof(trans, alice.Pk, uint64(40), withdrawProof, auth), true)
+
+	//the spend key survives the keystore, the view key alone stays view-only
+	spendKey, _ := alice.ExportSpendKey()
+	assert.Equal(t, viewer.ImportSpendKey(spendKey), nil)
+	assert.Equal(t, viewer.SpendPk.Equal(alice.SpendPk), 1)
+	params := KDFParams{Time: 1, Memory: 1024, Threads: 1}
+	ks, err := alice.EncryptKeystore([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := DecryptKeystore(ks, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, restored.CanSpend(), true)
+	assert.Equal(t, restored.SpendPk.Equal(alice.SpendPk), 1)
+	var viewOnly Account
+	viewOnly.ImportViewKey(alice.ExportViewKey())
+	viewOnly.Comm = alice.Comm
+	ks, err = viewOnly.EncryptKeystore([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err = DecryptKeystore(ks, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, restored.CanSpend(), false)
+	_, err = restored.GenWithdrawProof(trans, uint64(1))
+	assert.Equal(t, err, ErrViewOnly)
+}
+
+func TestAuditedTransfer(t *testing.T) {
+	var alice, bob, auditor, other Account
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	auditor.Init(sha256.Sum256([]byte("auditor")))
+	other.Init(sha256.Sum256([]byte("other auditor")))
+	sc.Init()
+	sc.Register(alice.Pk, alice.Comm)
+	sc.Register(bob.Pk, bob.Comm)
+	sc.RegisterAuditor(auditor.Pk)
+	alice.Deposit(uint64(100))
+
+	trans := sha512.Sum512([]byte("audit"))
+	proof, err := alice.GenTransferProof(trans, uint64(35), bob.Pk, auditor.Pk, other.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyTransferProof(trans, proof, alice.Pk, bob.Pk), true)
+	//other is not a registered auditor
+	assert.Equal(t, sc.VerifyAuditedTransferProof(trans, proof, nil, alice.Pk, bob.Pk, []*ristretto255.Element{auditor.Pk, other.Pk}), false)
+	sc.RegisterAuditor(other.Pk)
+	assert.Equal(t, sc.VerifyAuditedTransferProof(trans, proof, nil, alice.Pk, bob.Pk, []*ristretto255.Element{auditor.Pk, other.Pk}), true)
+	assert.Equal(t, sc.VerifyAuditedTransferProof(trans, proof, nil, alice.Pk, bob.Pk, []*ristretto255.Element{auditor.Pk}), false)
+
+	amount, err := auditor.AuditTransfer(proof)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, amount, uint64(35))
+	amount, _ = other.AuditTransfer(proof)
+	assert.Equal(t, amount, uint64(35))
+	_, err = bob.AuditTransfer(proof)
+	assert.Equal(t, err != nil, true)
+
+	//every encoding carries the auditor ciphertexts
+	var decoded, compact, fromJSON, fromProto TransferProof
+	assert.Equal(t, decoded.Deserialize(proof.Serialize()), nil)
+	assert.Equal(t, compact.DeserializeCompact(proof.SerializeCompact()), nil)
+	text, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, json.Unmarshal(text, &fromJSON), nil)
+	assert.Equal(t, fromProto.UnmarshalProto(proof.MarshalProto()), nil)
+	for _, p := range []*TransferProof{&decoded, &compact, &fromJSON, &fromProto} {
+		assert.Equal(t, p.Serialize(), proof.Serialize())
+		assert.Equal(t, sc.VerifyAuditedTransferProof(trans, p, nil, alice.Pk, bob.Pk, []*ristretto255.Element{auditor.Pk, other.Pk}), true)
+	}
+
+	//a ciphertext of a different amount is rejected
+	forged := *proof
+	forged.Auditors = append([]AuditorCiphertext(nil), proof.Auditors...)
+	forged.Auditors[0].Cl = new(ristretto255.Element).Add(forged.Auditors[0].Cl, sc.BasePoint)
+	assert.Equal(t, sc.VerifyTransferProof(trans, &forged, alice.Pk, bob.Pk), false)
+	//dropping a ciphertext changes the transcript
+	forged.Auditors = proof.Auditors[1:]
+	assert.Equal(t, sc.VerifyTransferProof(trans, &forged, alice.Pk, bob.Pk), false)
+
+	_, err = alice.GenTransferProof(trans, uint64(1), bob.Pk, make([]*ristretto255.Element, MaxAuditors+1)...)
+	assert.Equal(t, err, ErrTooManyAuditors)
+}
+
+func TestThresholdDecryption(t *testing.T) {
+	var alice, bob, committee Account
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	committee.Init(sha256.Sum256([]byte("committee")))
+	sc.Init()
+	sc.Register(alice.Pk, alice.Comm)
+	sc.Register(bob.Pk, bob.Comm)
+	alice.Deposit(uint64(100))
+
+	key, shares, err := SplitKey(committee.ExportSk(), 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, key.Pk.Equal(committee.Pk), 1)
+	_, _, err = SplitKey(committee.ExportSk(), 6, 5)
+	assert.Equal(t, err, ErrThresholdParams)
+
+	trans := sha512.Sum512([]byte("threshold"))
+	proof, err := alice.GenTransferProof(trans, uint64(42), bob.Pk, key.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comm, err := proof.AuditorCommitment(key.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decShares []*DecryptionShare
+	for _, i := range []int{4, 1, 3} {
+		var share KeyShare
+		assert.Equal(t, share.Deserialize(shares[i].Serialize()), nil)
+		var decShare DecryptionShare
+		assert.Equal(t, decShare.Deserialize(share.DecryptionShare(comm).Serialize()), nil)
+		assert.Equal(t, key.VerifyShare(comm, &decShare), true)
+		decShares = append(decShares, &decShare)
+	}
+	amount, err := key.Combine(comm, decShares, Upper)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, amount, uint64(42))
+	amount, err = key.Combine(comm, []*DecryptionShare{nil, decShares[0], nil, decShares[1], decShares[2]}, Upper)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, amount, uint64(42))
+
+	//two shares are not enough, a duplicate does not count twice
+	_, err = key.Combine(comm, []*DecryptionShare{decShares[0], decShares[1], decShares[1]}, Upper)
+	assert.Equal(t, err, ErrNotEnoughShares)
+
+	//a wrong share fails its DLEQ proof and is skipped
+	bad := *decShares[2]
+	bad.D = new(ristretto255.Element).Add(bad.D, sc.BasePoint)
+	assert.Equal(t, key.VerifyShare(comm, &bad), false)
+	_, err = key.Combine(comm, []*DecryptionShare{decShares[0], decShares[1], &bad}, Upper)
+	assert.Equal(t, err, ErrNotEnoughShares)
+	amount, _ = key.Combine(comm, []*DecryptionShare{&bad, decShares[0], shares[0].DecryptionShare(comm), decShares[1]}, Upper)
+	assert.Equal(t, amount, uint64(42))
+}
+
+func TestDistributedKeyGeneration(t *testing.T) {
+	const threshold, n = 3, 5
+	parties := make([]*DKGParticipant, n)
+	for i := range parties {
+		var err error
+		if parties[i], err = NewDKGParticipant(uint32(i+1), threshold, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//round 1, dealer 2 sends a wrong share to party 4 but justifies it, dealer 5 cheats party 1 and stays silent
+	var commitments []*DKGCommitment
+	var shares []*DKGShare
+	for _, p := range parties {
+		commitment, dealt, err := p.Round1()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded DKGCommitment
+		assert.Equal(t, deserialize(&decoded, serialize(commitment)), nil)
+		commitments = append(commitments, &decoded)
+		shares = append(shares, dealt...)
+	}
+	one, _ := InttoScalar(uint64(1))
+	for _, share := range shares {
+		if (share.Dealer == 2 && share.Recipient == 4) || (share.Dealer == 5 && share.Recipient == 1) {
+			share.Share = SumScalars(share.Share, one)
+		}
+	}
+	for _, p := range parties {
+		for _, commitment := range commitments {
+			assert.Equal(t, p.ReceiveCommitment(commitment), nil)
+		}
+	}
+	for _, share := range shares {
+		assert.Equal(t, parties[share.Recipient-1].ReceiveShare(share), nil)
+	}
+
+	var complaints []*DKGComplaint
+	for _, p := range parties {
+		complaints = append(complaints, p.Round2()...)
+	}
+	assert.Equal(t, len(complaints), 2)
+	var justifications []*DKGJustification
+	for _, p := range parties[:n-1] {
+		justifications = append(justifications, p.Justify(complaints)...)
+	}
+	assert.Equal(t, len(justifications), 1)
+
+	//complaints from outside 1..n or by the accused itself are ignored, f(0) of the dealer stays secret
+	bogus := []*DKGComplaint{{Accuser: 0, Accused: 3}, {Accuser: n + 1, Accused: 3}, {Accuser: 3, Accused: 3}}
+	assert.Equal(t, len(parties[2].Justify(bogus)), 0)
+	_, ok := parties[0].Transcript(append(bogus, complaints...), justifications).qualified()[3]
+	assert.Equal(t, ok, true)
+	var decodedComplaint DKGComplaint
+	assert.Equal(t, deserialize(&decodedComplaint, serialize(bogus[0])), ErrDKGMessage)
+	assert.Equal(t, deserialize(&decodedComplaint, serialize(bogus[2])), ErrDKGMessage)
+	var bogusTranscript DKGTranscript
+	assert.Equal(t, bogusTranscript.Deserialize(parties[0].Transcript(bogus[1:2], nil).Serialize()), ErrDKGMessage)
+
+	keys := make([]*ThresholdKey, n)
+	keyShares := make([]*KeyShare, n)
+	for i, p := range parties {
+		var err error
+		keys[i], keyShares[i], err = p.Finalize(complaints, justifications)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, keys[i].Pk.Equal(keys[0].Pk), 1)
+		assert.Equal(t, p.Transcript(complaints, justifications).Hash(), parties[0].Transcript(complaints, justifications).Hash())
+	}
+
+	//any observer derives the same key from the serialized transcript
+	var transcript DKGTranscript
+	assert.Equal(t, transcript.Deserialize(parties[2].Transcript(complaints, justifications).Serialize()), nil)
+	assert.Equal(t, transcript.Qualified(), []uint32{1, 2, 3, 4})
+	observed, err := transcript.ThresholdKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, observed.Pk.Equal(keys[0].Pk), 1)
+
+	//the key works as a threshold auditor key
+	var alice, bob Account
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	sc.Init()
+	sc.Register(alice.Pk, alice.Comm)
+	sc.Register(bob.Pk, bob.Comm)
+	alice.Deposit(uint64(100))
+	trans := sha512.Sum512([]byte("dkg"))
+	proof, err := alice.GenTransferProof(trans, uint64(17), bob.Pk, observed.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comm, _ := proof.AuditorCommitment(observed.Pk)
+	var decShares []*DecryptionShare
+	for _, i := range []int{0, 2, 4} {
+		decShares = append(decShares, keyShares[i].DecryptionShare(comm))
+	}
+	amount, err := observed.Combine(comm, decShares, Upper)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, amount, uint64(17))
+
+	_, _, err = parties[0].Round1()
+	assert.Equal(t, err, ErrDKGRound)
+}
+
+func TestMultisigProofs(t *testing.T) {
+	var members, bob Account
+	members.Init(sha256.Sum256([]byte("members")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	sc.Init()
+	key, shares, err := SplitKey(members.ExportSk(), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := InttoScalar(uint64(7))
+	hundred, _ := InttoScalar(uint64(100))
+	comm := &Commitment{
+		Cl: SumElements(new(ristretto255.Element).ScalarMult(hundred, sc.BasePoint), new(ristretto255.Element).ScalarMult(r, key.Pk)),
+		Cr: new(ristretto255.Element).ScalarMult(r, sc.BasePoint),
+	}
+	sc.Register(key.Pk, comm)
+	sc.Register(bob.Pk, bob.Comm)
+	balance, err := key.Combine(comm, []*DecryptionShare{shares[0].DecryptionShare(comm), shares[2].DecryptionShare(comm)}, Upper)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, balance, uint64(100))
+
+	//run the three rounds with the signers of shares
+	run := func(coordinator *MultisigCoordinator, signers []*MultisigSigner) (Codec, error) {
+		var commitments []*MultisigCommitment
+		for _, signer := range signers {
+			commitment, err := signer.Commit()
+			if err != nil {
+				return nil, err
+			}
+			commitments = append(commitments, commitment)
+		}
+		var nonces []*MultisigNonce
+		for _, signer := range signers {
+			nonce, err := signer.Reveal(commitments)
+			if err != nil {
+				return nil, err
+			}
+			var decoded MultisigNonce
+			if err := deserialize(&decoded, serialize(nonce)); err != nil {
+				return nil, err
+			}
+			nonces = append(nonces, &decoded)
+		}
+		var responses []*MultisigResponse
+		for _, signer := range signers {
+			response, err := signer.Respond(nonces)
+			if err != nil {
+				return nil, err
+			}
+			var decoded MultisigResponse
+			if err := deserialize(&decoded, serialize(response)); err != nil {
+				return nil, err
+			}
+			responses = append(responses, &decoded)
+		}
+		return coordinator.Finalize(commitments, nonces, responses)
+	}
+
+	trans := sha512.Sum512([]byte("multisig transfer"))
+	signerSet := []uint32{1, 3}
+	coordinator, request, err := NewMultisigTransfer(key, comm, balance, trans, uint64(30), bob.Pk, signerSet, members.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signers []*MultisigSigner
+	for _, share := range []*KeyShare{shares[0], shares[2]} {
+		signer, err := NewMultisigTransferSigner(key, share, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, signer)
+	}
+	_, err = NewMultisigTransferSigner(key, shares[1], request)
+	assert.Equal(t, err != nil, true)
+	proof, err := run(coordinator, signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transferProof := proof.(*TransferProof)
+	assert.Equal(t, sc.VerifyTransferProof(trans, transferProof, key.Pk, bob.Pk), true)
+	amount, _ := bob.DecryptTransfer(transferProof)
+	assert.Equal(t, amount, uint64(30))
+	amount, _ = members.AuditTransfer(transferProof)
+	assert.Equal(t, amount, uint64(30))
+	_, err = signers[0].Respond(nil)
+	assert.Equal(t, err, ErrMultisigRound)
+
+	//signers refuse a request whose ciphertexts do not match the amount
+	request.Amount = 31
+	_, err = NewMultisigTransferSigner(key, shares[0], request)
+	assert.Equal(t, err != nil, true)
+
+	trans = sha512.Sum512([]byte("multisig withdraw"))
+	coordinator, wdRequest, err := NewMultisigWithdraw(key, comm, balance, trans, uint64(60), []uint32{2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers = nil
+	for _, share := range []*KeyShare{shares[1], shares[2]} {
+		signer, err := NewMultisigWithdrawSigner(key, share, wdRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, signer)
+	}
+	proof, err = run(coordinator, signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyWithDrawProof(trans, key.Pk, uint64(60), proof.(*WithdrawProof)), true)
+	assert.Equal(t, sc.VerifyWithDrawProof(trans, key.Pk, uint64(61), proof.(*WithdrawProof)), false)
+
+	//a response computed with a wrong share is caught by the coordinator
+	coordinator, wdRequest, _ = NewMultisigWithdraw(key, comm, balance, trans, uint64(60), []uint32{2, 3})
+	signers = nil
+	for _, share := range []*KeyShare{shares[1], {Index: 3, sk: shares[0].sk}} {
+		signer, _ := NewMultisigWithdrawSigner(key, share, wdRequest)
+		signers = append(signers, signer)
+	}
+	_, err = run(coordinator, signers)
+	assert.Equal(t, err != nil, true)
+
+	//requests missing a part are rejected before any nonce is drawn
+	_, err = NewMultisigWithdrawSigner(key, shares[1], &MultisigWithdrawRequest{})
+	assert.Equal(t, err, ErrMultisigMessage)
+	_, err = NewMultisigTransferSigner(key, shares[1], &MultisigTransferRequest{})
+	assert.Equal(t, err, ErrMultisigMessage)
+	_, err = NewMultisigNullifierSigner(key, shares[1], &MultisigNullifierRequest{})
+	assert.Equal(t, err, ErrMultisigMessage)
+}
+
+func TestPendingRollover(t *testing.T) {
+	var alice, bob, carol Account
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	carol.Init(sha256.Sum256([]byte("carol")))
+	sc.Init()
+	sc.Register(bob.Pk, bob.Comm)
+	alice.Deposit(uint64(100))
+	carol.Deposit(uint64(50))
+
+	trans := sha512.Sum512([]byte("pending"))
+	proof, err := alice.GenTransferProof(trans, uint64(30), bob.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//carol pays alice before the proof of alice lands, the proof stays valid
+	carolProof, err := carol.GenTransferProof(trans, uint64(10), alice.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.ApplyTransfer(trans, carolProof, nil, carol.GenNullifier(trans, sc.Epoch, carolProof), carol.Pk, alice.Pk), true)
+	assert.Equal(t, sc.ApplyTransfer(trans, proof, nil, alice.GenNullifier(trans, sc.Epoch, proof), alice.Pk, bob.Pk), true)
+	pending, _ := alice.PendingBalance(sc.GetPending(alice.Pk))
+	assert.Equal(t, pending, uint64(10))
+	pending, _ = bob.PendingBalance(sc.GetPending(bob.Pk))
+	assert.Equal(t, pending, uint64(30))
+
+	//the credits become spendable in the next epoch
+	alice.Comm = sc.GetCommitment(alice.Pk)
+	assert.Equal(t, ScalartoInt(alice.GetCommitmentBalance()), uint64(70))
+	stale, _ := alice.GenWithdrawProof(trans, uint64(5))
+	sc.NextEpoch()
+	assert.Equal(t, sc.RollOver(alice.Pk), nil)
+	alice.Comm = sc.GetCommitment(alice.Pk)
+	assert.Equal(t, ScalartoInt(alice.GetCommitmentBalance()), uint64(80))
+	pending, _ = alice.PendingBalance(sc.GetPending(alice.Pk))
+	assert.Equal(t, pending, uint64(0))
+	assert.Equal(t, sc.ApplyWithdraw(trans, alice.Pk, uint64(5), stale, nil, alice.GenNullifier(trans, sc.Epoch, stale)), false)
+
+	wdProof, err := alice.GenWithdrawProof(trans, uint64(80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.ApplyWithdraw(trans, alice.Pk, uint64(80), wdProof, nil, alice.GenNullifier(trans, sc.Epoch, wdProof)), true)
+	assert.Equal(t, sc.PublicBalanceMap[pkKey(alice.Pk)], uint64(80))
+	alice.Comm = sc.GetCommitment(alice.Pk)
+	assert.Equal(t, ScalartoInt(alice.GetCommitmentBalance()), uint64(0))
+
+	//bob rolls over before his first transaction of the epoch
+	bob.Comm = sc.GetCommitment(bob.Pk)
+	assert.Equal(t, ScalartoInt(bob.GetCommitmentBalance()), uint64(0))
+	assert.Equal(t, sc.RollOver(bob.Pk), nil)
+	bob.Comm = sc.GetCommitment(bob.Pk)
+	assert.Equal(t, ScalartoInt(bob.GetCommitmentBalance()), uint64(30))
+	bobProof, err := bob.GenTransferProof(trans, uint64(1), alice.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobNullifier := bob.GenNullifier(trans, sc.Epoch, bobProof)
+	assert.Equal(t, sc.ApplyTransfer(trans, bobProof, nil, bobNullifier, bob.Pk, alice.Pk), true)
+}
+
+func TestNullifiers(t *testing.T) {
+	var alice, bob Account
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	sc.Init()
+	sc.Register(bob.Pk, bob.Comm)
+	alice.Deposit(uint64(100))
+
+	trans := sha512.Sum512([]byte("nullifier"))
+	proof, _ := alice.GenTransferProof(trans, uint64(10), bob.Pk)
+	nullifier := alice.GenNullifier(trans, sc.Epoch, proof)
+	var decoded Nullifier
+	assert.Equal(t, decoded.Deserialize(nullifier.Serialize()), nil)
+	assert.Equal(t, sc.VerifyNullifier(trans, alice.Pk, proof, &decoded), true)
+
+	//the nullifier is bound to the account, the epoch and the proof
+	assert.Equal(t, sc.VerifyNullifier(trans, bob.Pk, proof, nullifier), false)
+	other, _ := alice.GenTransferProof(trans, uint64(11), bob.Pk)
+	assert.Equal(t, sc.VerifyNullifier(trans, alice.Pk, other, nullifier), false)
+	assert.Equal(t, sc.ApplyTransfer(trans, proof, nil, alice.GenNullifier(trans, sc.Epoch+1, proof), alice.Pk, bob.Pk), false)
+	assert.Equal(t, sc.ApplyTransfer(trans, proof, nil, nil, alice.Pk, bob.Pk), false)
+
+	//a second spend in the same epoch reuses U and is rejected
+	assert.Equal(t, sc.ApplyTransfer(trans, proof, nil, nullifier, alice.Pk, bob.Pk), true)
+	alice.Comm = sc.GetCommitment(alice.Pk)
+	wdProof, _ := alice.GenWithdrawProof(trans, uint64(5))
+	wdNullifier := alice.GenNullifier(trans, sc.Epoch, wdProof)
+	assert.Equal(t, wdNullifier.U.Equal(nullifier.U), 1)
+	assert.Equal(t, sc.ApplyWithdraw(trans, alice.Pk, uint64(5), wdProof, nil, wdNullifier), false)
+	sc.NextEpoch()
+	assert.Equal(t, sc.ApplyWithdraw(trans, alice.Pk, uint64(5), wdProof, nil, wdNullifier), false)
+	wdNullifier = alice.GenNullifier(trans, sc.Epoch, wdProof)
+	assert.Equal(t, sc.ApplyWithdraw(trans, alice.Pk, uint64(5), wdProof, nil, wdNullifier), true)
+	assert.Equal(t, sc.ApplyWithdraw(trans, alice.Pk, uint64(5), wdProof, nil, wdNullifier), false)
+
+	//a multisig account derives the same nullifier jointly
+	key, shares, err := SplitKey(bob.ExportSk(), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &MultisigNullifierRequest{
+		Trans:   trans,
+		Epoch:   sc.Epoch,
+		Proof:   proof,
+		Signers: []uint32{1, 2},
+		Shares:  []*DecryptionShare{shares[0].EpochShare(sc.Epoch), nil, shares[1].EpochShare(sc.Epoch)},
+	}
+	coordinator, err := NewMultisigNullifier(key, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signers []*MultisigSigner
+	var commitments []*MultisigCommitment
+	for _, share := range shares[:2] {
+		signer, err := NewMultisigNullifierSigner(key, share, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commitment, _ := signer.Commit()
+		signers = append(signers, signer)
+		commitments = append(commitments, commitment)
+	}
+	var nonces []*MultisigNonce
+	for _, signer := range signers {
+		nonce, _ := signer.Reveal(commitments)
+		nonces = append(nonces, nonce)
+	}
+	var responses []*MultisigResponse
+	for _, signer := range signers {
+		response, _ := signer.Respond(nonces)
+		responses = append(responses, response)
+	}
+	result, err := coordinator.Finalize(commitments, nonces, responses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, result.(*Nullifier).U.Equal(bob.GenNullifier(trans, sc.Epoch, proof).U), 1)
+	assert.Equal(t, sc.VerifyNullifier(trans, bob.Pk, proof, result.(*Nullifier)), true)
+}
+
+func TestRingTransfer(t *testing.T) {
+	members := make([]Account, 4)
+	var ring []*ristretto255.Element
+	sc.Init()
+	for i, name := range []string{"alice", "bob", "carol", "dave"} {
+		members[i].Init(sha256.Sum256([]byte(name)))
+		sc.Register(members[i].Pk, members[i].Comm)
+		ring = append(ring, members[i].Pk)
+	}
+	alice, bob, carol := &members[0], &members[1], &members[2]
+	alice.Deposit(uint64(100))
+	reg, err := members[3].GenSpendKeyRegistration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.RegisterSpendKey(members[3].Pk, reg), nil)
+
+	_, err = sc.RingState(ring[:3])
+	assert.Equal(t, err, ErrRingSize)
+	state, err := sc.RingState(ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trans := sha512.Sum512([]byte("ring"))
+	_, err = alice.GenRingTransferProof(trans, state, 2, uint64(101))
+	assert.Equal(t, err != nil, true)
+	proof, err := alice.GenRingTransferProof(trans, state, 2, uint64(40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded RingTransferProof
+	assert.Equal(t, decoded.Deserialize(proof.Serialize()), nil)
+	assert.Equal(t, decoded.Serialize(), proof.Serialize())
+	assert.Equal(t, sc.VerifyRingTransferProof(trans, &decoded), true)
+
+	//the constant-time prover commits with the same values
+	rel := linearRelation{P: alice.Pk, bases: []*ristretto255.Element{sc.BasePoint, nil, alice.Pk}}
+	k := []*ristretto255.Scalar{alice.RandScalar(), nil, alice.RandScalar()}
+	assert.Equal(t, rel.commit(true, k).Equal(rel.commit(false, k)), 1)
+	assert.Equal(t, rel.recompute(true, k, k[0]).Equal(rel.recompute(false, k, k[0])), 1)
+	alice.SetConstantTime(true)
+	alice.SetDecryptionBound(uint64(1) << 10)
+	ctProof, err := alice.GenRingTransferProof(trans, state, 2, uint64(40))
+	alice.SetConstantTime(false)
+	alice.SetDecryptionBound(Upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyRingTransferProof(trans, ctProof), true)
+
+	//every ciphertext and the statement are bound
+	tampered := *proof
+	tampered.C = append([]*ristretto255.Element{}, proof.C...)
+	tampered.C[1] = new(ristretto255.Element).Add(tampered.C[1], sc.BasePoint)
+	assert.Equal(t, sc.VerifyRingTransferProof(trans, &tampered), false)
+	assert.Equal(t, sc.VerifyRingTransferProof(sha512.Sum512([]byte("other")), proof), false)
+	tampered = *proof
+	tampered.Ring = []*ristretto255.Element{ring[1], ring[0], ring[2], ring[3]}
+	assert.Equal(t, sc.VerifyRingTransferProof(trans, &tampered), false)
+
+	assert.Equal(t, sc.ApplyRingTransfer(trans, &decoded), true)
+	assert.Equal(t, sc.ApplyRingTransfer(trans, proof), false)
+	amount, _ := carol.DecryptRingTransfer(proof)
+	assert.Equal(t, amount, uint64(40))
+	amount, _ = bob.DecryptRingTransfer(proof)
+	assert.Equal(t, amount, uint64(0))
+	pending, _ := carol.PendingBalance(sc.GetPending(carol.Pk))
+	assert.Equal(t, pending, uint64(40))
+
+	//the nullifier is the one of ordinary spends, alice can not spend again in this epoch
+	alice.Comm = sc.GetCommitment(alice.Pk)
+	wdProof, _ := alice.GenWithdrawProof(trans, uint64(10))
+	assert.Equal(t, sc.ApplyWithdraw(trans, alice.Pk, uint64(10), wdProof, nil, alice.GenNullifier(trans, sc.Epoch, wdProof)), false)
+
+	sc.NextEpoch()
+	state, _ = sc.RingState(ring)
+	balance, _ := alice.decryptAmount(state.Comms[0].Cl, state.Comms[0].Cr)
+	assert.Equal(t, balance, uint64(60))
+	proof, err = carol.GenRingTransferProof(trans, state, 3, uint64(15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.ApplyRingTransfer(trans, proof), true)
+	state, _ = sc.RingState(ring)
+	balance, _ = carol.decryptAmount(state.Comms[2].Cl, state.Comms[2].Cr)
+	assert.Equal(t, balance, uint64(40))
+	sc.NextEpoch()
+	state, _ = sc.RingState(ring)
+	balance, _ = carol.decryptAmount(state.Comms[2].Cl, state.Comms[2].Cr)
+	assert.Equal(t, balance, uint64(25))
+
+	//dave registered a spend key, a view-only copy of dave can not send from the ring
+	var viewer Account
+	viewer.Init(sha256.Sum256([]byte("dave")))
+	assert.Equal(t, viewer.ImportViewKey(members[3].ExportViewKey()), nil)
+	_, err = viewer.GenRingTransferProof(trans, state, 0, uint64(1))
+	assert.Equal(t, err, ErrViewOnly)
+}
+
+func TestRingTransferForgery(t *testing.T) {
+	members := make([]Account, 4)
+	var ring []*ristretto255.Element
+	sc.Init()
+	for i := range members {
+		members[i].Init(sha256.Sum256([]byte{'r', byte(i)}))
+		sc.Register(members[i].Pk, members[i].Comm)
+		ring = append(ring, members[i].Pk)
+	}
+	state, err := sc.RingState(ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//without any secret key, members 0 and 1 pose as recipients of a zero amount, their ciphertexts carry
+	//+delta*H and -delta*H and their openings of Vb differ by delta, so nobody sends and U is random
+	var mallory Account
+	mallory.Init(sha256.Sum256([]byte("mallory")))
+	g, h := sc.BasePoint, sc.rangeProver.H
+	gEpoch := EpochBase(state.Epoch)
+	zero := new(ristretto255.Scalar).Zero()
+	r, tau, taub, delta := mallory.RandScalar(), mallory.RandScalar(), mallory.RandScalar(), mallory.RandScalar()
+	deltaH := new(ristretto255.Element).ScalarMult(delta, h)
+	proof := &RingTransferProof{
+		Ring: ring,
+		D:    new(ristretto255.Element).ScalarMult(r, g),
+		V:    new(ristretto255.Element).ScalarMult(tau, h),
+		Vb:   new(ristretto255.Element).ScalarMult(taub, h),
+		U:    new(ristretto255.Element).ScalarMult(mallory.RandScalar(), gEpoch),
+	}
+	witnesses := make([]ringWitness, len(ring))
+	rho := zero
+	for i, y := range ring {
+		ci := new(ristretto255.Element).ScalarMult(r, y)
+		rhoi := mallory.RandScalar()
+		rho = SumScalars(rho, rhoi)
+		proof.B = append(proof.B, new(ristretto255.Element).ScalarMult(rhoi, h))
+		switch i {
+		case 0:
+			ci = SumElements(ci, deltaH)
+			witnesses[i] = ringWitness{ringRecipient, []*ristretto255.Scalar{r, zero, new(ristretto255.Scalar).Subtract(taub, delta), rhoi}}
+		case 1:
+			ci = SumElements(ci, new(ristretto255.Element).Negate(deltaH))
+			witnesses[i] = ringWitness{ringRecipient, []*ristretto255.Scalar{r, zero, SumScalars(taub, delta), rhoi}}
+		default:
+			witnesses[i] = ringWitness{ringUntouched, []*ristretto255.Scalar{r, rhoi}}
+		}
+		proof.C = append(proof.C, ci)
+	}
+	trans := sha512.Sum512([]byte("ring forgery"))
+	prover, _ := NewRangeProver(RANGEBITS, sha256.Sum256([]byte("mallory prover")))
+	rangeTrans := proof.rangeTrans(trans, gEpoch)
+	rangeTrans, proof.balanceProof, err = prover.GenRangeProof(rangeTrans, ElgamalCommitment{g: g, h: h, v: zero, gamma: tau, comm: proof.V})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rangeTrans, proof.amountProof, err = prover.GenRangeProof(rangeTrans, ElgamalCommitment{g: g, h: h, v: zero, gamma: taub, comm: proof.Vb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mallory.proveRing(rangeTrans, &ringStatement{state: state, proof: proof, g: g, h: h, gEpoch: gEpoch}, witnesses, r, rho)
+	assert.Equal(t, sc.VerifyRingTransferProof(trans, proof), false)
+	assert.Equal(t, sc.ApplyRingTransfer(trans, proof), false)
+	assert.Equal(t, sc.GetPending(ring[0]) == nil || sc.GetPending(ring[0]).Cl.Equal(new(ristretto255.Element).Zero()) == 1, true)
+}
+
+func TestMultiTransfer(t *testing.T) {
+	accounts := make([]Account, 4)
+	sc.Init()
+	for i, name := range []string{"alice", "bob", "carol", "dave"} {
+		accounts[i].Init(sha256.Sum256([]byte(name)))
+		sc.Register(accounts[i].Pk, accounts[i].Comm)
+	}
+	alice := &accounts[0]
+	alice.Deposit(uint64(100))
+	sc.CommitmentMap[pkKey(alice.Pk)] = alice.Comm
+	recipients := []*ristretto255.Element{accounts[1].Pk, accounts[2].Pk, accounts[3].Pk}
+	trans := sha512.Sum512([]byte("multi"))
+
+	_, err := alice.GenMultiTransferProof(trans, recipients, []uint64{50, 30, 21})
+	assert.Equal(t, err != nil, true)
+	_, err = alice.GenMultiTransferProof(trans, []*ristretto255.Element{alice.Pk}, []uint64{1})
+	assert.Equal(t, err != nil, true)
+	proof, err := alice.GenMultiTransferProof(trans, recipients, []uint64{50, 30, 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeProof(EncodeProof(proof))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, decoded.Serialize(), proof.Serialize())
+	assert.Equal(t, sc.VerifyMultiTransferProof(trans, decoded.(*MultiTransferProof), alice.Pk), true)
+
+	//the amounts of every recipient and the transcript are bound
+	tampered := *proof
+	tampered.Recipients = append([]RecipientCiphertext{}, proof.Recipients...)
+	tampered.Recipients[1].CPrimeComm.Cl = new(ristretto255.Element).Add(tampered.Recipients[1].CPrimeComm.Cl, sc.BasePoint)
+	assert.Equal(t, sc.VerifyMultiTransferProof(trans, &tampered, alice.Pk), false)
+	tampered.Recipients = proof.Recipients[:2]
+	assert.Equal(t, sc.VerifyMultiTransferProof(trans, &tampered, alice.Pk), false)
+	assert.Equal(t, sc.VerifyMultiTransferProof(sha512.Sum512([]byte("other")), proof, alice.Pk), false)
+
+	nullifier := alice.GenNullifier(trans, sc.Epoch, proof)
+	assert.Equal(t, sc.ApplyMultiTransfer(trans, proof, nil, nullifier, alice.Pk), true)
+	assert.Equal(t, sc.ApplyMultiTransfer(trans, proof, nil, nullifier, alice.Pk), false)
+	for i, want := range []uint64{50, 30, 20} {
+		amount, _ := accounts[i+1].DecryptMultiTransfer(proof)
+		assert.Equal(t, amount, want)
+		pending, _ := accounts[i+1].PendingBalance(sc.GetPending(accounts[i+1].Pk))
+		assert.Equal(t, pending, want)
+	}
+	balance, _ := alice.decryptAmount(sc.GetCommitment(alice.Pk).Cl, sc.GetCommitment(alice.Pk).Cr)
+	assert.Equal(t, balance, uint64(0))
+
+	//an unregistered recipient aborts the whole transfer
+	sc.NextEpoch()
+	bob := &accounts[1]
+	assert.Equal(t, sc.RollOver(bob.Pk), nil)
+	bob.Comm = sc.GetCommitment(bob.Pk)
+	var eve Account
+	eve.Init(sha256.Sum256([]byte("eve")))
+	proof, err = bob.GenMultiTransferProof(trans, []*ristretto255.Element{alice.Pk, eve.Pk}, []uint64{10, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.ApplyMultiTransfer(trans, proof, nil, bob.GenNullifier(trans, sc.Epoch, proof), bob.Pk), false)
+	pending, _ := alice.PendingBalance(sc.GetPending(alice.Pk))
+	assert.Equal(t, pending, uint64(0))
+}
+
+func TestMultiTransferBinding(t *testing.T) {
+	accounts := make([]Account, MaxRecipients+1)
+	sc.Init()
+	for i := range accounts {
+		accounts[i].Init(sha256.Sum256([]byte{'m', byte(i)}))
+		sc.Register(accounts[i].Pk, accounts[i].Comm)
+	}
+	alice := &accounts[0]
+	alice.Deposit(uint64(100))
+	sc.CommitmentMap[pkKey(alice.Pk)] = alice.Comm
+	trans := sha512.Sum512([]byte("multi binding"))
+
+	//MaxRecipients recipients aggregate MaxAggregation values, the proof survives the envelope
+	var recipients []*ristretto255.Element
+	var amounts []uint64
+	for i := 1; i <= MaxRecipients; i++ {
+		recipients = append(recipients, accounts[i].Pk)
+		amounts = append(amounts, uint64(10))
+	}
+	assert.Equal(t, aggCount(MaxRecipients), uint64(MaxAggregation))
+	proof, err := alice.GenMultiTransferProof(trans, recipients, amounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeProof(EncodeProof(proof))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, decoded.Serialize(), proof.Serialize())
+	assert.Equal(t, sc.VerifyMultiTransferProof(trans, decoded.(*MultiTransferProof), alice.Pk), true)
+
+	//ciphertexts changed after z along its weights keep the weighted sum, they must not verify:
+	//bob would receive 1000 and carol a junk value that pays for it
+	bob, carol := &accounts[1], &accounts[2]
+	forged := &MultiTransferProof{}
+	var rs []*ristretto255.Scalar
+	for _, recipient := range []*ristretto255.Element{bob.Pk, carol.Pk} {
+		b, _ := InttoScalar(uint64(10))
+		r, cComm := alice.Commit(b)
+		rs = append(rs, r)
+		forged.Recipients = append(forged.Recipients, RecipientCiphertext{
+			Recipient:  recipient,
+			CComm:      cComm,
+			CPrimeComm: Commitment{Cl: SumElements(alice.mult(b, alice.basePoint), alice.mult(r, recipient)), Cr: cComm.Cr},
+		})
+	}
+	values := []uint64{10, 10, 80, 0}
+	sigmaRangeProof, z, rangeTrans, err := alice.rangeProver.genAggSigmaRangeProof(forged.bind(trans, alice.Pk, alice.Comm), values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.sigmaRangeProof = sigmaRangeProof
+	powersOfZ := PowersList(z, 5)
+	delta0, _ := InttoScalar(uint64(990))
+	a0 := SumScalars(powersOfZ[2], new(ristretto255.Scalar).Negate(powersOfZ[4]))
+	a1 := SumScalars(powersOfZ[3], new(ristretto255.Scalar).Negate(powersOfZ[4]))
+	delta1 := new(ristretto255.Scalar).Negate(Mul(delta0, a0, new(ristretto255.Scalar).Invert(a1)))
+	for j, delta := range []*ristretto255.Scalar{delta0, delta1} {
+		shift := new(ristretto255.Element).ScalarMult(delta, alice.basePoint)
+		rc := &forged.Recipients[j]
+		rc.CComm.Cl = new(ristretto255.Element).Add(rc.CComm.Cl, shift)
+		rc.CPrimeComm.Cl = new(ristretto255.Element).Add(rc.CPrimeComm.Cl, shift)
+	}
+	alice.proveMultiTransfer(forged, rangeTrans, z, values, rs)
+	assert.Equal(t, sc.VerifyMultiTransferProof(trans, forged, alice.Pk), false)
+	assert.Equal(t, sc.ApplyMultiTransfer(trans, forged, nil, alice.GenNullifier(trans, sc.Epoch, forged), alice.Pk), false)
+	pending, _ := bob.PendingBalance(sc.GetPending(bob.Pk))
+	assert.Equal(t, pending, uint64(0))
+}
+
+func TestFeeTransfer(t *testing.T) {
+	var alice, bob, miner Account
+	sc.Init()
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	miner.Init(sha256.Sum256([]byte("miner")))
+	sc.Register(bob.Pk, bob.Comm)
+	sc.Register(miner.Pk, miner.Comm)
+	alice.Deposit(uint64(100))
+	trans := sha512.Sum512([]byte("fee"))
+
+	_, err := alice.GenFeeTransferProof(trans, uint64(96), uint64(5), bob.Pk)
+	assert.Equal(t, err != nil, true)
+	proof, err := alice.GenFeeTransferProof(trans, uint64(60), uint64(5), bob.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeProof(EncodeProof(proof))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, decoded.Serialize(), proof.Serialize())
+	assert.Equal(t, sc.VerifyFeeTransferProof(trans, decoded.(*FeeTransferProof), alice.Pk, bob.Pk), true)
+	var legacy FeeTransferProof
+	assert.Equal(t, legacy.Deserialize(proof.Serialize()), nil)
+	assert.Equal(t, sc.VerifyFeeTransferProof(trans, &legacy, alice.Pk, bob.Pk), true)
+
+	//the fee is bound, a plain transfer proof does not verify against the balance after the fee
+	tampered := *proof
+	tampered.Fee = 4
+	assert.Equal(t, sc.VerifyFeeTransferProof(trans, &tampered, alice.Pk, bob.Pk), false)
+	assert.Equal(t, sc.VerifyTransferProof(trans, &proof.TransferProof, alice.Pk, bob.Pk), false)
+
+	nullifier := alice.GenNullifier(trans, sc.Epoch, proof)
+	assert.Equal(t, sc.ApplyFeeTransfer(trans, proof, nil, nullifier, alice.Pk, bob.Pk), false)
+	sc.SetFeeAccount(miner.Pk)
+	assert.Equal(t, sc.ApplyFeeTransfer(trans, proof, nil, nullifier, alice.Pk, bob.Pk), true)
+	assert.Equal(t, sc.ApplyFeeTransfer(trans, proof, nil, nullifier, alice.Pk, bob.Pk), false)
+	assert.Equal(t, sc.PublicBalanceMap[pkKey(miner.Pk)], uint64(5))
+	pending, _ := bob.PendingBalance(sc.GetPending(bob.Pk))
+	assert.Equal(t, pending, uint64(60))
+	balance, _ := alice.decryptAmount(sc.GetCommitment(alice.Pk).Cl, sc.GetCommitment(alice.Pk).Cr)
+	assert.Equal(t, balance, uint64(35))
+}
+
+func TestMemo(t *testing.T) {
+	var alice, bob, carol Account
+	sc.Init()
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	carol.Init(sha256.Sum256([]byte("carol")))
+	sc.Register(bob.Pk, bob.Comm)
+	alice.Deposit(uint64(100))
+	trans := sha512.Sum512([]byte("memo"))
+
+	_, err := alice.SealMemo(bob.Pk, make([]byte, MaxMemoSize+1))
+	assert.Equal(t, err, ErrMemoSize)
+	memo, err := alice.SealMemo(bob.Pk, []byte("invoice 42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := alice.GenMemoTransferProof(trans, uint64(10), bob.Pk, memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Memo
+	assert.Equal(t, decoded.Deserialize(memo.Serialize()), nil)
+	assert.Equal(t, sc.VerifyMemoTransferProof(trans, proof, &decoded, alice.Pk, bob.Pk), true)
+	plaintext, err := bob.OpenMemo(&decoded)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(plaintext), "invoice 42")
+	_, err = carol.OpenMemo(&decoded)
+	assert.Equal(t, err, ErrMemo)
+
+	//a swapped or altered memo invalidates the proof
+	other, _ := alice.SealMemo(bob.Pk, []byte("invoice 43"))
+	assert.Equal(t, sc.VerifyMemoTransferProof(trans, proof, other, alice.Pk, bob.Pk), false)
+	assert.Equal(t, sc.VerifyMemoTransferProof(trans, proof, nil, alice.Pk, bob.Pk), false)
+	assert.Equal(t, sc.VerifyTransferProof(trans, proof, alice.Pk, bob.Pk), false)
+	decoded.Ciphertext[0] ^= 1
+	assert.Equal(t, sc.VerifyMemoTransferProof(trans, proof, &decoded, alice.Pk, bob.Pk), false)
+	_, err = bob.OpenMemo(&decoded)
+	assert.Equal(t, err, ErrMemo)
+
+	//a memo sealed to someone else than the recipient is refused, Bob could not read it
+	toCarol, _ := alice.SealMemo(carol.Pk, []byte("invoice 42"))
+	_, err = alice.GenMemoTransferProof(trans, uint64(10), bob.Pk, toCarol)
+	assert.Equal(t, err, ErrMemoRecipient)
+	misdirected, err := alice.GenTransferProof(toCarol.Bind(trans), uint64(10), bob.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.VerifyMemoTransferProof(trans, misdirected, toCarol, alice.Pk, bob.Pk), false)
+	assert.Equal(t, sc.ApplyMemoTransfer(trans, misdirected, nil, alice.GenNullifier(toCarol.Bind(trans), sc.Epoch, misdirected), toCarol, alice.Pk, bob.Pk), false)
+
+	assert.Equal(t, sc.ApplyMemoTransfer(trans, proof, nil, alice.GenNullifier(memo.Bind(trans), sc.Epoch, proof), memo, alice.Pk, bob.Pk), true)
+}
+
+func TestAmountHints(t *testing.T) {
+	var alice, bob, carol Account
+	sc.Init()
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	carol.Init(sha256.Sum256([]byte("carol")))
+	sc.Register(bob.Pk, bob.Comm)
+	alice.Deposit(uint64(100))
+	depositHint, err := alice.DepositHint(uint64(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Account
+	restored.Init(sha256.Sum256([]byte("alice")))
+	restored.SetCommitment(alice.Comm, depositHint)
+	assert.Equal(t, restored.cachedBalance, uint64(100))
+	assert.Equal(t, string(restored.cachedComm), string(alice.Comm.Encode()))
+
+	trans := sha512.Sum512([]byte("hint"))
+	proof, err := alice.GenTransferProof(trans, uint64(30), bob.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hints, err := alice.GenTransferHints(proof, uint64(30), bob.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded TransferHints
+	assert.Equal(t, decoded.Deserialize(hints.Serialize()), nil)
+	amount, ok := bob.OpenAmountHint(&proof.CPrimeComm, &decoded.Amount)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, amount, uint64(30))
+	_, ok = carol.OpenAmountHint(&proof.CPrimeComm, &decoded.Amount)
+	assert.Equal(t, ok, false)
+
+	//without sk a guess can not be tested, the ElGamal secret Cl - v*G of the right guess does not open the hint
+	var r [32]byte
+	copy(r[:], decoded.Amount[:32])
+	hintR, err := ElementFromBytes(r)
+	assert.Equal(t, err, nil)
+	masked := binary.LittleEndian.Uint64(decoded.Amount[32:])
+	matches := 0
+	for v := uint64(0); v < 1000; v++ {
+		s, _ := InttoScalar(v)
+		shared := new(ristretto255.Element).Subtract(proof.CPrimeComm.Cl, new(ristretto255.Element).ScalarMult(s, sc.BasePoint))
+		if masked^hintPad(shared, hintR, proof.CPrimeComm.Cr) == v {
+			matches++
+		}
+	}
+	assert.Equal(t, matches, 0)
+
+	//a forged hint is detected and the discrete log is solved instead
+	forged := decoded.Amount
+	forged[0] ^= 1
+	_, ok = bob.OpenAmountHint(&proof.CPrimeComm, &forged)
+	assert.Equal(t, ok, false)
+	amount, err = bob.DecryptCommitment(&proof.CPrimeComm, &forged)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, amount, uint64(30))
+	amount, _ = bob.DecryptCommitment(&proof.CPrimeComm, nil)
+	assert.Equal(t, amount, uint64(30))
+
+	assert.Equal(t, sc.ApplyTransfer(trans, proof, nil, alice.GenNullifier(trans, sc.Epoch, proof), alice.Pk, bob.Pk), true)
+	restored.SetCommitment(sc.GetCommitment(alice.Pk), &decoded.Balance)
+	assert.Equal(t, restored.cachedBalance, uint64(70))
+	assert.Equal(t, ScalartoInt(restored.GetCommitmentBalance()), uint64(70))
+}
+
+func TestStealthTransfer(t *testing.T) {
+	var alice, bob, carol Account
+	sc.Init()
+	alice.Init(sha256.Sum256([]byte("alice")))
+	bob.Init(sha256.Sum256([]byte("bob")))
+	carol.Init(sha256.Sum256([]byte("carol")))
+	sc.Register(carol.Pk, carol.Comm)
+	alice.Deposit(uint64(100))
+	addr, err := bob.StealthAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//two payments to the same address use unrelated one-time keys
+	out := alice.GenStealthOutput(addr)
+	other := alice.GenStealthOutput(addr)
+	assert.Equal(t, out.OneTimePk.Equal(other.OneTimePk), 0)
+	assert.Equal(t, out.OneTimePk.Equal(bob.Pk), 0)
+
+	trans := sha512.Sum512([]byte("stealth"))
+	proof, err := alice.GenTransferProof(trans, uint64(40), out.OneTimePk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.ApplyTransfer(trans, proof, nil, alice.GenNullifier(trans, sc.Epoch, proof), alice.Pk, out.OneTimePk), false)
+	assert.Equal(t, sc.ApplyStealthTransfer(trans, proof, nil, nil, alice.Pk, out), false)
+	assert.Equal(t, sc.GetCommitment(out.OneTimePk) == nil, true)
+	assert.Equal(t, sc.ApplyStealthTransfer(trans, proof, nil, alice.GenNullifier(trans, sc.Epoch, proof), alice.Pk, out), true)
+	assert.Equal(t, len(sc.StealthOutputs()), 1)
+
+	var decoded StealthOutput
+	assert.Equal(t, decoded.Deserialize(sc.StealthOutputs()[0].Serialize()), nil)
+	assert.Equal(t, bob.ScanStealth(&decoded), true)
+	assert.Equal(t, carol.ScanStealth(&decoded), false)
+	_, err = carol.StealthAccount(&decoded)
+	assert.Equal(t, err, ErrNotStealthRecipient)
+
+	//the view key scans but can not recover the one-time key
+	var watcher Account
+	watcher.Init(sha256.Sum256([]byte("bob")))
+	assert.Equal(t, watcher.ImportViewKey(bob.ExportViewKey()), nil)
+	watcher.SpendPk = addr.SpendPk
+	assert.Equal(t, watcher.ScanStealth(&decoded), true)
+	_, err = watcher.StealthAccount(&decoded)
+	assert.Equal(t, err, ErrViewOnly)
+
+	//the view key reads the amount from the hint sealed to it, not from the ciphertext
+	viewHint, err := alice.SealAmountHint(addr.ViewPk, &proof.CPrimeComm, uint64(40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, ok := watcher.OpenStealthAmount(&decoded, &proof.CPrimeComm, viewHint)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, v, uint64(40))
+	_, ok = carol.OpenStealthAmount(&decoded, &proof.CPrimeComm, viewHint)
+	assert.Equal(t, ok, false)
+	_, ok = bob.OpenAmountHint(&proof.CPrimeComm, viewHint)
+	assert.Equal(t, ok, false)
+
+	oneTime, err := bob.StealthAccount(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, oneTime.Pk.Equal(out.OneTimePk), 1)
+	v, err = oneTime.DecryptCommitment(&proof.CPrimeComm, viewHint)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, v, uint64(40))
+	sc.NextEpoch()
+	assert.Equal(t, sc.RollOver(oneTime.Pk), nil)
+	oneTime.Comm = sc.GetCommitment(oneTime.Pk)
+	assert.Equal(t, ScalartoInt(oneTime.GetCommitmentBalance()), uint64(40))
+	wdProof, err := oneTime.GenWithdrawProof(trans, uint64(15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.ApplyWithdraw(trans, oneTime.Pk, uint64(15), wdProof, nil, oneTime.GenNullifier(trans, sc.Epoch, wdProof)), true)
+	sc.NextEpoch()
+	assert.Equal(t, sc.RollOver(oneTime.Pk), nil)
+	oneTime.Comm = sc.GetCommitment(oneTime.Pk)
+	proof, err = oneTime.GenTransferProof(trans, uint64(25), carol.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sc.ApplyTransfer(trans, proof, nil, oneTime.GenNullifier(trans, sc.Epoch, proof), oneTime.Pk, carol.Pk), true)
+	pending, _ := carol.PendingBalance(sc.GetPending(carol.Pk))
+	assert.Equal(t, pending, uint64(25))
+}
+
+func TestAddress(t *testing.T) {
+	var alice Account
+	alice.Init(sha256.Sum256([]byte("alice")))
+	addr := alice.Address(Mainnet)
+	assert.Equal(t, strings.HasPrefix(addr, "xv1"), true)
+	assert.Equal(t, strings.HasPrefix(alice.Address(Testnet), "txv1"), true)
+	network, pk, err := DecodeAddress(addr)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, network, Mainnet)
+	assert.Equal(t, pk.Equal(alice.Pk), 1)
+	_, pk, err = DecodeAddress(strings.ToUpper(addr))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, pk.Equal(alice.Pk), 1)
+	_, _, err = DecodeAddress(strings.ToUpper(addr[:10]) + addr[10:])
+	assert.Equal(t, err, ErrAddressFormat)
+
+	//BIP-350 vectors
+	for _, valid := range []string{"A1LQFN3A", "a1lqfn3a", "abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx"} {
+		_, _, err = decodeBech32m(valid)
+		assert.Equal(t, err, nil)
+	}
+	_, _, err = decodeBech32m("a1lqfn3q")
+	assert.Equal(t, err, ErrAddressChecksum)
+
+	typo := []byte(addr)
+	if typo[20] == 'q' {
+		typo[20] = 'p'
+	} else {
+		typo[20] = 'q'
+	}
+	_, _, err = DecodeAddress(string(typo))
+	assert.Equal(t, err, ErrAddressChecksum)
+	_, _, err = DecodeAddress(EncodeAddress(Mainnet, new(ristretto255.Element).Zero()))
+	assert.Equal(t, err, ErrIdentityAddress)
+	nonCanonical := make([]byte, 32)
+	for i := range nonCanonical {
+		nonCanonical[i] = 0xff
+	}
+	_, _, err = DecodeAddress(encodeBech32m(string(Mainnet), nonCanonical))
+	assert.Equal(t, err, ErrInvalidElement)
+	_, _, err = DecodeAddress(encodeBech32m("yv", alice.Pk.Encode(nil)))
+	assert.Equal(t, err, ErrAddressNetwork)
+
+	stealth, _ := alice.StealthAddress()
+	encoded := stealth.Encode(Testnet)
+	assert.Equal(t, strings.HasPrefix(encoded, "txvs1"), true)
+	network, decoded, err := DecodeStealthAddress(encoded)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, network, Testnet)
+	assert.Equal(t, decoded.ViewPk.Equal(alice.Pk), 1)
+	assert.Equal(t, decoded.SpendPk.Equal(alice.SpendPk), 1)
+	_, _, err = DecodeAddress(encoded)
+	assert.Equal(t, err, ErrAddressKind)
+	_, _, err = DecodeStealthAddress(addr)
+	assert.Equal(t, err, ErrAddressKind)
+
+	//JSON writes addresses and still reads hex public keys
+	text, err := json.Marshal(stealth)
+	assert.Equal(t, err, nil)
+	var fromJSON StealthAddress
+	assert.Equal(t, json.Unmarshal(text, &fromJSON), nil)
+	assert.Equal(t, fromJSON.SpendPk.Equal(alice.SpendPk), 1)
+	pk, err = pkFromJSON(elementToHex(alice.Pk))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, pk.Equal(alice.Pk), 1)
+	ks, err := alice.EncryptKeystore([]byte("password"), KDFParams{Time: 1, Memory: 64, Threads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ks.Pk, addr)
+	restored, err := DecryptKeystore(ks, []byte("password"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, restored.Pk.Equal(alice.Pk), 1)
+
+	//addresses of another network are not accepted where DefaultNetwork is expected
+	_, err = pkFromJSON(alice.Address(Testnet))
+	assert.Equal(t, err, ErrWrongNetwork)
+	assert.Equal(t, json.Unmarshal([]byte(`"`+encoded+`"`), &fromJSON), ErrWrongNetwork)
+	DefaultNetwork = Testnet
+	ks, err = alice.EncryptKeystore([]byte("password"), KDFParams{Time: 1, Memory: 64, Threads: 1})
+	DefaultNetwork = Mainnet
+	assert.Equal(t, err, nil)
+	_, err = DecryptKeystore(ks, []byte("password"))
+	assert.Equal(t, err, ErrWrongNetwork)
+}
+
 func TestGenacc(t *testing.T) {
 
 }
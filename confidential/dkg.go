@@ -0,0 +1,525 @@
+package confidential
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+	"io"
+	"sort"
+)
+
+//Distributed key generation, Pedersen's DKG with Feldman commitments. It replaces the dealer of
+//SplitKey and outputs the same ThresholdKey and KeyShare types.
+//
+//  1. Round1: dealer i picks a random polynomial fi of degree t-1, broadcasts a DKGCommitment with
+//     Cik = aik*G and a proof of knowledge of ai0, and sends the private DKGShare fi(j) to party j.
+//  2. Round2: party j checks every share against the commitments and broadcasts a DKGComplaint
+//     against each dealer whose share is missing or wrong.
+//  3. Justify: an accused dealer broadcasts the disputed shares as DKGJustification.
+//  4. Finalize: dealers without a valid commitment, or with a complaint that is not justified by a
+//     share matching their commitments, are disqualified. The key is the sum over the qualified dealers.
+//
+//All broadcast messages form a DKGTranscript, every party and any observer derives the ThresholdKey
+//from it with DKGTranscript.ThresholdKey, so comparing transcript hashes confirms agreement.
+
+var (
+	ErrDKGRound        = errors.New("dkg message out of round")
+	ErrDKGMessage      = errors.New("invalid dkg message")
+	ErrDKGDisqualified = errors.New("not enough qualified dealers")
+)
+
+var dkgDomain = []byte("xv-crypto dkg")
+
+type DKGCommitment struct {
+	Dealer      uint32
+	Commitments []*ristretto255.Element //Cik = aik*G, k = 0..t-1
+	r           *ristretto255.Element   //proof of knowledge of ai0
+	s           *ristretto255.Scalar
+}
+
+//DKGShare is the private message fi(j) from Dealer to Recipient, it must be sent over a secure channel
+type DKGShare struct {
+	Dealer, Recipient uint32
+	Share             *ristretto255.Scalar
+}
+
+type DKGComplaint struct {
+	Accuser, Accused uint32
+}
+
+//valid reports whether the accuser is one of the n parties other than the accused. Any other complaint
+//would make the accused publish a point of its polynomial nobody is entitled to, f(0) is its secret.
+func (complaint *DKGComplaint) valid(n int) bool {
+	return complaint.Accuser != 0 && int(complaint.Accuser) <= n && complaint.Accuser != complaint.Accused
+}
+
+//DKGJustification publishes the share a complaint is about
+type DKGJustification DKGShare
+
+type DKGTranscript struct {
+	T, N           int
+	Commitments    []*DKGCommitment
+	Complaints     []*DKGComplaint
+	Justifications []*DKGJustification
+}
+
+type DKGParticipant struct {
+	Index       uint32
+	t, n        int
+	random      io.Reader
+	coeffs      []*ristretto255.Scalar
+	commitments map[uint32]*DKGCommitment
+	shares      map[uint32]*ristretto255.Scalar //received shares by dealer
+	round       int
+}
+
+func NewDKGParticipant(index uint32, t, n int) (*DKGParticipant, error) {
+	return newDKGParticipant(index, t, n, rand.Reader)
+}
+
+func newDKGParticipant(index uint32, t, n int, random io.Reader) (*DKGParticipant, error) {
+	if t < 1 || t > n || n > MaxThresholdParties {
+		return nil, ErrThresholdParams
+	}
+	if index == 0 || int(index) > n {
+		return nil, errors.New("participant index must be in 1..n")
+	}
+	return &DKGParticipant{
+		Index:       index,
+		t:           t,
+		n:           n,
+		random:      random,
+		commitments: make(map[uint32]*DKGCommitment),
+		shares:      make(map[uint32]*ristretto255.Scalar),
+	}, nil
+}
+
+func dkgChallenge(dealer uint32, c0, r *ristretto255.Element) *ristretto255.Scalar {
+	var index [4]byte
+	binary.LittleEndian.PutUint32(index[:], dealer)
+	_, c := UpdateTranscript(sha512.Sum512(append(append([]byte{}, dkgDomain...), index[:]...)), c0, r)
+	return c
+}
+
+//Round1 returns the broadcast commitment and the private shares for every party, including the own share
+func (p *DKGParticipant) Round1() (*DKGCommitment, []*DKGShare, error) {
+	if p.round != 0 {
+		return nil, nil, ErrDKGRound
+	}
+	basePoint := hdBasePoint()
+	commitment := &DKGCommitment{Dealer: p.Index}
+	for i := 0; i < p.t; i++ {
+		coeff, err := randomScalar(p.random)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.coeffs = append(p.coeffs, coeff)
+		commitment.Commitments = append(commitment.Commitments, new(ristretto255.Element).ScalarMult(coeff, basePoint))
+	}
+	k, err := randomScalar(p.random)
+	if err != nil {
+		return nil, nil, err
+	}
+	commitment.r = new(ristretto255.Element).ScalarMult(k, basePoint)
+	c := dkgChallenge(p.Index, commitment.Commitments[0], commitment.r)
+	commitment.s = SumScalars(k, Mul(c, p.coeffs[0]))
+
+	shares := make([]*DKGShare, p.n)
+	for j := range shares {
+		shares[j] = &DKGShare{Dealer: p.Index, Recipient: uint32(j + 1), Share: evalPolynomial(p.coeffs, uint32(j+1))}
+	}
+	p.round = 1
+	return commitment, shares, nil
+}
+
+//verify checks the shape of the commitment and the proof of knowledge of the constant term
+func (commitment *DKGCommitment) verify(t, n int) (result bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			result = false
+		}
+	}()
+
+	if commitment.Dealer == 0 || int(commitment.Dealer) > n || len(commitment.Commitments) != t {
+		return false
+	}
+	c := dkgChallenge(commitment.Dealer, commitment.Commitments[0], commitment.r)
+	left := new(ristretto255.Element).ScalarMultWnaf(commitment.s, hdBasePoint())
+	right := SumElements(commitment.r, new(ristretto255.Element).ScalarMultWnaf(c, commitment.Commitments[0]))
+	return left.Equal(right) == 1
+}
+
+//verifyShare checks share*G = sum(Cik * recipient^k)
+func (commitment *DKGCommitment) verifyShare(recipient uint32, share *ristretto255.Scalar) bool {
+	if share == nil {
+		return false
+	}
+	left := new(ristretto255.Element).ScalarMultWnaf(share, hdBasePoint())
+	return left.Equal(evalPolynomialCommitment(commitment.Commitments, recipient)) == 1
+}
+
+//evalPolynomialCommitment returns sum(commitments[k]*x^k), the public image of evalPolynomial
+func evalPolynomialCommitment(commitments []*ristretto255.Element, x uint32) *ristretto255.Element {
+	xScalar, _ := InttoScalar(uint64(x))
+	powers := PowersList(xScalar, uint64(len(commitments)))
+	return new(ristretto255.Element).VarTimeMultiScalarMult(powers, commitments)
+}
+
+//ReceiveCommitment records the broadcast commitment of a dealer, invalid commitments are rejected and
+//the dealer is disqualified by every honest party
+func (p *DKGParticipant) ReceiveCommitment(commitment *DKGCommitment) error {
+	if p.round != 1 {
+		return ErrDKGRound
+	}
+	if !commitment.verify(p.t, p.n) {
+		return ErrDKGMessage
+	}
+	if _, ok := p.commitments[commitment.Dealer]; ok {
+		return errors.New("duplicate dkg commitment")
+	}
+	p.commitments[commitment.Dealer] = commitment
+	return nil
+}
+
+//ReceiveShare records a private share, it is checked in Round2
+func (p *DKGParticipant) ReceiveShare(share *DKGShare) error {
+	if p.round != 1 {
+		return ErrDKGRound
+	}
+	if share.Recipient != p.Index || share.Dealer == 0 || int(share.Dealer) > p.n {
+		return ErrDKGMessage
+	}
+	p.shares[share.Dealer] = share.Share
+	return nil
+}
+
+//Round2 returns a complaint against every committed dealer whose share is missing or does not match
+func (p *DKGParticipant) Round2() []*DKGComplaint {
+	if p.round != 1 {
+		return nil
+	}
+	p.round = 2
+	var complaints []*DKGComplaint
+	for _, dealer := range p.dealers() {
+		if !p.commitments[dealer].verifyShare(p.Index, p.shares[dealer]) {
+			complaints = append(complaints, &DKGComplaint{Accuser: p.Index, Accused: dealer})
+		}
+	}
+	return complaints
+}
+
+//Justify answers every complaint against this party with the disputed share
+func (p *DKGParticipant) Justify(complaints []*DKGComplaint) []*DKGJustification {
+	var justifications []*DKGJustification
+	for _, complaint := range complaints {
+		if complaint.Accused == p.Index && complaint.valid(p.n) && p.coeffs != nil {
+			justifications = append(justifications, &DKGJustification{
+				Dealer:    p.Index,
+				Recipient: complaint.Accuser,
+				Share:     evalPolynomial(p.coeffs, complaint.Accuser),
+			})
+		}
+	}
+	return justifications
+}
+
+//Finalize resolves the complaints and returns the shared key and the share of this party. A valid
+//justification of a complaint of this party replaces the share it received.
+func (p *DKGParticipant) Finalize(complaints []*DKGComplaint, justifications []*DKGJustification) (*ThresholdKey, *KeyShare, error) {
+	if p.round != 2 {
+		return nil, nil, ErrDKGRound
+	}
+	transcript := p.Transcript(complaints, justifications)
+	qualified := transcript.qualified()
+	key, err := transcript.thresholdKey(qualified)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, justification := range transcript.Justifications {
+		if justification.Recipient == p.Index && qualified[justification.Dealer] != nil {
+			p.shares[justification.Dealer] = justification.Share
+		}
+	}
+	sk := new(ristretto255.Scalar).Zero()
+	for dealer, commitment := range qualified {
+		if !commitment.verifyShare(p.Index, p.shares[dealer]) {
+			return nil, nil, errors.New("share of a qualified dealer is missing")
+		}
+		sk = SumScalars(sk, p.shares[dealer])
+	}
+	if new(ristretto255.Element).ScalarMult(sk, hdBasePoint()).Equal(key.VerificationKeys[p.Index-1]) != 1 {
+		return nil, nil, errors.New("key share does not match the transcript")
+	}
+	p.round = 3
+	return key, &KeyShare{Index: p.Index, sk: sk}, nil
+}
+
+//Transcript collects the broadcast messages seen by this party in canonical order
+func (p *DKGParticipant) Transcript(complaints []*DKGComplaint, justifications []*DKGJustification) *DKGTranscript {
+	transcript := &DKGTranscript{
+		T:              p.t,
+		N:              p.n,
+		Complaints:     append([]*DKGComplaint(nil), complaints...),
+		Justifications: append([]*DKGJustification(nil), justifications...),
+	}
+	for _, dealer := range p.dealers() {
+		transcript.Commitments = append(transcript.Commitments, p.commitments[dealer])
+	}
+	sort.Slice(transcript.Complaints, func(i, j int) bool {
+		a, b := transcript.Complaints[i], transcript.Complaints[j]
+		return a.Accused < b.Accused || (a.Accused == b.Accused && a.Accuser < b.Accuser)
+	})
+	sort.Slice(transcript.Justifications, func(i, j int) bool {
+		a, b := transcript.Justifications[i], transcript.Justifications[j]
+		return a.Dealer < b.Dealer || (a.Dealer == b.Dealer && a.Recipient < b.Recipient)
+	})
+	return transcript
+}
+
+func (p *DKGParticipant) dealers() []uint32 {
+	dealers := make([]uint32, 0, len(p.commitments))
+	for dealer := range p.commitments {
+		dealers = append(dealers, dealer)
+	}
+	sort.Slice(dealers, func(i, j int) bool { return dealers[i] < dealers[j] })
+	return dealers
+}
+
+//qualified returns the dealers with a valid commitment and a valid justification for every complaint
+func (transcript *DKGTranscript) qualified() map[uint32]*DKGCommitment {
+	qualified := make(map[uint32]*DKGCommitment)
+	for _, commitment := range transcript.Commitments {
+		if commitment.verify(transcript.T, transcript.N) {
+			qualified[commitment.Dealer] = commitment
+		}
+	}
+	for _, complaint := range transcript.Complaints {
+		commitment, ok := qualified[complaint.Accused]
+		if !ok || !complaint.valid(transcript.N) {
+			continue
+		}
+		justified := false
+		for _, justification := range transcript.Justifications {
+			if justification.Dealer == complaint.Accused && justification.Recipient == complaint.Accuser &&
+				commitment.verifyShare(complaint.Accuser, justification.Share) {
+				justified = true
+				break
+			}
+		}
+		if !justified {
+			delete(qualified, complaint.Accused)
+		}
+	}
+	return qualified
+}
+
+func (transcript *DKGTranscript) thresholdKey(qualified map[uint32]*DKGCommitment) (*ThresholdKey, error) {
+	if len(qualified) < transcript.T {
+		return nil, ErrDKGDisqualified
+	}
+	//the public polynomial of the key is the sum of the qualified dealer polynomials
+	sum := make([]*ristretto255.Element, transcript.T)
+	for k := range sum {
+		sum[k] = new(ristretto255.Element).Zero()
+		for _, commitment := range qualified {
+			sum[k] = new(ristretto255.Element).Add(sum[k], commitment.Commitments[k])
+		}
+	}
+	key := &ThresholdKey{T: transcript.T, N: transcript.N, Pk: sum[0]}
+	for j := 1; j <= transcript.N; j++ {
+		key.VerificationKeys = append(key.VerificationKeys, evalPolynomialCommitment(sum, uint32(j)))
+	}
+	return key, nil
+}
+
+//ThresholdKey derives the shared public key from the broadcast messages alone
+func (transcript *DKGTranscript) ThresholdKey() (*ThresholdKey, error) {
+	return transcript.thresholdKey(transcript.qualified())
+}
+
+//Qualified returns the indexes of the dealers that contribute to the key
+func (transcript *DKGTranscript) Qualified() []uint32 {
+	var dealers []uint32
+	for dealer := range transcript.qualified() {
+		dealers = append(dealers, dealer)
+	}
+	sort.Slice(dealers, func(i, j int) bool { return dealers[i] < dealers[j] })
+	return dealers
+}
+
+func (commitment *DKGCommitment) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint32(commitment.Dealer)
+	sink.WriteVarUint(uint64(len(commitment.Commitments)))
+	for _, c := range commitment.Commitments {
+		sink.WriteFixedElement(c)
+	}
+	sink.WriteFixedElement(commitment.r)
+	sink.WriteFixedScalar(commitment.s)
+}
+
+func (commitment *DKGCommitment) Deserialization(source *ZeroCopySource) error {
+	var eof, irregular bool
+	var err error
+	commitment.Dealer, eof = source.NextUint32()
+	if eof {
+		return ErrProofLength
+	}
+	count, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return ErrIrregularData
+	}
+	if eof || count > MaxThresholdParties || source.Len() < (count+1)*32+32 {
+		return ErrProofLength
+	}
+	commitment.Commitments = make([]*ristretto255.Element, count)
+	for i := range commitment.Commitments {
+		if commitment.Commitments[i], err = source.NextFixedElement(); err != nil {
+			return err
+		}
+	}
+	if commitment.r, err = source.NextFixedElement(); err != nil {
+		return err
+	}
+	commitment.s, err = source.NextFixedScalar()
+	return err
+}
+
+func (share *DKGShare) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint32(share.Dealer)
+	sink.WriteUint32(share.Recipient)
+	sink.WriteFixedScalar(share.Share)
+}
+
+func (share *DKGShare) Deserialization(source *ZeroCopySource) error {
+	var eof bool
+	if share.Dealer, eof = source.NextUint32(); eof {
+		return ErrProofLength
+	}
+	if share.Recipient, eof = source.NextUint32(); eof {
+		return ErrProofLength
+	}
+	var err error
+	share.Share, err = source.NextFixedScalar()
+	return err
+}
+
+func (complaint *DKGComplaint) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint32(complaint.Accuser)
+	sink.WriteUint32(complaint.Accused)
+}
+
+func (complaint *DKGComplaint) Deserialization(source *ZeroCopySource) error {
+	var eof bool
+	if complaint.Accuser, eof = source.NextUint32(); eof {
+		return ErrProofLength
+	}
+	if complaint.Accused, eof = source.NextUint32(); eof {
+		return ErrProofLength
+	}
+	if !complaint.valid(MaxThresholdParties) {
+		return ErrDKGMessage
+	}
+	return nil
+}
+
+func (justification *DKGJustification) Serialization(sink *ZeroCopySink) {
+	(*DKGShare)(justification).Serialization(sink)
+}
+
+func (justification *DKGJustification) Deserialization(source *ZeroCopySource) error {
+	return (*DKGShare)(justification).Deserialization(source)
+}
+
+//Serialization writes t, n and the three message lists, each with a length prefix
+func (transcript *DKGTranscript) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint32(uint32(transcript.T))
+	sink.WriteUint32(uint32(transcript.N))
+	sink.WriteVarUint(uint64(len(transcript.Commitments)))
+	for _, commitment := range transcript.Commitments {
+		commitment.Serialization(sink)
+	}
+	sink.WriteVarUint(uint64(len(transcript.Complaints)))
+	for _, complaint := range transcript.Complaints {
+		complaint.Serialization(sink)
+	}
+	sink.WriteVarUint(uint64(len(transcript.Justifications)))
+	for _, justification := range transcript.Justifications {
+		justification.Serialization(sink)
+	}
+}
+
+func (transcript *DKGTranscript) Deserialization(source *ZeroCopySource) error {
+	t, eof := source.NextUint32()
+	if eof {
+		return ErrProofLength
+	}
+	n, eof := source.NextUint32()
+	if eof {
+		return ErrProofLength
+	}
+	if t < 1 || t > n || n > MaxThresholdParties {
+		return ErrThresholdParams
+	}
+	transcript.T, transcript.N = int(t), int(n)
+	//every party sends at most one commitment and one complaint or justification per other party
+	nextCount := func(max uint64) (uint64, error) {
+		count, _, irregular, eof := source.NextVarUint()
+		if irregular {
+			return 0, ErrIrregularData
+		}
+		if eof || count > max {
+			return 0, ErrProofLength
+		}
+		return count, nil
+	}
+	count, err := nextCount(uint64(n))
+	if err != nil {
+		return err
+	}
+	transcript.Commitments = make([]*DKGCommitment, count)
+	for i := range transcript.Commitments {
+		transcript.Commitments[i] = new(DKGCommitment)
+		if err := transcript.Commitments[i].Deserialization(source); err != nil {
+			return err
+		}
+	}
+	if count, err = nextCount(uint64(n) * uint64(n)); err != nil {
+		return err
+	}
+	transcript.Complaints = make([]*DKGComplaint, count)
+	for i := range transcript.Complaints {
+		transcript.Complaints[i] = new(DKGComplaint)
+		if err := transcript.Complaints[i].Deserialization(source); err != nil {
+			return err
+		}
+		if !transcript.Complaints[i].valid(int(n)) {
+			return ErrDKGMessage
+		}
+	}
+	if count, err = nextCount(uint64(n) * uint64(n)); err != nil {
+		return err
+	}
+	transcript.Justifications = make([]*DKGJustification, count)
+	for i := range transcript.Justifications {
+		transcript.Justifications[i] = new(DKGJustification)
+		if err := transcript.Justifications[i].Deserialization(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (transcript *DKGTranscript) Serialize() []byte {
+	return serialize(transcript)
+}
+
+func (transcript *DKGTranscript) Deserialize(b []byte) error {
+	return deserialize(transcript, b)
+}
+
+//Hash identifies the transcript, parties compare it to confirm they derived the same key
+func (transcript *DKGTranscript) Hash() [64]byte {
+	return sha512.Sum512(transcript.Serialize())
+}
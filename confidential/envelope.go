@@ -0,0 +1,258 @@
+package confidential
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+//envelope layout: magic||version||kind||paramsID||varbytes(payload)
+//version 1 carries the Serialize layout, version 2 the SerializeCompact layout
+const (
+	ProofMagic         = uint32(0x46505658) //"XVPF" little-endian
+	ProofVersionLegacy = uint8(1)
+	ProofVersion       = uint8(2)
+)
+
+type ProofKind uint8
+
+const (
+	KindCommitmentProof ProofKind = iota + 1
+	KindTransferProof
+	KindWithdrawProof
+	KindRangeProof
+	KindSigmaRangeProof
+	KindMultiTransferProof
+	KindFeeTransferProof
+)
+
+var (
+	ErrProofMagic    = errors.New("not a proof envelope")
+	ErrProofVersion  = errors.New("unsupported proof version")
+	ErrProofKind     = errors.New("unknown proof kind")
+	ErrProofParamsID = errors.New("proof generated with different range prover params")
+)
+
+//Proof is implemented by every proof type that can be wrapped in an envelope
+type Proof interface {
+	Codec
+	Kind() ProofKind
+	Serialize() []byte
+	Deserialize(b []byte) error
+	SerializeCompact() []byte
+	DeserializeCompact(b []byte) error
+}
+
+func (proof *CommitmentProof) Kind() ProofKind { return KindCommitmentProof }
+
+func (proof *TransferProof) Kind() ProofKind { return KindTransferProof }
+
+func (proof *WithdrawProof) Kind() ProofKind { return KindWithdrawProof }
+
+func (proof *RangeProof) Kind() ProofKind { return KindRangeProof }
+
+func (proof *SigmaRangeProof) Kind() ProofKind { return KindSigmaRangeProof }
+
+func newProof(kind ProofKind) (Proof, error) {
+	switch kind {
+	case KindCommitmentProof:
+		return new(CommitmentProof), nil
+	case KindTransferProof:
+		return new(TransferProof), nil
+	case KindWithdrawProof:
+		return new(WithdrawProof), nil
+	case KindRangeProof:
+		return new(RangeProof), nil
+	case KindSigmaRangeProof:
+		return new(SigmaRangeProof), nil
+	case KindMultiTransferProof:
+		return new(MultiTransferProof), nil
+	case KindFeeTransferProof:
+		return new(FeeTransferProof), nil
+	}
+	return nil, ErrProofKind
+}
+
+//ParamsID identifies the generators and bit length a proof was made for, so a verifier
+//configured differently rejects the proof before doing any curve arithmetic.
+func ParamsID(rangeN, aggCount uint64) [8]byte {
+	buf := make([]byte, 16)
+	binary.LittleEndian.PutUint64(buf, rangeN)
+	binary.LittleEndian.PutUint64(buf[8:], aggCount)
+	buf = append(buf, GHXOFSeed...)
+	buf = append(buf, rangeProverXofSeed...)
+	hash := sha256.Sum256(buf)
+	var id [8]byte
+	copy(id[:], hash[:8])
+	return id
+}
+
+//DefaultParamsID is the params id of the transfer range proofs built by Account and SmartContract
+var DefaultParamsID = ParamsID(RANGEBITS, RANGEPROOFCOUNT)
+
+//ProofParamsID returns the params id of the range proof carried by proof: two values for a transfer,
+//one for a withdraw or a range proof, aggCount(k) for k recipients and none for a burn. A single
+//range proof may be made for fewer bits, its inner product proof has log2(N) rounds.
+func ProofParamsID(proof Proof) [8]byte {
+	switch p := proof.(type) {
+	case *CommitmentProof:
+		return ParamsID(0, 0)
+	case *WithdrawProof:
+		if p.rangeProof == nil {
+			return ParamsID(RANGEBITS, 1)
+		}
+		return ParamsID(uint64(1)<<len(p.rangeProof.InnerProof.Ls), 1)
+	case *RangeProof:
+		return ParamsID(uint64(1)<<len(p.InnerProof.Ls), 1)
+	case *SigmaRangeProof:
+		return ParamsID(RANGEBITS, (uint64(1)<<len(p.InnerProof.Ls))/RANGEBITS)
+	case *MultiTransferProof:
+		return ParamsID(RANGEBITS, aggCount(len(p.Recipients)))
+	}
+	return DefaultParamsID
+}
+
+//knownParamsIDs lists the params ids every kind can be generated with, DecodeProof rejects any
+//other id before the payload is decoded
+var knownParamsIDs = func() map[ProofKind]map[[8]byte]bool {
+	known := map[ProofKind]map[[8]byte]bool{
+		KindCommitmentProof:    {ParamsID(0, 0): true},
+		KindTransferProof:      {DefaultParamsID: true},
+		KindFeeTransferProof:   {DefaultParamsID: true},
+		KindWithdrawProof:      {},
+		KindRangeProof:         {},
+		KindSigmaRangeProof:    {},
+		KindMultiTransferProof: {},
+	}
+	for n := uint64(1); n <= MaxRangeBits; n <<= 1 {
+		known[KindWithdrawProof][ParamsID(n, 1)] = true
+		known[KindRangeProof][ParamsID(n, 1)] = true
+	}
+	for count := uint64(1); count <= MaxAggregation; count <<= 1 {
+		known[KindSigmaRangeProof][ParamsID(RANGEBITS, count)] = true
+	}
+	for k := 1; k <= MaxRecipients; k++ {
+		known[KindMultiTransferProof][ParamsID(RANGEBITS, aggCount(k))] = true
+	}
+	return known
+}()
+
+type ProofHeader struct {
+	Version  uint8
+	Kind     ProofKind
+	ParamsID [8]byte
+}
+
+//EncodeProof wraps the compact serialization of the proof in a self-describing envelope
+func EncodeProof(proof Proof) []byte {
+	return encodeEnvelope(ProofVersion, proof.Kind(), ProofParamsID(proof), proof.SerializeCompact())
+}
+
+//EncodeProofLegacy wraps the Serialize layout, for verifiers that only understand version 1
+func EncodeProofLegacy(proof Proof) []byte {
+	return encodeEnvelope(ProofVersionLegacy, proof.Kind(), ProofParamsID(proof), proof.Serialize())
+}
+
+func encodeEnvelope(version uint8, kind ProofKind, paramsID [8]byte, payload []byte) []byte {
+	sink := NewZeroCopySink(nil)
+	sink.WriteUint32(ProofMagic)
+	sink.WriteUint8(version)
+	sink.WriteUint8(uint8(kind))
+	sink.WriteBytes(paramsID[:])
+	EncodeBytes(sink, payload)
+	return sink.Bytes()
+}
+
+//MigrateProof re-encodes an envelope of any supported version with the current version
+func MigrateProof(b []byte) ([]byte, error) {
+	proof, err := DecodeProof(b)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeProof(proof), nil
+}
+
+func readProofHeader(source *ZeroCopySource) (ProofHeader, error) {
+	var header ProofHeader
+	magic, eof := source.NextUint32()
+	if eof {
+		return header, io.ErrUnexpectedEOF
+	}
+	if magic != ProofMagic {
+		return header, ErrProofMagic
+	}
+	version, eof := source.NextUint8()
+	if eof {
+		return header, io.ErrUnexpectedEOF
+	}
+	kind, eof := source.NextUint8()
+	if eof {
+		return header, io.ErrUnexpectedEOF
+	}
+	id, eof := source.NextBytes(8)
+	if eof {
+		return header, io.ErrUnexpectedEOF
+	}
+	header.Version = version
+	header.Kind = ProofKind(kind)
+	copy(header.ParamsID[:], id)
+	return header, nil
+}
+
+//DecodeProofHeader reads the envelope header without decoding the payload
+func DecodeProofHeader(b []byte) (ProofHeader, error) {
+	return readProofHeader(NewZeroCopySource(b))
+}
+
+//DecodeProof checks the envelope and dispatches the payload to the decoder of its kind
+func DecodeProof(b []byte) (Proof, error) {
+	source := NewZeroCopySource(b)
+	header, err := readProofHeader(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := header.check(); err != nil {
+		return nil, err
+	}
+	payload, err := DecodeBytes(source)
+	if err != nil {
+		return nil, err
+	}
+	if source.Len() != 0 {
+		return nil, ErrTrailingBytes
+	}
+	return decodePayload(header, payload)
+}
+
+func (header ProofHeader) check() error {
+	if header.Version != ProofVersion && header.Version != ProofVersionLegacy {
+		return ErrProofVersion
+	}
+	if _, err := newProof(header.Kind); err != nil {
+		return err
+	}
+	if !knownParamsIDs[header.Kind][header.ParamsID] {
+		return ErrProofParamsID
+	}
+	return nil
+}
+
+func decodePayload(header ProofHeader, payload []byte) (Proof, error) {
+	proof, err := newProof(header.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if header.Version == ProofVersionLegacy {
+		err = proof.Deserialize(payload)
+	} else {
+		err = proof.DeserializeCompact(payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ProofParamsID(proof) != header.ParamsID {
+		return nil, ErrProofParamsID
+	}
+	return proof, nil
+}
@@ -0,0 +1,158 @@
+package confidential
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Transfers with a public fee. The sender pays b to the recipient and a public fee f to the fee account
+//of the ledger. The transfer proof is made against the sender commitment minus f*G, so the range proof
+//covers the balance left after the fee, and f is bound to the transcript before any proof is generated.
+
+var ErrNoFeeAccount = errors.New("no fee account registered")
+
+var feeDomain = []byte("xv-crypto fee")
+
+type FeeTransferProof struct {
+	Fee uint64
+	TransferProof
+}
+
+func (proof *FeeTransferProof) Kind() ProofKind { return KindFeeTransferProof }
+
+func feeTranscript(trans [64]byte, fee uint64) [64]byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], fee)
+	h := append(append(append([]byte{}, trans[:]...), feeDomain...), buf[:]...)
+	return sha512.Sum512(h)
+}
+
+//feeCommitment returns comm without the fee, fee*G is subtracted from the plaintext side
+func feeCommitment(comm *Commitment, fee uint64, g *ristretto255.Element) (*Commitment, error) {
+	f, err := InttoScalar(fee)
+	if err != nil {
+		return nil, err
+	}
+	return &Commitment{
+		Cl: new(ristretto255.Element).Add(comm.Cl, new(ristretto255.Element).Negate(new(ristretto255.Element).ScalarMultWnaf(f, g))),
+		Cr: comm.Cr,
+	}, nil
+}
+
+//GenFeeTransferProof transfers amount to yPrime and pays fee to the fee account of the ledger
+func (acc *Account) GenFeeTransferProof(trans [64]byte, amount, fee uint64, yPrime *ristretto255.Element, auditors ...*ristretto255.Element) (*FeeTransferProof, error) {
+	if acc.viewOnly {
+		return nil, ErrViewOnly
+	}
+	if len(auditors) > MaxAuditors {
+		return nil, ErrTooManyAuditors
+	}
+	balance := ScalartoInt(acc.GetCommitmentBalance())
+	if fee > balance || amount > balance-fee {
+		return nil, errors.New("amount and fee exceed the balance")
+	}
+	comm, err := feeCommitment(acc.Comm, fee, acc.basePoint)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := acc.genTransferProof(feeTranscript(trans, fee), amount, yPrime, comm, balance-fee, auditors)
+	if err != nil {
+		return nil, err
+	}
+	return &FeeTransferProof{Fee: fee, TransferProof: *proof}, nil
+}
+
+//SetFeeAccount designates the account whose public balance receives the fees
+func (sc *SmartContract) SetFeeAccount(pk *ristretto255.Element) {
+	sc.FeeAccount = pk
+}
+
+//VerifyFeeTransferProof rejects senders with a registered spend key, use VerifyAuthorizedFeeTransferProof for them
+func (sc *SmartContract) VerifyFeeTransferProof(trans [64]byte, proof *FeeTransferProof, y, yPrime *ristretto255.Element) bool {
+	return sc.getSpendKey(y) == nil && sc.verifyFeeTransferProof(trans, proof, y, yPrime)
+}
+
+func (sc *SmartContract) VerifyAuthorizedFeeTransferProof(trans [64]byte, proof *FeeTransferProof, auth *SpendAuthorization, y, yPrime *ristretto255.Element) bool {
+	return sc.verifySpendAuthorization(trans, y, proof, auth) && sc.verifyFeeTransferProof(trans, proof, y, yPrime)
+}
+
+func (sc *SmartContract) verifyFeeTransferProof(trans [64]byte, proof *FeeTransferProof, y, yPrime *ristretto255.Element) bool {
+	cOld := sc.GetCommitment(y)
+	if proof == nil || cOld == nil {
+		return false
+	}
+	comm, err := feeCommitment(cOld, proof.Fee, sc.BasePoint)
+	if err != nil {
+		return false
+	}
+	return sc.verifyTransferProofFrom(feeTranscript(trans, proof.Fee), &proof.TransferProof, y, yPrime, comm)
+}
+
+//ApplyFeeTransfer applies a fee transfer like ApplyTransfer, CComm and the fee leave the available
+//balance of y and the fee is added to the public balance of the fee account
+func (sc *SmartContract) ApplyFeeTransfer(trans [64]byte, proof *FeeTransferProof, auth *SpendAuthorization, nullifier *Nullifier, y, yPrime *ristretto255.Element) bool {
+	sc.Mu.Lock()
+	defer sc.Mu.Unlock()
+	if sc.FeeAccount == nil {
+		return false
+	}
+	feeKey := pkKey(sc.FeeAccount)
+	if _, ok := sc.PublicBalanceMap[feeKey]; !ok {
+		return false
+	}
+	if sc.rollOver(y) != nil || sc.GetCommitment(yPrime) == nil || !sc.VerifyNullifier(trans, y, proof, nullifier) {
+		return false
+	}
+	if sc.getSpendKey(y) != nil {
+		if !sc.VerifyAuthorizedFeeTransferProof(trans, proof, auth, y, yPrime) {
+			return false
+		}
+	} else if !sc.verifyFeeTransferProof(trans, proof, y, yPrime) {
+		return false
+	}
+	sc.NullifierMap[pkKey(nullifier.U)] = sc.Epoch
+	key := pkKey(y)
+	comm, _ := feeCommitment(sc.CommitmentMap[key], proof.Fee, sc.BasePoint)
+	sc.CommitmentMap[key] = new(Commitment).Sub(comm, &proof.CComm)
+	sc.PublicBalanceMap[feeKey] += proof.Fee
+	return sc.credit(yPrime, &proof.CPrimeComm) == nil
+}
+
+//fee||transfer proof
+func (proof *FeeTransferProof) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint64(proof.Fee)
+	proof.TransferProof.Serialization(sink)
+}
+
+func (proof *FeeTransferProof) Deserialization(source *ZeroCopySource) error {
+	var eof bool
+	if proof.Fee, eof = source.NextUint64(); eof {
+		return ErrProofLength
+	}
+	return proof.TransferProof.Deserialization(source)
+}
+
+func (proof *FeeTransferProof) Serialize() []byte {
+	return serialize(proof)
+}
+
+func (proof *FeeTransferProof) Deserialize(b []byte) error {
+	return deserialize(proof, b)
+}
+
+func (proof *FeeTransferProof) SerializeCompact() []byte {
+	sink := NewZeroCopySink(nil)
+	sink.WriteUint64(proof.Fee)
+	sink.WriteBytes(proof.TransferProof.SerializeCompact())
+	return sink.Bytes()
+}
+
+func (proof *FeeTransferProof) DeserializeCompact(b []byte) error {
+	if len(b) < 8 {
+		return ErrProofLength
+	}
+	proof.Fee, _ = NewZeroCopySource(b).NextUint64()
+	return proof.TransferProof.DeserializeCompact(b[8:])
+}
@@ -0,0 +1,233 @@
+package confidential
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+	"github.com/tyler-smith/go-bip39"
+	"strconv"
+	"strings"
+)
+
+//Hierarchical deterministic account keys. The derivation follows BIP32 with the ristretto group:
+//a child key is the parent key plus a tweak computed from the parent chain code, so the child public
+//key of a non-hardened index is the parent public key plus tweak*G and can be derived without any
+//secret. Hardened children hash the parent secret key instead.
+//
+//As in BIP32, a leaked non-hardened child secret key together with the parent chain code reveals the
+//parent secret key, so only hand out extended public keys below a hardened level.
+
+//HardenedIndex is the first hardened child index, written as i' in derivation paths
+const HardenedIndex uint32 = 1 << 31
+
+//MaxDerivationDepth bounds the length of a derivation path
+const MaxDerivationDepth = 255
+
+var hdMasterKey = []byte("xv-crypto hd seed")
+
+var (
+	ErrHardenedPublicDerivation = errors.New("hardened child can not be derived from a public key")
+	ErrInvalidDerivationPath    = errors.New("invalid derivation path")
+)
+
+type ExtendedKey struct {
+	sk        *ristretto255.Scalar //nil for an extended public key
+	Pk        *ristretto255.Element
+	ChainCode [32]byte
+	Depth     uint8
+	Index     uint32
+}
+
+//hdBasePoint returns the base point used by every account, it is the first generator of the range prover
+func hdBasePoint() *ristretto255.Element {
+	G, _ := generates(1, GHXOFSeed)
+	return G[0]
+}
+
+//hdHash splits HMAC-SHA512(key, data) into a 64 bytes scalar seed and a chain code
+func hdHash(key []byte, data ...[]byte) (*ristretto255.Scalar, [32]byte) {
+	mac := hmac.New(sha512.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	sum := mac.Sum(nil)
+	scalar := new(ristretto255.Scalar).FromUniformBytes(sum)
+	mac.Reset()
+	mac.Write(sum)
+	var chainCode [32]byte
+	copy(chainCode[:], mac.Sum(nil))
+	return scalar, chainCode
+}
+
+//NewMasterKey derives the root key from a seed of 16 to 64 bytes, usually the output of MnemonicToSeed
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("seed length must be between 16 and 64 bytes")
+	}
+	sk, chainCode := hdHash(hdMasterKey, seed)
+	if sk.Equal(new(ristretto255.Scalar).Zero()) == 1 {
+		return nil, errors.New("seed derives a zero key")
+	}
+	return &ExtendedKey{
+		sk:        sk,
+		Pk:        new(ristretto255.Element).ScalarMult(sk, hdBasePoint()),
+		ChainCode: chainCode,
+	}, nil
+}
+
+func (key *ExtendedKey) IsPrivate() bool {
+	return key.sk != nil
+}
+
+//Public returns the extended public key, it can derive non-hardened children only
+func (key *ExtendedKey) Public() *ExtendedKey {
+	return &ExtendedKey{
+		Pk:        DeepCopyElement(key.Pk),
+		ChainCode: key.ChainCode,
+		Depth:     key.Depth,
+		Index:     key.Index,
+	}
+}
+
+func (key *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if key.Depth == MaxDerivationDepth {
+		return nil, errors.New("maximum derivation depth reached")
+	}
+	var indexBytes [4]byte
+	binary.BigEndian.PutUint32(indexBytes[:], index)
+	child := &ExtendedKey{
+		Depth: key.Depth + 1,
+		Index: index,
+	}
+	var tweak *ristretto255.Scalar
+	if index >= HardenedIndex {
+		if !key.IsPrivate() {
+			return nil, ErrHardenedPublicDerivation
+		}
+		sk := ScalarToBytes(key.sk)
+		tweak, child.ChainCode = hdHash(key.ChainCode[:], []byte{0}, sk[:], indexBytes[:])
+	} else {
+		tweak, child.ChainCode = hdHash(key.ChainCode[:], []byte{1}, key.Pk.Encode(nil), indexBytes[:])
+	}
+
+	if key.IsPrivate() {
+		child.sk = new(ristretto255.Scalar).Add(key.sk, tweak)
+		if child.sk.Equal(new(ristretto255.Scalar).Zero()) == 1 {
+			return nil, errors.New("child key is zero, use the next index")
+		}
+		child.Pk = new(ristretto255.Element).ScalarMult(child.sk, hdBasePoint())
+	} else {
+		child.Pk = new(ristretto255.Element).Add(key.Pk, new(ristretto255.Element).ScalarMult(tweak, hdBasePoint()))
+	}
+	return child, nil
+}
+
+//ParseDerivationPath parses paths such as "m/44'/0'/3/7", both ' and h mark a hardened index
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" || len(parts)-1 > MaxDerivationDepth {
+		return nil, ErrInvalidDerivationPath
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(index) >= HardenedIndex {
+			return nil, ErrInvalidDerivationPath
+		}
+		if hardened {
+			index += uint64(HardenedIndex)
+		}
+		indexes = append(indexes, uint32(index))
+	}
+	return indexes, nil
+}
+
+//Derive walks a derivation path relative to key, which is normally the master key
+func (key *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+//ViewKeyIndex is the hardened child of an account key that holds the view key
+const ViewKeyIndex = HardenedIndex
+
+//InitFromKey initializes the account from a derived secret key. The spend key is derived from the key
+//itself and the view key is its hardened child ViewKeyIndex, a shared view key and the public chain code
+//do not reveal the parent secret. The nonce generator is seeded from the key and fresh system randomness
+//like ImportSk, so an account restored from the same key never reuses the nonces of an earlier session.
+func (acc *Account) InitFromKey(key *ExtendedKey) error {
+	if !key.IsPrivate() {
+		return errors.New("extended key has no secret key")
+	}
+	viewKey, err := key.Child(ViewKeyIndex)
+	if err != nil {
+		return err
+	}
+	sk := ScalarToBytes(key.sk)
+	entropy := make([]byte, 32)
+	if _, err := rand.Read(entropy); err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, key.ChainCode[:])
+	mac.Write([]byte("account nonce"))
+	mac.Write(sk[:])
+	mac.Write(entropy)
+	var seed [32]byte
+	copy(seed[:], mac.Sum(nil))
+	acc.xof = NewXofExpend(64, seed)
+	acc.setKey(DeepCopyScalar(viewKey.sk))
+	acc.setSpendKey(deriveSpendKey(append(key.ChainCode[:], sk[:]...)))
+	zero := new(ristretto255.Scalar).Zero()
+	_, comm := acc.Commit(zero)
+	acc.Comm = &comm
+	acc.PubBalance = uint64(0)
+	return nil
+}
+
+//NewMnemonic returns a BIP39 english mnemonic with bits of entropy, bits must be a multiple of 32 in [128, 256]
+func NewMnemonic(bits int) (string, error) {
+	entropy, err := bip39.NewEntropy(bits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+//MnemonicToSeed checks the mnemonic checksum and returns the 64 bytes BIP39 seed for NewMasterKey
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	return bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+}
+
+//MnemonicToInitSeed returns a seed for Account.Init, the same phrase backs up a single Init account
+func MnemonicToInitSeed(mnemonic, passphrase string) ([32]byte, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(seed), nil
+}
+
+//NewMasterKeyFromMnemonic is NewMasterKey over the BIP39 seed of the mnemonic
+func NewMasterKeyFromMnemonic(mnemonic, passphrase string) (*ExtendedKey, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewMasterKey(seed)
+}
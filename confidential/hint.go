@@ -0,0 +1,139 @@
+package confidential
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Amount hints. A hint is R = e*G for an ephemeral e followed by the amount xor a pad derived from the
+//ECDH secret e*Pk = sk*R and the ciphertext it belongs to. The ElGamal secret Cl - v*G = sk*Cr can not
+//be used for the pad, anyone can compute it for a guessed v and test the guess against the hint. The
+//owner of sk opens a hint with one scalar multiplication and checks v*G = Cl - sk*Cr, a missing, stale
+//or forged hint falls back to the discrete log.
+
+//R||amount
+const AmountHintSize = 32 + 8
+
+type AmountHint [AmountHintSize]byte
+
+var hintDomain = []byte("xv-crypto amount hint")
+
+func hintPad(shared, r, cr *ristretto255.Element) uint64 {
+	h := sha512.New()
+	h.Write(hintDomain)
+	h.Write(shared.Encode(nil))
+	h.Write(r.Encode(nil))
+	h.Write(cr.Encode(nil))
+	return binary.LittleEndian.Uint64(h.Sum(nil))
+}
+
+//SealAmountHint returns the hint of comm for recipient, comm has to encrypt v under recipient
+func (acc *Account) SealAmountHint(recipient *ristretto255.Element, comm *Commitment, v uint64) (*AmountHint, error) {
+	if _, err := InttoScalar(v); err != nil {
+		return nil, err
+	}
+	e := acc.RandScalar()
+	r := acc.mult(e, acc.basePoint)
+	var hint AmountHint
+	copy(hint[:32], r.Encode(nil))
+	binary.LittleEndian.PutUint64(hint[32:], v^hintPad(acc.mult(e, recipient), r, comm.Cr))
+	return &hint, nil
+}
+
+//hintAmount removes the pad of a hint sealed to acc.Pk, the amount is not checked against comm
+func (acc *Account) hintAmount(comm *Commitment, hint *AmountHint) (uint64, bool) {
+	if hint == nil {
+		return 0, false
+	}
+	var rBytes [32]byte
+	copy(rBytes[:], hint[:32])
+	r, err := ElementFromBytes(rBytes)
+	if err != nil {
+		return 0, false
+	}
+	v := binary.LittleEndian.Uint64(hint[32:]) ^ hintPad(acc.mult(acc.sk, r), r, comm.Cr)
+	return v, v < acc.upper
+}
+
+//OpenAmountHint returns the amount of the hint if comm encrypts it under this account
+func (acc *Account) OpenAmountHint(comm *Commitment, hint *AmountHint) (uint64, bool) {
+	v, ok := acc.hintAmount(comm, hint)
+	if !ok {
+		return 0, false
+	}
+	s, err := InttoScalar(v)
+	if err != nil {
+		return 0, false
+	}
+	vEncrypt := new(ristretto255.Element).Add(comm.Cl, new(ristretto255.Element).Negate(acc.mult(acc.sk, comm.Cr)))
+	return v, vEncrypt.Equal(acc.mult(s, acc.basePoint)) == 1
+}
+
+//DecryptCommitment tries the hint first and solves the discrete log only if the hint is nil or wrong
+func (acc *Account) DecryptCommitment(comm *Commitment, hint *AmountHint) (uint64, error) {
+	if v, ok := acc.OpenAmountHint(comm, hint); ok {
+		return v, nil
+	}
+	return acc.decryptAmount(comm.Cl, comm.Cr)
+}
+
+//SetCommitment replaces the balance commitment of the account, a valid hint fills the balance cache
+//so GetCommitmentBalance does not have to solve the discrete log
+func (acc *Account) SetCommitment(comm *Commitment, hint *AmountHint) {
+	acc.Comm = comm
+	if v, ok := acc.OpenAmountHint(comm, hint); ok {
+		acc.cachedComm = comm.Encode()
+		acc.cachedBalance = v
+	}
+}
+
+//TransferHints travel with a transfer proof, Amount opens CPrimeComm for the recipient and Balance
+//opens the balance left to the sender
+type TransferHints struct {
+	Amount  AmountHint
+	Balance AmountHint
+}
+
+//GenTransferHints returns the hints of a transfer proof to yPrime generated by this account from acc.Comm
+func (acc *Account) GenTransferHints(proof *TransferProof, amount uint64, yPrime *ristretto255.Element) (*TransferHints, error) {
+	amountHint, err := acc.SealAmountHint(yPrime, &proof.CPrimeComm, amount)
+	if err != nil {
+		return nil, err
+	}
+	balance := ScalartoInt(acc.GetCommitmentBalance())
+	balanceHint, err := acc.SealAmountHint(acc.Pk, new(Commitment).Sub(acc.Comm, &proof.CComm), balance-amount)
+	if err != nil {
+		return nil, err
+	}
+	return &TransferHints{Amount: *amountHint, Balance: *balanceHint}, nil
+}
+
+//DepositHint returns the hint of the commitment of the last deposit
+func (acc *Account) DepositHint(v uint64) (*AmountHint, error) {
+	return acc.SealAmountHint(acc.Pk, acc.Comm, v)
+}
+
+//Amount||Balance
+func (hints *TransferHints) Serialization(sink *ZeroCopySink) {
+	sink.WriteBytes(hints.Amount[:])
+	sink.WriteBytes(hints.Balance[:])
+}
+
+func (hints *TransferHints) Deserialization(source *ZeroCopySource) error {
+	b, eof := source.NextBytes(2 * AmountHintSize)
+	if eof {
+		return ErrProofLength
+	}
+	copy(hints.Amount[:], b)
+	copy(hints.Balance[:], b[AmountHintSize:])
+	return nil
+}
+
+func (hints *TransferHints) Serialize() []byte {
+	return serialize(hints)
+}
+
+func (hints *TransferHints) Deserialize(b []byte) error {
+	return deserialize(hints, b)
+}
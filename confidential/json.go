@@ -0,0 +1,499 @@
+package confidential
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//JSON encodings use lower-case hex of the same 32 bytes encodings as the binary format, account public
+//keys are written as bech32m addresses
+
+func elementToHex(e *ristretto255.Element) string {
+	if e == nil {
+		return ""
+	}
+	b := ElementToBytes(e)
+	return hex.EncodeToString(b[:])
+}
+
+func scalarToHex(s *ristretto255.Scalar) string {
+	if s == nil {
+		return ""
+	}
+	b := ScalarToBytes(s)
+	return hex.EncodeToString(b[:])
+}
+
+func fixedFromHex(s string) ([32]byte, error) {
+	var buf [32]byte
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return buf, err
+	}
+	if len(b) != 32 {
+		return buf, errors.New("hex field is not 32 bytes")
+	}
+	copy(buf[:], b)
+	return buf, nil
+}
+
+func elementFromHex(s string) (*ristretto255.Element, error) {
+	buf, err := fixedFromHex(s)
+	if err != nil {
+		return nil, err
+	}
+	return ElementFromBytes(buf)
+}
+
+//account public keys are written as addresses of DefaultNetwork, hex is still accepted when reading
+func pkToJSON(pk *ristretto255.Element) string {
+	if pk == nil {
+		return ""
+	}
+	return EncodeAddress(DefaultNetwork, pk)
+}
+
+//a hex public key has 64 characters, more than any account address. Addresses of any network
+//other than DefaultNetwork are rejected, a testnet key must not be read as a mainnet one
+func pkFromJSON(s string) (*ristretto255.Element, error) {
+	if len(s) == 64 {
+		return elementFromHex(s)
+	}
+	network, pk, err := DecodeAddress(s)
+	if err != nil {
+		return nil, err
+	}
+	if network != DefaultNetwork {
+		return nil, ErrWrongNetwork
+	}
+	return pk, nil
+}
+
+func scalarFromHex(s string) (*ristretto255.Scalar, error) {
+	buf, err := fixedFromHex(s)
+	if err != nil {
+		return nil, err
+	}
+	return ScalarFromBytes(buf)
+}
+
+func elementsFromHex(pairs map[string]**ristretto255.Element, fields map[string]string) error {
+	var err error
+	for name, e := range pairs {
+		*e, err = elementFromHex(fields[name])
+		if err != nil {
+			return errors.New(name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func scalarsFromHex(pairs map[string]**ristretto255.Scalar, fields map[string]string) error {
+	var err error
+	for name, s := range pairs {
+		*s, err = scalarFromHex(fields[name])
+		if err != nil {
+			return errors.New(name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+type commitmentJSON struct {
+	Cl string `json:"cl"`
+	Cr string `json:"cr"`
+}
+
+func (comm Commitment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(commitmentJSON{Cl: elementToHex(comm.Cl), Cr: elementToHex(comm.Cr)})
+}
+
+func (comm *Commitment) UnmarshalJSON(b []byte) error {
+	var v commitmentJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return elementsFromHex(map[string]**ristretto255.Element{"cl": &comm.Cl, "cr": &comm.Cr},
+		map[string]string{"cl": v.Cl, "cr": v.Cr})
+}
+
+type innerProductProofJSON struct {
+	A  string   `json:"a"`
+	B  string   `json:"b"`
+	Ls []string `json:"ls"`
+	Rs []string `json:"rs"`
+}
+
+func (self InnerProductProof) MarshalJSON() ([]byte, error) {
+	v := innerProductProofJSON{
+		A:  scalarToHex(self.a),
+		B:  scalarToHex(self.b),
+		Ls: make([]string, len(self.Ls)),
+		Rs: make([]string, len(self.Rs)),
+	}
+	for i := range self.Ls {
+		v.Ls[i] = elementToHex(self.Ls[i])
+	}
+	for i := range self.Rs {
+		v.Rs[i] = elementToHex(self.Rs[i])
+	}
+	return json.Marshal(v)
+}
+
+func (self *InnerProductProof) UnmarshalJSON(b []byte) error {
+	var v innerProductProofJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if len(v.Ls) != len(v.Rs) || len(v.Ls) > maxInnerProductRounds {
+		return ErrProofLength
+	}
+	err := scalarsFromHex(map[string]**ristretto255.Scalar{"a": &self.a, "b": &self.b},
+		map[string]string{"a": v.A, "b": v.B})
+	if err != nil {
+		return err
+	}
+	self.iteration = int32(len(v.Ls))
+	self.Ls = make([]*ristretto255.Element, len(v.Ls))
+	self.Rs = make([]*ristretto255.Element, len(v.Rs))
+	for i := range v.Ls {
+		if self.Ls[i], err = elementFromHex(v.Ls[i]); err != nil {
+			return err
+		}
+		if self.Rs[i], err = elementFromHex(v.Rs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type rangeProofJSON struct {
+	G          string            `json:"g,omitempty"`
+	H          string            `json:"h,omitempty"`
+	Taux       string            `json:"taux"`
+	Mu         string            `json:"mu"`
+	THat       string            `json:"t_hat"`
+	T1         string            `json:"t1"`
+	T2         string            `json:"t2"`
+	A          string            `json:"a"`
+	S          string            `json:"s"`
+	InnerProof InnerProductProof `json:"inner_proof"`
+}
+
+//G and H are omitted when the proof came from the compact encoding
+func (proof RangeProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rangeProofJSON{
+		G:          elementToHex(proof.G),
+		H:          elementToHex(proof.H),
+		Taux:       scalarToHex(proof.Taux),
+		Mu:         scalarToHex(proof.Mu),
+		THat:       scalarToHex(proof.THat),
+		T1:         elementToHex(proof.T1),
+		T2:         elementToHex(proof.T2),
+		A:          elementToHex(proof.A),
+		S:          elementToHex(proof.S),
+		InnerProof: proof.InnerProof,
+	})
+}
+
+func (proof *RangeProof) UnmarshalJSON(b []byte) error {
+	var v rangeProofJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	err := scalarsFromHex(map[string]**ristretto255.Scalar{"taux": &proof.Taux, "mu": &proof.Mu, "t_hat": &proof.THat},
+		map[string]string{"taux": v.Taux, "mu": v.Mu, "t_hat": v.THat})
+	if err != nil {
+		return err
+	}
+	err = elementsFromHex(map[string]**ristretto255.Element{"t1": &proof.T1, "t2": &proof.T2, "a": &proof.A, "s": &proof.S},
+		map[string]string{"t1": v.T1, "t2": v.T2, "a": v.A, "s": v.S})
+	if err != nil {
+		return err
+	}
+	proof.G, proof.H = nil, nil
+	if v.G != "" || v.H != "" {
+		err = elementsFromHex(map[string]**ristretto255.Element{"g": &proof.G, "h": &proof.H},
+			map[string]string{"g": v.G, "h": v.H})
+		if err != nil {
+			return err
+		}
+	}
+	proof.InnerProof = v.InnerProof
+	return nil
+}
+
+type sigmaRangeProofJSON struct {
+	Taux       string            `json:"taux"`
+	Mu         string            `json:"mu"`
+	THat       string            `json:"t_hat"`
+	T1         string            `json:"t1"`
+	T2         string            `json:"t2"`
+	A          string            `json:"a"`
+	S          string            `json:"s"`
+	InnerProof InnerProductProof `json:"inner_proof"`
+}
+
+func (proof SigmaRangeProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sigmaRangeProofJSON{
+		Taux:       scalarToHex(proof.Taux),
+		Mu:         scalarToHex(proof.Mu),
+		THat:       scalarToHex(proof.THat),
+		T1:         elementToHex(proof.T1),
+		T2:         elementToHex(proof.T2),
+		A:          elementToHex(proof.A),
+		S:          elementToHex(proof.S),
+		InnerProof: proof.InnerProof,
+	})
+}
+
+func (proof *SigmaRangeProof) UnmarshalJSON(b []byte) error {
+	var v sigmaRangeProofJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	err := scalarsFromHex(map[string]**ristretto255.Scalar{"taux": &proof.Taux, "mu": &proof.Mu, "t_hat": &proof.THat},
+		map[string]string{"taux": v.Taux, "mu": v.Mu, "t_hat": v.THat})
+	if err != nil {
+		return err
+	}
+	err = elementsFromHex(map[string]**ristretto255.Element{"t1": &proof.T1, "t2": &proof.T2, "a": &proof.A, "s": &proof.S},
+		map[string]string{"t1": v.T1, "t2": v.T2, "a": v.A, "s": v.S})
+	if err != nil {
+		return err
+	}
+	proof.InnerProof = v.InnerProof
+	return nil
+}
+
+type commitmentProofJSON struct {
+	Ay  string `json:"ay"`
+	Acr string `json:"acr"`
+	Ssk string `json:"ssk"`
+	B   string `json:"b"`
+}
+
+func (proof CommitmentProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(commitmentProofJSON{
+		Ay:  elementToHex(proof.ay),
+		Acr: elementToHex(proof.acr),
+		Ssk: scalarToHex(proof.ssk),
+		B:   scalarToHex(proof.B),
+	})
+}
+
+func (proof *CommitmentProof) UnmarshalJSON(b []byte) error {
+	var v commitmentProofJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	err := elementsFromHex(map[string]**ristretto255.Element{"ay": &proof.ay, "acr": &proof.acr},
+		map[string]string{"ay": v.Ay, "acr": v.Acr})
+	if err != nil {
+		return err
+	}
+	return scalarsFromHex(map[string]**ristretto255.Scalar{"ssk": &proof.ssk, "b": &proof.B},
+		map[string]string{"ssk": v.Ssk, "b": v.B})
+}
+
+type withdrawProofJSON struct {
+	RangeProof *RangeProof `json:"range_proof"`
+	CommWD     Commitment  `json:"comm_wd"`
+	Ad         string      `json:"ad"`
+	Ay         string      `json:"ay"`
+	Ag         string      `json:"ag"`
+	Ssk        string      `json:"ssk"`
+	Sr         string      `json:"sr"`
+}
+
+func (proof WithdrawProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(withdrawProofJSON{
+		RangeProof: proof.rangeProof,
+		CommWD:     proof.CommWD,
+		Ad:         elementToHex(proof.ad),
+		Ay:         elementToHex(proof.ay),
+		Ag:         elementToHex(proof.ag),
+		Ssk:        scalarToHex(proof.ssk),
+		Sr:         scalarToHex(proof.sr),
+	})
+}
+
+func (proof *WithdrawProof) UnmarshalJSON(b []byte) error {
+	var v withdrawProofJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.RangeProof == nil {
+		return errors.New("range_proof: missing")
+	}
+	err := elementsFromHex(map[string]**ristretto255.Element{"ad": &proof.ad, "ay": &proof.ay, "ag": &proof.ag},
+		map[string]string{"ad": v.Ad, "ay": v.Ay, "ag": v.Ag})
+	if err != nil {
+		return err
+	}
+	err = scalarsFromHex(map[string]**ristretto255.Scalar{"ssk": &proof.ssk, "sr": &proof.sr},
+		map[string]string{"ssk": v.Ssk, "sr": v.Sr})
+	if err != nil {
+		return err
+	}
+	proof.rangeProof = v.RangeProof
+	proof.CommWD = v.CommWD
+	return nil
+}
+
+type transferProofJSON struct {
+	SigmaRangeProof *SigmaRangeProof    `json:"sigma_range_proof"`
+	Ay              string              `json:"ay"`
+	Ad              string              `json:"ad"`
+	Ab              string              `json:"ab"`
+	AyPrime         string              `json:"ay_prime"`
+	At              string              `json:"at"`
+	Ssk             string              `json:"ssk"`
+	Sr              string              `json:"sr"`
+	Sb              string              `json:"sb"`
+	Stau            string              `json:"stau"`
+	CComm           Commitment          `json:"c_comm"`
+	CPrimeComm      Commitment          `json:"c_prime_comm"`
+	Auditors        []AuditorCiphertext `json:"auditors,omitempty"`
+}
+
+func (proof TransferProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(transferProofJSON{
+		SigmaRangeProof: proof.sigmaRangeProof,
+		Ay:              elementToHex(proof.ay),
+		Ad:              elementToHex(proof.ad),
+		Ab:              elementToHex(proof.ab),
+		AyPrime:         elementToHex(proof.ayPrime),
+		At:              elementToHex(proof.at),
+		Ssk:             scalarToHex(proof.ssk),
+		Sr:              scalarToHex(proof.sr),
+		Sb:              scalarToHex(proof.sb),
+		Stau:            scalarToHex(proof.stau),
+		CComm:           proof.CComm,
+		CPrimeComm:      proof.CPrimeComm,
+		Auditors:        proof.Auditors,
+	})
+}
+
+func (proof *TransferProof) UnmarshalJSON(b []byte) error {
+	var v transferProofJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.SigmaRangeProof == nil {
+		return errors.New("sigma_range_proof: missing")
+	}
+	err := elementsFromHex(map[string]**ristretto255.Element{"ay": &proof.ay, "ad": &proof.ad, "ab": &proof.ab,
+		"ay_prime": &proof.ayPrime, "at": &proof.at},
+		map[string]string{"ay": v.Ay, "ad": v.Ad, "ab": v.Ab, "ay_prime": v.AyPrime, "at": v.At})
+	if err != nil {
+		return err
+	}
+	err = scalarsFromHex(map[string]**ristretto255.Scalar{"ssk": &proof.ssk, "sr": &proof.sr, "sb": &proof.sb, "stau": &proof.stau},
+		map[string]string{"ssk": v.Ssk, "sr": v.Sr, "sb": v.Sb, "stau": v.Stau})
+	if err != nil {
+		return err
+	}
+	proof.sigmaRangeProof = v.SigmaRangeProof
+	proof.CComm = v.CComm
+	proof.CPrimeComm = v.CPrimeComm
+	if len(v.Auditors) > MaxAuditors {
+		return ErrTooManyAuditors
+	}
+	proof.Auditors = v.Auditors
+	return nil
+}
+
+type feeTransferProofJSON struct {
+	Fee           uint64         `json:"fee"`
+	TransferProof *TransferProof `json:"transfer_proof"`
+}
+
+//FeeTransferProof needs its own methods, the ones promoted from the embedded TransferProof drop the fee
+func (proof FeeTransferProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(feeTransferProofJSON{Fee: proof.Fee, TransferProof: &proof.TransferProof})
+}
+
+func (proof *FeeTransferProof) UnmarshalJSON(b []byte) error {
+	var v feeTransferProofJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.TransferProof == nil {
+		return errors.New("transfer_proof: missing")
+	}
+	proof.Fee = v.Fee
+	proof.TransferProof = *v.TransferProof
+	return nil
+}
+
+type auditorCiphertextJSON struct {
+	Auditor string `json:"auditor"`
+	Cl      string `json:"cl"`
+	Ae      string `json:"ae"`
+}
+
+func (ct AuditorCiphertext) MarshalJSON() ([]byte, error) {
+	return json.Marshal(auditorCiphertextJSON{
+		Auditor: pkToJSON(ct.Auditor),
+		Cl:      elementToHex(ct.Cl),
+		Ae:      elementToHex(ct.ae),
+	})
+}
+
+func (ct *AuditorCiphertext) UnmarshalJSON(b []byte) error {
+	var v auditorCiphertextJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var err error
+	if ct.Auditor, err = pkFromJSON(v.Auditor); err != nil {
+		return errors.New("auditor: " + err.Error())
+	}
+	return elementsFromHex(map[string]**ristretto255.Element{"cl": &ct.Cl, "ae": &ct.ae},
+		map[string]string{"cl": v.Cl, "ae": v.Ae})
+}
+
+func (addr StealthAddress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(addr.Encode(DefaultNetwork))
+}
+
+func (addr *StealthAddress) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	network, decoded, err := DecodeStealthAddress(s)
+	if err != nil {
+		return err
+	}
+	if network != DefaultNetwork {
+		return ErrWrongNetwork
+	}
+	*addr = *decoded
+	return nil
+}
+
+type stealthOutputJSON struct {
+	R         string `json:"r"`
+	OneTimePk string `json:"one_time_pk"`
+}
+
+func (out StealthOutput) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stealthOutputJSON{R: elementToHex(out.R), OneTimePk: pkToJSON(out.OneTimePk)})
+}
+
+func (out *StealthOutput) UnmarshalJSON(b []byte) error {
+	var v stealthOutputJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var err error
+	if out.OneTimePk, err = pkFromJSON(v.OneTimePk); err != nil {
+		return errors.New("one_time_pk: " + err.Error())
+	}
+	out.R, err = elementFromHex(v.R)
+	return err
+}
@@ -0,0 +1,215 @@
+package confidential
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"io/ioutil"
+)
+
+const (
+	KeystoreVersion = 1
+	keystoreKDF     = "argon2id"
+	keystoreCipher  = "xchacha20-poly1305"
+)
+
+//A keystore file chooses its own KDF cost, the limits keep a crafted file from exhausting the memory
+//or CPU of the wallet that loads it
+const (
+	MaxKDFTime   = 16
+	MaxKDFMemory = 1 << 20 //KiB, 1 GiB
+)
+
+var (
+	ErrKeystorePassword  = errors.New("keystore password is wrong or the file is corrupted")
+	ErrKeystoreKDFParams = errors.New("invalid keystore kdf params")
+)
+
+type KDFParams struct {
+	Salt    string `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` //KiB
+	Threads uint8  `json:"threads"`
+}
+
+//DefaultKDFParams follows the argon2id recommendation of RFC 9106 for memory-constrained settings
+var DefaultKDFParams = KDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+//Keystore is the JSON keystore file. The public key and parameters are bound to the ciphertext
+//as additional data, the plaintext is sk||Comm||balance||PubBalance, followed by the spend key
+//if the account has one.
+type Keystore struct {
+	Version    int       `json:"version"`
+	Pk         string    `json:"pk"`
+	ViewOnly   bool      `json:"view_only,omitempty"`
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdfparams"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+}
+
+func (ks *Keystore) additionalData() []byte {
+	header := *ks
+	header.Nonce, header.Ciphertext = "", ""
+	b, _ := json.Marshal(header)
+	return b
+}
+
+func (ks *Keystore) key(password []byte) ([]byte, error) {
+	if ks.KDF != keystoreKDF || ks.Cipher != keystoreCipher || ks.Version != KeystoreVersion {
+		return nil, errors.New("unsupported keystore format")
+	}
+	salt, err := hex.DecodeString(ks.KDFParams.Salt)
+	if err != nil {
+		return nil, err
+	}
+	params := ks.KDFParams
+	if params.Time == 0 || params.Time > MaxKDFTime || params.Memory > MaxKDFMemory || params.Threads == 0 {
+		return nil, ErrKeystoreKDFParams
+	}
+	return argon2.IDKey(password, salt, ks.KDFParams.Time, ks.KDFParams.Memory, ks.KDFParams.Threads, chacha20poly1305.KeySize), nil
+}
+
+//EncryptKeystore encrypts the secret key, the last known commitment and its plaintext balance.
+//The balance is decrypted with GetCommitmentBalance unless it is already cached.
+func (acc *Account) EncryptKeystore(password []byte, params KDFParams) (*Keystore, error) {
+	balance := acc.GetCommitmentBalance()
+	if balance == nil {
+		return nil, errors.New("balance of the commitment is out of range")
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	params.Salt = hex.EncodeToString(salt)
+	ks := &Keystore{
+		Version:   KeystoreVersion,
+		Pk:        pkToJSON(acc.Pk),
+		ViewOnly:  acc.viewOnly,
+		KDF:       keystoreKDF,
+		KDFParams: params,
+		Cipher:    keystoreCipher,
+		Nonce:     hex.EncodeToString(nonce),
+	}
+	key, err := ks.key(password)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	sk := acc.ExportSk()
+	plaintext := append(sk[:], acc.Comm.Encode()...)
+	plaintext = append(plaintext, make([]byte, 16)...)
+	binary.LittleEndian.PutUint64(plaintext[96:], ScalartoInt(balance))
+	binary.LittleEndian.PutUint64(plaintext[104:], acc.PubBalance)
+	if acc.CanSpend() {
+		spendSk := ScalarToBytes(acc.spendSk)
+		plaintext = append(plaintext, spendSk[:]...)
+	}
+	ks.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, plaintext, ks.additionalData()))
+	return ks, nil
+}
+
+//DecryptKeystore restores the account with the stored commitment and balance, the balance is
+//checked against the commitment so no discrete log has to be solved.
+func DecryptKeystore(ks *Keystore, password []byte) (*Account, error) {
+	pk, err := pkFromJSON(ks.Pk)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ks.key(password)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(ks.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(ks.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid keystore nonce")
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, ks.additionalData())
+	if err != nil {
+		return nil, ErrKeystorePassword
+	}
+	if len(plaintext) != 112 && len(plaintext) != 144 {
+		return nil, errors.New("invalid keystore plaintext")
+	}
+
+	var sk [32]byte
+	copy(sk[:], plaintext[:32])
+	acc := new(Account)
+	if err := acc.ImportSk(sk); err != nil {
+		return nil, err
+	}
+	if pk.Equal(acc.Pk) != 1 {
+		return nil, errors.New("keystore public key does not match the secret key")
+	}
+	var comm Commitment
+	if err := comm.Decode(plaintext[32:96]); err != nil {
+		return nil, err
+	}
+	balance := binary.LittleEndian.Uint64(plaintext[96:104])
+	v, _ := InttoScalar(balance)
+	vEncrypt := new(ristretto255.Element).Add(comm.Cl, new(ristretto255.Element).Negate(acc.mult(acc.sk, comm.Cr)))
+	if vEncrypt.Equal(acc.mult(v, acc.basePoint)) != 1 {
+		return nil, errors.New("keystore balance does not match the commitment")
+	}
+	acc.Comm = &comm
+	acc.cachedComm = comm.Encode()
+	acc.cachedBalance = balance
+	acc.PubBalance = binary.LittleEndian.Uint64(plaintext[104:112])
+	if len(plaintext) == 144 {
+		var spendSk [32]byte
+		copy(spendSk[:], plaintext[112:])
+		if err := acc.ImportSpendKey(spendSk); err != nil {
+			return nil, err
+		}
+	}
+	acc.viewOnly = ks.ViewOnly
+	return acc, nil
+}
+
+func (acc *Account) SaveKeystore(path string, password []byte, params KDFParams) error {
+	ks, err := acc.EncryptKeystore(password, params)
+	if err != nil {
+		return err
+	}
+	text, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, text, 0600)
+}
+
+func LoadKeystore(path string, password []byte) (*Account, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ks Keystore
+	if err := json.Unmarshal(text, &ks); err != nil {
+		return nil, err
+	}
+	return DecryptKeystore(&ks, password)
+}
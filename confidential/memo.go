@@ -0,0 +1,151 @@
+package confidential
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+//Encrypted memos. The sender picks an ephemeral key e, publishes R = e*G and encrypts the memo with
+//chacha20-poly1305 under a key derived from e*Pk = sk*R. Every key encrypts a single memo, so the nonce
+//is fixed. A memo is attached to a transfer with GenMemoTransferProof and checked with VerifyMemoTransferProof
+//or ApplyMemoTransfer, they bind the memo to the transcript and require it to be sealed to the recipient, so
+//it can not be moved to another transfer or altered without invalidating the proof.
+
+const MaxMemoSize = 512
+
+//memoOverhead is the poly1305 tag appended to every ciphertext
+const memoOverhead = 16
+
+var (
+	ErrMemoSize      = errors.New("memo too long")
+	ErrMemo          = errors.New("memo can not be decrypted with this account")
+	ErrMemoRecipient = errors.New("memo is not sealed to the recipient of the transfer")
+)
+
+var memoDomain = []byte("xv-crypto memo")
+
+type Memo struct {
+	R          *ristretto255.Element
+	Recipient  *ristretto255.Element
+	Ciphertext []byte
+}
+
+func memoAEAD(shared, r, recipient *ristretto255.Element) (cipher.AEAD, error) {
+	salt := append(r.Encode(nil), recipient.Encode(nil)...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared.Encode(nil), salt, memoDomain), key); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+//SealMemo encrypts plaintext to recipient
+func (acc *Account) SealMemo(recipient *ristretto255.Element, plaintext []byte) (*Memo, error) {
+	if len(plaintext) > MaxMemoSize {
+		return nil, ErrMemoSize
+	}
+	e := acc.RandScalar()
+	r := acc.mult(e, acc.basePoint)
+	aead, err := memoAEAD(acc.mult(e, recipient), r, recipient)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	return &Memo{
+		R:          r,
+		Recipient:  recipient,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, memoDomain),
+	}, nil
+}
+
+//OpenMemo decrypts a memo sent to this account, view-only accounts can read memos
+func (acc *Account) OpenMemo(memo *Memo) ([]byte, error) {
+	if memo == nil || memo.Recipient.Equal(acc.Pk) != 1 {
+		return nil, ErrMemo
+	}
+	aead, err := memoAEAD(acc.mult(acc.sk, memo.R), memo.R, memo.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	plaintext, err := aead.Open(nil, nonce, memo.Ciphertext, memoDomain)
+	if err != nil {
+		return nil, ErrMemo
+	}
+	return plaintext, nil
+}
+
+//Bind returns the transcript of a proof carrying the memo, a nil memo leaves trans unchanged
+func (memo *Memo) Bind(trans [64]byte) [64]byte {
+	if memo == nil {
+		return trans
+	}
+	h := append(append([]byte{}, trans[:]...), memoDomain...)
+	h = append(h, serialize(memo)...)
+	return sha512.Sum512(h)
+}
+
+func (memo *Memo) sealedTo(yPrime *ristretto255.Element) bool {
+	return memo != nil && memo.R != nil && memo.Recipient != nil && yPrime != nil && memo.Recipient.Equal(yPrime) == 1
+}
+
+//GenMemoTransferProof is GenTransferProof with the memo bound to the transcript, memo must be sealed to yPrime.
+//The nullifier and the spend authorization of the transfer are generated over memo.Bind(trans).
+func (acc *Account) GenMemoTransferProof(trans [64]byte, amount uint64, yPrime *ristretto255.Element, memo *Memo, auditors ...*ristretto255.Element) (*TransferProof, error) {
+	if !memo.sealedTo(yPrime) {
+		return nil, ErrMemoRecipient
+	}
+	return acc.GenTransferProof(memo.Bind(trans), amount, yPrime, auditors...)
+}
+
+//VerifyMemoTransferProof verifies a transfer generated by GenMemoTransferProof
+func (sc *SmartContract) VerifyMemoTransferProof(trans [64]byte, proof *TransferProof, memo *Memo, y, yPrime *ristretto255.Element) bool {
+	if !memo.sealedTo(yPrime) {
+		return false
+	}
+	return sc.VerifyTransferProof(memo.Bind(trans), proof, y, yPrime)
+}
+
+//ApplyMemoTransfer is ApplyTransfer for a transfer generated by GenMemoTransferProof
+func (sc *SmartContract) ApplyMemoTransfer(trans [64]byte, proof *TransferProof, auth *SpendAuthorization, nullifier *Nullifier, memo *Memo, y, yPrime *ristretto255.Element) bool {
+	if !memo.sealedTo(yPrime) {
+		return false
+	}
+	return sc.ApplyTransfer(memo.Bind(trans), proof, auth, nullifier, y, yPrime)
+}
+
+//R||Recipient||varbytes(Ciphertext)
+func (memo *Memo) Serialization(sink *ZeroCopySink) {
+	sink.WriteFixedElement(memo.R)
+	sink.WriteFixedElement(memo.Recipient)
+	EncodeBytes(sink, memo.Ciphertext)
+}
+
+func (memo *Memo) Deserialization(source *ZeroCopySource) error {
+	if err := nextFixedElements(source, &memo.R, &memo.Recipient); err != nil {
+		return err
+	}
+	ciphertext, err := DecodeBytes(source)
+	if err != nil {
+		return err
+	}
+	if len(ciphertext) > MaxMemoSize+memoOverhead {
+		return ErrMemoSize
+	}
+	memo.Ciphertext = ciphertext
+	return nil
+}
+
+func (memo *Memo) Serialize() []byte {
+	return serialize(memo)
+}
+
+func (memo *Memo) Deserialize(b []byte) error {
+	return deserialize(memo, b)
+}
@@ -0,0 +1,653 @@
+package confidential
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+	"io"
+	"sort"
+)
+
+//Multisignature accounts. The account key is a ThresholdKey from SplitKey or the DKG, and k signers
+//produce the proofs together with a coordinator, who knows the balance (see ThresholdKey.Combine) and
+//the amount but no key share. The coordinator builds every part of the proof that does not involve
+//the account secret key and sends a request to the signers, then:
+//
+//  1. Commit: every signer picks its nonces and sends a hash of them.
+//  2. Reveal: once all hashes are known, the signers reveal the nonces with DLEQ proofs.
+//  3. Respond: every signer recomputes the aggregated nonces and the challenges of the proof from the
+//     request and returns its responses, linear in the nonces and in its Lagrange weighted key share.
+//  4. Finalize: the coordinator checks each response against the verification key of the signer and
+//     sums them into a standard TransferProof or WithdrawProof.
+//
+//Signers check the request against the amount and the randomness of the new commitment before they
+//take part, and derive every challenge themselves, so a coordinator can not get a response for a
+//different statement.
+
+var (
+	ErrMultisigRound   = errors.New("multisig message out of round")
+	ErrMultisigMessage = errors.New("invalid multisig message")
+)
+
+//multisigStatement describes the joint part of a proof. Signers pick one nonce k per base, publishing
+//k*G and k*base, and response m of a signer is sum(e[m][j]*k[j]) + f[m]*lambda*sk.
+type multisigStatement interface {
+	bases() []*ristretto255.Element
+	challenges(kg, kb []*ristretto255.Element) (e [][]*ristretto255.Scalar, f []*ristretto255.Scalar)
+}
+
+type MultisigCommitment struct {
+	Signer uint32
+	Hash   [32]byte
+}
+
+type MultisigNonce struct {
+	Signer uint32
+	KG, KB []*ristretto255.Element
+	proofs []DLEQProof
+}
+
+type MultisigResponse struct {
+	Signer    uint32
+	Responses []*ristretto255.Scalar
+}
+
+func (nonce *MultisigNonce) hash() [32]byte {
+	return sha256.Sum256(serialize(nonce))
+}
+
+func (nonce *MultisigNonce) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint32(nonce.Signer)
+	sink.WriteVarUint(uint64(len(nonce.KG)))
+	for i := range nonce.KG {
+		sink.WriteFixedElement(nonce.KG[i])
+		sink.WriteFixedElement(nonce.KB[i])
+		nonce.proofs[i].Serialization(sink)
+	}
+}
+
+func (nonce *MultisigNonce) Deserialization(source *ZeroCopySource) error {
+	var eof bool
+	if nonce.Signer, eof = source.NextUint32(); eof {
+		return ErrProofLength
+	}
+	count, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return ErrIrregularData
+	}
+	if eof || count > 8 || source.Len() < count*4*32 {
+		return ErrProofLength
+	}
+	nonce.KG = make([]*ristretto255.Element, count)
+	nonce.KB = make([]*ristretto255.Element, count)
+	nonce.proofs = make([]DLEQProof, count)
+	for i := range nonce.KG {
+		if err := nextFixedElements(source, &nonce.KG[i], &nonce.KB[i]); err != nil {
+			return err
+		}
+		if err := nonce.proofs[i].Deserialization(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (response *MultisigResponse) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint32(response.Signer)
+	sink.WriteVarUint(uint64(len(response.Responses)))
+	for _, s := range response.Responses {
+		sink.WriteFixedScalar(s)
+	}
+}
+
+func (response *MultisigResponse) Deserialization(source *ZeroCopySource) error {
+	var eof bool
+	if response.Signer, eof = source.NextUint32(); eof {
+		return ErrProofLength
+	}
+	count, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return ErrIrregularData
+	}
+	if eof || count > 8 || source.Len() < count*32 {
+		return ErrProofLength
+	}
+	response.Responses = make([]*ristretto255.Scalar, count)
+	for i := range response.Responses {
+		if err := nextFixedScalars(source, &response.Responses[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//checkSigners requires at least T distinct signers in 1..N, sorted
+func checkSigners(key *ThresholdKey, signers []uint32) error {
+	if len(signers) < key.T {
+		return ErrNotEnoughShares
+	}
+	for i, signer := range signers {
+		if signer == 0 || int(signer) > key.N || (i > 0 && signers[i-1] >= signer) {
+			return errors.New("signers must be sorted distinct indexes in 1..N")
+		}
+	}
+	return nil
+}
+
+//multisigSession holds the messages of one protocol run, it is shared by signers and the coordinator
+type multisigSession struct {
+	key         *ThresholdKey
+	signers     []uint32
+	statement   multisigStatement
+	commitments map[uint32][32]byte
+	nonces      map[uint32]*MultisigNonce
+}
+
+func (session *multisigSession) receiveCommitments(commitments []*MultisigCommitment) error {
+	session.commitments = make(map[uint32][32]byte)
+	for _, commitment := range commitments {
+		session.commitments[commitment.Signer] = commitment.Hash
+	}
+	for _, signer := range session.signers {
+		if _, ok := session.commitments[signer]; !ok {
+			return errors.New("missing multisig commitment")
+		}
+	}
+	return nil
+}
+
+//receiveNonces checks every nonce against its commitment and DLEQ proofs, and aggregates them
+func (session *multisigSession) receiveNonces(nonces []*MultisigNonce) ([]*ristretto255.Element, []*ristretto255.Element, error) {
+	bases := session.statement.bases()
+	basePoint := hdBasePoint()
+	session.nonces = make(map[uint32]*MultisigNonce)
+	for _, nonce := range nonces {
+		hash, ok := session.commitments[nonce.Signer]
+		if !ok || hash != nonce.hash() || len(nonce.KG) != len(bases) || len(nonce.KB) != len(bases) || len(nonce.proofs) != len(bases) {
+			return nil, nil, errors.New("multisig nonce does not match its commitment")
+		}
+		for j, base := range bases {
+			if !verifyDLEQ(nonce.proofs[j], basePoint, nonce.KG[j], base, nonce.KB[j]) {
+				return nil, nil, ErrMultisigMessage
+			}
+		}
+		session.nonces[nonce.Signer] = nonce
+	}
+	kg := make([]*ristretto255.Element, len(bases))
+	kb := make([]*ristretto255.Element, len(bases))
+	for j := range bases {
+		kg[j] = new(ristretto255.Element).Zero()
+		kb[j] = new(ristretto255.Element).Zero()
+	}
+	for _, signer := range session.signers {
+		nonce, ok := session.nonces[signer]
+		if !ok {
+			return nil, nil, errors.New("missing multisig nonce")
+		}
+		for j := range bases {
+			kg[j] = new(ristretto255.Element).Add(kg[j], nonce.KG[j])
+			kb[j] = new(ristretto255.Element).Add(kb[j], nonce.KB[j])
+		}
+	}
+	return kg, kb, nil
+}
+
+type MultisigSigner struct {
+	session multisigSession
+	share   *KeyShare
+	random  io.Reader
+	k       []*ristretto255.Scalar
+	nonce   *MultisigNonce
+	round   int
+}
+
+func newMultisigSigner(key *ThresholdKey, share *KeyShare, signers []uint32, statement multisigStatement) (*MultisigSigner, error) {
+	if err := checkSigners(key, signers); err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(signers), func(i int) bool { return signers[i] >= share.Index })
+	if i == len(signers) || signers[i] != share.Index {
+		return nil, errors.New("share is not in the signer set")
+	}
+	return &MultisigSigner{
+		session: multisigSession{key: key, signers: signers, statement: statement},
+		share:   share,
+		random:  rand.Reader,
+	}, nil
+}
+
+//Commit picks the nonces of this signer and returns their hash
+func (signer *MultisigSigner) Commit() (*MultisigCommitment, error) {
+	if signer.round != 0 {
+		return nil, ErrMultisigRound
+	}
+	basePoint := hdBasePoint()
+	nonce := &MultisigNonce{Signer: signer.share.Index}
+	for _, base := range signer.session.statement.bases() {
+		k, err := randomScalar(signer.random)
+		if err != nil {
+			return nil, err
+		}
+		kg := new(ristretto255.Element).ScalarMult(k, basePoint)
+		kb := new(ristretto255.Element).ScalarMult(k, base)
+		signer.k = append(signer.k, k)
+		nonce.KG = append(nonce.KG, kg)
+		nonce.KB = append(nonce.KB, kb)
+		nonce.proofs = append(nonce.proofs, proveDLEQ(k, basePoint, kg, base, kb))
+	}
+	signer.nonce = nonce
+	signer.round = 1
+	return &MultisigCommitment{Signer: nonce.Signer, Hash: nonce.hash()}, nil
+}
+
+//Reveal returns the nonces once the commitments of all signers are known
+func (signer *MultisigSigner) Reveal(commitments []*MultisigCommitment) (*MultisigNonce, error) {
+	if signer.round != 1 {
+		return nil, ErrMultisigRound
+	}
+	if err := signer.session.receiveCommitments(commitments); err != nil {
+		return nil, err
+	}
+	if signer.session.commitments[signer.share.Index] != signer.nonce.hash() {
+		return nil, errors.New("own multisig commitment was replaced")
+	}
+	signer.round = 2
+	return signer.nonce, nil
+}
+
+//Respond computes the challenges from the revealed nonces and returns the responses, the nonces are
+//erased so they are never used twice
+func (signer *MultisigSigner) Respond(nonces []*MultisigNonce) (*MultisigResponse, error) {
+	if signer.round != 2 {
+		return nil, ErrMultisigRound
+	}
+	signer.round = 3
+	kg, kb, err := signer.session.receiveNonces(nonces)
+	if err != nil {
+		return nil, err
+	}
+	e, f := signer.session.statement.challenges(kg, kb)
+	lambdaSk := Mul(lagrangeCoefficient(signer.session.signers, signer.share.Index), signer.share.sk)
+	response := &MultisigResponse{Signer: signer.share.Index}
+	for m := range f {
+		s := Mul(f[m], lambdaSk)
+		for j, k := range signer.k {
+			s = SumScalars(s, Mul(e[m][j], k))
+		}
+		response.Responses = append(response.Responses, s)
+	}
+	signer.k = nil
+	return response, nil
+}
+
+//MultisigCoordinator assembles the proof, finish builds it from the aggregated nonces and summed responses
+type MultisigCoordinator struct {
+	session multisigSession
+	finish  func(kg, kb []*ristretto255.Element, responses []*ristretto255.Scalar) Codec
+}
+
+//Finalize verifies every response against the verification key of its signer and returns the
+//*TransferProof, *WithdrawProof or *Nullifier of the session
+func (coordinator *MultisigCoordinator) Finalize(commitments []*MultisigCommitment, nonces []*MultisigNonce, responses []*MultisigResponse) (Codec, error) {
+	session := &coordinator.session
+	if err := session.receiveCommitments(commitments); err != nil {
+		return nil, err
+	}
+	kg, kb, err := session.receiveNonces(nonces)
+	if err != nil {
+		return nil, err
+	}
+	e, f := session.statement.challenges(kg, kb)
+	basePoint := hdBasePoint()
+	sums := make([]*ristretto255.Scalar, len(f))
+	for m := range sums {
+		sums[m] = new(ristretto255.Scalar).Zero()
+	}
+	received := make(map[uint32]*MultisigResponse)
+	for _, response := range responses {
+		received[response.Signer] = response
+	}
+	for _, signer := range session.signers {
+		response, ok := received[signer]
+		if !ok || len(response.Responses) != len(f) {
+			return nil, errors.New("missing multisig response")
+		}
+		lambdaVK := new(ristretto255.Element).ScalarMultWnaf(lagrangeCoefficient(session.signers, signer),
+			session.key.VerificationKeys[signer-1])
+		nonce := session.nonces[signer]
+		for m, s := range response.Responses {
+			right := new(ristretto255.Element).ScalarMultWnaf(f[m], lambdaVK)
+			for j := range nonce.KG {
+				right = new(ristretto255.Element).Add(right, new(ristretto255.Element).ScalarMultWnaf(e[m][j], nonce.KG[j]))
+			}
+			if new(ristretto255.Element).ScalarMultWnaf(s, basePoint).Equal(right) != 1 {
+				return nil, errors.New("invalid multisig response")
+			}
+			sums[m] = SumScalars(sums[m], s)
+		}
+	}
+	return coordinator.finish(kg, kb, sums), nil
+}
+
+//newCoordinatorProver returns a range prover with fresh randomness for the coordinator nonces
+func newCoordinatorProver() (*RangeProver, error) {
+	var seed [32]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return nil, err
+	}
+	return NewRangeProver(RANGEBITS, seed)
+}
+
+//MultisigTransferRequest is sent to the signers of a transfer, R is the randomness of CComm so that
+//signers can check the amount and the receiver
+type MultisigTransferRequest struct {
+	Trans   [64]byte
+	Amount  uint64
+	YPrime  *ristretto255.Element
+	Comm    *Commitment //ledger commitment of the account
+	R       *ristretto255.Scalar
+	Signers []uint32
+	Proof   *TransferProof //without ay, ab and ssk
+	KbG     *ristretto255.Element
+}
+
+//transferStatement: the only nonce is ksk, with ay = ksk*G and ab = kb*G + ksk*(z^2*d + z^3*crNew)
+type transferStatement struct {
+	request *MultisigTransferRequest
+	trans   [64]byte //after the sigma range proof
+	base    *ristretto255.Element
+}
+
+func newTransferStatement(request *MultisigTransferRequest) *transferStatement {
+	proof := request.Proof
+	trans, _, z, _ := rangeTranscript(request.Trans, proof.sigmaRangeProof.A, proof.sigmaRangeProof.S,
+		proof.sigmaRangeProof.T1, proof.sigmaRangeProof.T2)
+	zz := Mul(z, z)
+	crNew := new(ristretto255.Element).Add(request.Comm.Cr, new(ristretto255.Element).Negate(proof.CComm.Cr))
+	return &transferStatement{
+		request: request,
+		trans:   trans,
+		base: SumElements(new(ristretto255.Element).ScalarMultWnaf(zz, proof.CComm.Cr),
+			new(ristretto255.Element).ScalarMultWnaf(Mul(zz, z), crNew)),
+	}
+}
+
+func (statement *transferStatement) bases() []*ristretto255.Element {
+	return []*ristretto255.Element{statement.base}
+}
+
+func (statement *transferStatement) ay(kg []*ristretto255.Element) *ristretto255.Element {
+	return kg[0]
+}
+
+func (statement *transferStatement) ab(kb []*ristretto255.Element) *ristretto255.Element {
+	return new(ristretto255.Element).Add(statement.request.KbG, kb[0])
+}
+
+func (statement *transferStatement) challenge(kg, kb []*ristretto255.Element) *ristretto255.Scalar {
+	proof := statement.request.Proof
+	_, c := UpdateTranscript(statement.trans, append([]*ristretto255.Element{statement.ay(kg), proof.ad,
+		statement.ab(kb), proof.ayPrime, proof.at}, auditorTranscript(proof.Auditors)...)...)
+	return c
+}
+
+func (statement *transferStatement) challenges(kg, kb []*ristretto255.Element) ([][]*ristretto255.Scalar, []*ristretto255.Scalar) {
+	one, _ := InttoScalar(uint64(1))
+	return [][]*ristretto255.Scalar{{one}}, []*ristretto255.Scalar{statement.challenge(kg, kb)}
+}
+
+//NewMultisigTransfer prepares a transfer of amount from the account of key, whose ledger commitment comm
+//holds balance, to yPrime. The request goes to every signer, the coordinator later assembles the proof.
+func NewMultisigTransfer(key *ThresholdKey, comm *Commitment, balance uint64, trans [64]byte, amount uint64,
+	yPrime *ristretto255.Element, signers []uint32, auditors ...*ristretto255.Element) (*MultisigCoordinator, *MultisigTransferRequest, error) {
+	if err := checkSigners(key, signers); err != nil {
+		return nil, nil, err
+	}
+	if amount > balance {
+		return nil, nil, errors.New("amount exceeds the balance")
+	}
+	if len(auditors) > MaxAuditors {
+		return nil, nil, ErrTooManyAuditors
+	}
+	prover, err := newCoordinatorProver()
+	if err != nil {
+		return nil, nil, err
+	}
+	basePoint := prover.G
+	b, err := InttoScalar(amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	bPrime, err := InttoScalar(balance - amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := prover.RandScalar()
+	cComm := Commitment{
+		Cl: SumElements(new(ristretto255.Element).ScalarMult(b, basePoint), new(ristretto255.Element).ScalarMult(r, key.Pk)),
+		Cr: new(ristretto255.Element).ScalarMult(r, basePoint),
+	}
+	cPrimeComm := Commitment{
+		Cl: SumElements(new(ristretto255.Element).ScalarMult(b, basePoint), new(ristretto255.Element).ScalarMult(r, yPrime)),
+		Cr: DeepCopyElement(cComm.Cr),
+	}
+	sigmaRangeProof, z, _, err := prover.GenSigmaRangeProof(trans, amount, balance-amount,
+		ElgamalCommitment{v: b}, ElgamalCommitment{v: bPrime})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kr := prover.RandScalar()
+	kb := prover.RandScalar()
+	ktau := prover.RandScalar()
+	proof := &TransferProof{
+		sigmaRangeProof: sigmaRangeProof,
+		ad:              new(ristretto255.Element).ScalarMult(kr, basePoint),
+		ayPrime:         new(ristretto255.Element).ScalarMult(kr, new(ristretto255.Element).Add(key.Pk, new(ristretto255.Element).Negate(yPrime))),
+		at: SumElements(new(ristretto255.Element).ScalarMult(new(ristretto255.Scalar).Negate(kb), basePoint),
+			new(ristretto255.Element).ScalarMult(ktau, prover.H)),
+		CComm:      cComm,
+		CPrimeComm: cPrimeComm,
+	}
+	for _, auditor := range auditors {
+		proof.Auditors = append(proof.Auditors, AuditorCiphertext{
+			Auditor: auditor,
+			Cl:      SumElements(new(ristretto255.Element).ScalarMult(b, basePoint), new(ristretto255.Element).ScalarMult(r, auditor)),
+			ae:      new(ristretto255.Element).ScalarMult(kr, new(ristretto255.Element).Add(key.Pk, new(ristretto255.Element).Negate(auditor))),
+		})
+	}
+	request := &MultisigTransferRequest{
+		Trans:   trans,
+		Amount:  amount,
+		YPrime:  yPrime,
+		Comm:    comm,
+		R:       r,
+		Signers: signers,
+		Proof:   proof,
+		KbG:     new(ristretto255.Element).ScalarMult(kb, basePoint),
+	}
+	statement := newTransferStatement(request)
+	zz := Mul(z, z)
+	finish := func(kg, kbs []*ristretto255.Element, responses []*ristretto255.Scalar) Codec {
+		c := statement.challenge(kg, kbs)
+		final := *proof
+		final.ay = statement.ay(kg)
+		final.ab = statement.ab(kbs)
+		final.ssk = responses[0]
+		final.sr = SumScalars(kr, Mul(c, r))
+		final.sb = SumScalars(kb, Mul(c, SumScalars(Mul(b, zz), Mul(bPrime, zz, z))))
+		final.stau = SumScalars(ktau, Mul(c, sigmaRangeProof.Taux))
+		return &final
+	}
+	return &MultisigCoordinator{
+		session: multisigSession{key: key, signers: signers, statement: statement},
+		finish:  finish,
+	}, request, nil
+}
+
+//NewMultisigTransferSigner checks the request for key and returns the signer of share
+func NewMultisigTransferSigner(key *ThresholdKey, share *KeyShare, request *MultisigTransferRequest) (*MultisigSigner, error) {
+	proof := request.Proof
+	if proof == nil || proof.sigmaRangeProof == nil || request.Comm == nil {
+		return nil, ErrMultisigMessage
+	}
+	basePoint := hdBasePoint()
+	b, err := InttoScalar(request.Amount)
+	if err != nil {
+		return nil, err
+	}
+	bG := new(ristretto255.Element).ScalarMult(b, basePoint)
+	encrypts := func(cl, y *ristretto255.Element) bool {
+		return cl.Equal(SumElements(bG, new(ristretto255.Element).ScalarMult(request.R, y))) == 1
+	}
+	if !encrypts(proof.CComm.Cl, key.Pk) || !encrypts(proof.CPrimeComm.Cl, request.YPrime) ||
+		proof.CComm.Cr.Equal(new(ristretto255.Element).ScalarMult(request.R, basePoint)) != 1 ||
+		proof.CPrimeComm.Cr.Equal(proof.CComm.Cr) != 1 {
+		return nil, errors.New("transfer request does not encrypt the amount")
+	}
+	for _, ct := range proof.Auditors {
+		if !encrypts(ct.Cl, ct.Auditor) {
+			return nil, errors.New("transfer request does not encrypt the amount")
+		}
+	}
+	return newMultisigSigner(key, share, request.Signers, newTransferStatement(request))
+}
+
+//MultisigWithdrawRequest is sent to the signers of a withdraw. The range proof over the new balance is
+//blinded with the account key, so the signers share tau1, tau2 and taux as well.
+type MultisigWithdrawRequest struct {
+	Trans    [64]byte
+	Amount   uint64
+	Comm     *Commitment //ledger commitment of the account
+	R        *ristretto255.Scalar
+	Signers  []uint32
+	CommWD   Commitment
+	A, S     *ristretto255.Element
+	T1G, T2G *ristretto255.Element //t1*G and t2*G, the signers add tau1*H and tau2*H
+	Ay, Ag   *ristretto255.Element
+}
+
+//withdrawStatement: nonces tau1, tau2 with base H = Cr of the new balance and ksk with base CommWD.Cr
+type withdrawStatement struct {
+	request *MultisigWithdrawRequest
+	h       *ristretto255.Element
+	z       *ristretto255.Scalar
+}
+
+func newWithdrawStatement(request *MultisigWithdrawRequest) *withdrawStatement {
+	trans, _ := UpdateTranscript(request.Trans, request.A, request.S)
+	_, z := UpdateTranscript(trans, request.A, request.S)
+	return &withdrawStatement{
+		request: request,
+		h:       new(Commitment).Sub(request.Comm, &request.CommWD).Cr,
+		z:       z,
+	}
+}
+
+func (statement *withdrawStatement) bases() []*ristretto255.Element {
+	return []*ristretto255.Element{statement.h, statement.h, statement.request.CommWD.Cr}
+}
+
+func (statement *withdrawStatement) t1t2(kb []*ristretto255.Element) (*ristretto255.Element, *ristretto255.Element) {
+	return new(ristretto255.Element).Add(statement.request.T1G, kb[0]), new(ristretto255.Element).Add(statement.request.T2G, kb[1])
+}
+
+//challenges returns x and c, with taux = x*tau1 + x^2*tau2 + z^2*sk and ssk = ksk + c*sk
+func (statement *withdrawStatement) challenges(kg, kb []*ristretto255.Element) ([][]*ristretto255.Scalar, []*ristretto255.Scalar) {
+	request := statement.request
+	t1, t2 := statement.t1t2(kb)
+	trans, _, _, x := rangeTranscript(request.Trans, request.A, request.S, t1, t2)
+	_, c := UpdateTranscript(trans, kb[2], request.Ay, request.Ag)
+	zero := new(ristretto255.Scalar).Zero()
+	one, _ := InttoScalar(uint64(1))
+	return [][]*ristretto255.Scalar{{x, Mul(x, x), zero}, {zero, zero, one}},
+		[]*ristretto255.Scalar{Mul(statement.z, statement.z), c}
+}
+
+//NewMultisigWithdraw prepares a withdraw of amount from the account of key, whose ledger commitment
+//comm holds balance
+func NewMultisigWithdraw(key *ThresholdKey, comm *Commitment, balance uint64, trans [64]byte, amount uint64,
+	signers []uint32) (*MultisigCoordinator, *MultisigWithdrawRequest, error) {
+	if err := checkSigners(key, signers); err != nil {
+		return nil, nil, err
+	}
+	if amount > balance {
+		return nil, nil, errors.New("amount exceeds the balance")
+	}
+	prover, err := newCoordinatorProver()
+	if err != nil {
+		return nil, nil, err
+	}
+	basePoint := prover.G
+	b, err := InttoScalar(amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	bNew, err := InttoScalar(balance - amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := prover.RandScalar()
+	commWD := Commitment{
+		Cl: SumElements(new(ristretto255.Element).ScalarMult(b, basePoint), new(ristretto255.Element).ScalarMult(r, key.Pk)),
+		Cr: new(ristretto255.Element).ScalarMult(r, basePoint),
+	}
+	commNew := new(Commitment).Sub(comm, &commWD)
+	witness, err := prover.commitRangeProof(trans, bNew, commNew.Cr)
+	if err != nil {
+		return nil, nil, err
+	}
+	kr := prover.RandScalar()
+	request := &MultisigWithdrawRequest{
+		Trans:   trans,
+		Amount:  amount,
+		Comm:    comm,
+		R:       r,
+		Signers: signers,
+		CommWD:  commWD,
+		A:       witness.aCommit,
+		S:       witness.sCommit,
+		T1G:     new(ristretto255.Element).ScalarMult(witness.t1, basePoint),
+		T2G:     new(ristretto255.Element).ScalarMult(witness.t2, basePoint),
+		Ay:      new(ristretto255.Element).ScalarMult(kr, key.Pk),
+		Ag:      new(ristretto255.Element).ScalarMult(kr, basePoint),
+	}
+	statement := newWithdrawStatement(request)
+	finish := func(kg, kb []*ristretto255.Element, responses []*ristretto255.Scalar) Codec {
+		t1, t2 := statement.t1t2(kb)
+		trans, rangeProof, _ := prover.finishRangeProof(witness, t1, t2)
+		rangeProof.Taux = responses[0]
+		_, c := UpdateTranscript(trans, kb[2], request.Ay, request.Ag)
+		return &WithdrawProof{
+			rangeProof: rangeProof,
+			CommWD:     commWD,
+			ad:         kb[2],
+			ay:         request.Ay,
+			ag:         request.Ag,
+			ssk:        responses[1],
+			sr:         SumScalars(kr, Mul(c, r)),
+		}
+	}
+	return &MultisigCoordinator{
+		session: multisigSession{key: key, signers: signers, statement: statement},
+		finish:  finish,
+	}, request, nil
+}
+
+//NewMultisigWithdrawSigner checks the request for key and returns the signer of share
+func NewMultisigWithdrawSigner(key *ThresholdKey, share *KeyShare, request *MultisigWithdrawRequest) (*MultisigSigner, error) {
+	if request.Comm == nil || request.R == nil {
+		return nil, ErrMultisigMessage
+	}
+	basePoint := hdBasePoint()
+	b, err := InttoScalar(request.Amount)
+	if err != nil {
+		return nil, err
+	}
+	cl := SumElements(new(ristretto255.Element).ScalarMult(b, basePoint), new(ristretto255.Element).ScalarMult(request.R, key.Pk))
+	if request.CommWD.Cl.Equal(cl) != 1 || request.CommWD.Cr.Equal(new(ristretto255.Element).ScalarMult(request.R, basePoint)) != 1 {
+		return nil, errors.New("withdraw request does not encrypt the amount")
+	}
+	return newMultisigSigner(key, share, request.Signers, newWithdrawStatement(request))
+}
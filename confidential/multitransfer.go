@@ -0,0 +1,373 @@
+package confidential
+
+import (
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Multi-recipient transfers. One proof debits the sender and credits k recipients. Every amount b_j has
+//its own randomness r_j, CComm_j = (b_j*G + r_j*y, r_j*G) is taken from the sender and CPrimeComm_j, with
+//the same Cr, is credited to recipient j. A single sigma range proof aggregates b_1..b_k and the balance
+//left to the sender, padded with zeros to a power of two values, and the ledger applies all credits or none.
+
+//MaxRecipients bounds the recipients of one MultiTransferProof, the range proof then covers 8 values
+const MaxRecipients = 7
+
+var ErrTooManyRecipients = errors.New("too many recipients")
+
+type RecipientCiphertext struct {
+	Recipient  *ristretto255.Element
+	CComm      Commitment
+	CPrimeComm Commitment
+	ad         *ristretto255.Element //kr_j*G
+	ayPrime    *ristretto255.Element //kr_j*(y - Recipient)
+	sr         *ristretto255.Scalar
+}
+
+type MultiTransferProof struct {
+	Recipients      []RecipientCiphertext
+	sigmaRangeProof *SigmaRangeProof
+	ay, ab, at      *ristretto255.Element
+	ssk, sb, stau   *ristretto255.Scalar
+}
+
+func (proof *MultiTransferProof) Kind() ProofKind { return KindMultiTransferProof }
+
+//aggCount is the number of values in the range proof of k recipients
+func aggCount(k int) uint64 {
+	count := uint64(1)
+	for count < uint64(k)+1 {
+		count <<= 1
+	}
+	return count
+}
+
+//Debit returns the sum of the commitments taken from the sender
+func (proof *MultiTransferProof) Debit() *Commitment {
+	debit := zeroCommitment()
+	for i := range proof.Recipients {
+		debit = new(Commitment).Add(debit, &proof.Recipients[i].CComm)
+	}
+	return debit
+}
+
+//weighted returns sum(z^(j+2)*comm_j) over the recipient commitments and z^(k+2)*cNew, the combination
+//tHat binds the amounts and the new balance with
+func (proof *MultiTransferProof) weighted(z *ristretto255.Scalar, cNew *Commitment, part func(*Commitment) *ristretto255.Element) *ristretto255.Element {
+	k := len(proof.Recipients)
+	powersOfZ := PowersList(z, uint64(k)+3)
+	var scalars []*ristretto255.Scalar
+	var elements []*ristretto255.Element
+	for j := range proof.Recipients {
+		scalars = append(scalars, powersOfZ[j+2])
+		elements = append(elements, part(&proof.Recipients[j].CComm))
+	}
+	scalars = append(scalars, powersOfZ[k+2])
+	elements = append(elements, part(cNew))
+	return new(ristretto255.Element).VarTimeMultiScalarMult(scalars, elements)
+}
+
+func (proof *MultiTransferProof) challenge(trans [64]byte) *ristretto255.Scalar {
+	elements := []*ristretto255.Element{proof.ay, proof.ab, proof.at}
+	for _, rc := range proof.Recipients {
+		elements = append(elements, rc.Recipient, rc.CComm.Cl, rc.CComm.Cr, rc.CPrimeComm.Cl, rc.ad, rc.ayPrime)
+	}
+	_, c := UpdateTranscript(trans, elements...)
+	return c
+}
+
+//bind hashes the sender, its balance commitment and every recipient ciphertext into trans before the
+//range proof, the challenge z the ciphertexts are weighted with can then not be known when they are chosen
+func (proof *MultiTransferProof) bind(trans [64]byte, y *ristretto255.Element, cOld *Commitment) [64]byte {
+	elements := []*ristretto255.Element{y, cOld.Cl, cOld.Cr}
+	for _, rc := range proof.Recipients {
+		elements = append(elements, rc.Recipient, rc.CComm.Cl, rc.CComm.Cr, rc.CPrimeComm.Cl, rc.CPrimeComm.Cr)
+	}
+	trans, _ = UpdateTranscript(trans, elements...)
+	return trans
+}
+
+func clOf(comm *Commitment) *ristretto255.Element { return comm.Cl }
+
+func crOf(comm *Commitment) *ristretto255.Element { return comm.Cr }
+
+//GenMultiTransferProof sends amounts[j] to recipients[j]
+func (acc *Account) GenMultiTransferProof(trans [64]byte, recipients []*ristretto255.Element, amounts []uint64) (*MultiTransferProof, error) {
+	if acc.viewOnly {
+		return nil, ErrViewOnly
+	}
+	k := len(recipients)
+	if k == 0 || k != len(amounts) {
+		return nil, errors.New("every recipient needs one amount")
+	}
+	if k > MaxRecipients {
+		return nil, ErrTooManyRecipients
+	}
+	balance := ScalartoInt(acc.GetCommitmentBalance())
+	total := uint64(0)
+	for _, amount := range amounts {
+		if amount > balance-total {
+			return nil, errors.New("amounts exceed the balance")
+		}
+		total += amount
+	}
+
+	proof := &MultiTransferProof{}
+	var rs []*ristretto255.Scalar
+	values := make([]uint64, aggCount(k))
+	for j, recipient := range recipients {
+		if recipient.Equal(acc.Pk) == 1 {
+			return nil, errors.New("sender can not be a recipient")
+		}
+		b, err := InttoScalar(amounts[j])
+		if err != nil {
+			return nil, err
+		}
+		r, cComm := acc.Commit(b)
+		rs = append(rs, r)
+		values[j] = amounts[j]
+		proof.Recipients = append(proof.Recipients, RecipientCiphertext{
+			Recipient: recipient,
+			CComm:     cComm,
+			CPrimeComm: Commitment{
+				Cl: SumElements(acc.mult(b, acc.basePoint), acc.mult(r, recipient)),
+				Cr: cComm.Cr,
+			},
+		})
+	}
+	values[k] = balance - total
+
+	sigmaRangeProof, z, trans, err := acc.rangeProver.genAggSigmaRangeProof(proof.bind(trans, acc.Pk, acc.Comm), values)
+	if err != nil {
+		return nil, err
+	}
+	proof.sigmaRangeProof = sigmaRangeProof
+	acc.proveMultiTransfer(proof, trans, z, values, rs)
+	return proof, nil
+}
+
+//proveMultiTransfer completes the sigma protocol of proof for the range proof challenge z, rs are the
+//randomness of the recipient ciphertexts
+func (acc *Account) proveMultiTransfer(proof *MultiTransferProof, trans [64]byte, z *ristretto255.Scalar, values []uint64, rs []*ristretto255.Scalar) {
+	k := len(proof.Recipients)
+	cNew := new(Commitment).Sub(acc.Comm, proof.Debit())
+	ksk := acc.RandScalar()
+	kb := acc.RandScalar()
+	ktau := acc.RandScalar()
+	var krs []*ristretto255.Scalar
+	for j := range proof.Recipients {
+		rc := &proof.Recipients[j]
+		kr := acc.RandScalar()
+		krs = append(krs, kr)
+		rc.ad = acc.mult(kr, acc.basePoint)
+		rc.ayPrime = acc.mult(kr, new(ristretto255.Element).Add(acc.Pk, new(ristretto255.Element).Negate(rc.Recipient)))
+	}
+	proof.ay = acc.mult(ksk, acc.basePoint)
+	proof.ab = SumElements(acc.mult(kb, acc.basePoint), acc.mult(ksk, proof.weighted(z, cNew, crOf)))
+	proof.at = SumElements(acc.mult(new(ristretto255.Scalar).Negate(kb), acc.basePoint), acc.mult(ktau, acc.rangeProver.H))
+
+	c := proof.challenge(trans)
+	powersOfZ := PowersList(z, uint64(k)+3)
+	weightedValues := new(ristretto255.Scalar).Zero()
+	for j := 0; j <= k; j++ {
+		v, _ := InttoScalar(values[j])
+		weightedValues = SumScalars(weightedValues, Mul(powersOfZ[j+2], v))
+	}
+	proof.ssk = SumScalars(ksk, Mul(c, acc.sk))
+	proof.sb = SumScalars(kb, Mul(c, weightedValues))
+	proof.stau = SumScalars(ktau, Mul(c, proof.sigmaRangeProof.Taux))
+	for j := range proof.Recipients {
+		proof.Recipients[j].sr = SumScalars(krs[j], Mul(c, rs[j]))
+	}
+}
+
+//VerifyMultiTransferProof rejects senders with a registered spend key, use VerifyAuthorizedMultiTransferProof for them
+func (sc *SmartContract) VerifyMultiTransferProof(trans [64]byte, proof *MultiTransferProof, y *ristretto255.Element) bool {
+	return sc.getSpendKey(y) == nil && sc.verifyMultiTransferProof(trans, proof, y)
+}
+
+func (sc *SmartContract) VerifyAuthorizedMultiTransferProof(trans [64]byte, proof *MultiTransferProof, auth *SpendAuthorization, y *ristretto255.Element) bool {
+	return sc.verifySpendAuthorization(trans, y, proof, auth) && sc.verifyMultiTransferProof(trans, proof, y)
+}
+
+func (sc *SmartContract) verifyMultiTransferProof(trans [64]byte, proof *MultiTransferProof, y *ristretto255.Element) (result bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			result = false
+		}
+	}()
+
+	k := len(proof.Recipients)
+	if k == 0 || k > MaxRecipients {
+		return false
+	}
+	cOld := sc.GetCommitment(y)
+	if cOld == nil {
+		return false
+	}
+	count := aggCount(k)
+	trans, yRangeProof, z, x, res := sc.rangeProver.verifyAggSigmaRangeProof(proof.bind(trans, y, cOld), proof.sigmaRangeProof, count)
+	if !res {
+		return false
+	}
+	c := proof.challenge(trans)
+
+	sskG := new(ristretto255.Element).ScalarMultWnaf(proof.ssk, sc.BasePoint)
+	if sskG.Equal(new(ristretto255.Element).Add(proof.ay, new(ristretto255.Element).ScalarMultWnaf(c, y))) != 1 {
+		return false
+	}
+
+	for _, rc := range proof.Recipients {
+		if rc.Recipient.Equal(y) == 1 || rc.CPrimeComm.Cr.Equal(rc.CComm.Cr) != 1 {
+			return false
+		}
+		srG := new(ristretto255.Element).ScalarMultWnaf(rc.sr, sc.BasePoint)
+		if srG.Equal(new(ristretto255.Element).Add(rc.ad, new(ristretto255.Element).ScalarMultWnaf(c, rc.CComm.Cr))) != 1 {
+			return false
+		}
+		left := new(ristretto255.Element).ScalarMultWnaf(rc.sr, new(ristretto255.Element).Add(y, new(ristretto255.Element).Negate(rc.Recipient)))
+		tmp := new(ristretto255.Element).Add(rc.CComm.Cl, new(ristretto255.Element).Negate(rc.CPrimeComm.Cl))
+		if left.Equal(new(ristretto255.Element).Add(rc.ayPrime, new(ristretto255.Element).ScalarMultWnaf(c, tmp))) != 1 {
+			return false
+		}
+	}
+
+	cNew := new(Commitment).Sub(cOld, proof.Debit())
+	left := SumElements(new(ristretto255.Element).ScalarMultWnaf(proof.sb, sc.BasePoint),
+		new(ristretto255.Element).ScalarMultWnaf(proof.ssk, proof.weighted(z, cNew, crOf)))
+	right := new(ristretto255.Element).Add(proof.ab, new(ristretto255.Element).ScalarMultWnaf(c, proof.weighted(z, cNew, clOf)))
+	if left.Equal(right) != 1 {
+		return false
+	}
+
+	delta := sc.rangeProver.GetAggDelta(yRangeProof, z, count)
+	t := new(ristretto255.Scalar).Add(proof.sigmaRangeProof.THat, new(ristretto255.Scalar).Negate(delta))
+	tmpScalar := SumScalars(Mul(t, c), new(ristretto255.Scalar).Negate(proof.sb))
+	left = SumElements(new(ristretto255.Element).ScalarMultWnaf(tmpScalar, sc.BasePoint),
+		new(ristretto255.Element).ScalarMultWnaf(proof.stau, sc.rangeProver.H))
+	xx := new(ristretto255.Scalar).Multiply(x, x)
+	T12 := SumElements(new(ristretto255.Element).ScalarMultWnaf(x, proof.sigmaRangeProof.T1),
+		new(ristretto255.Element).ScalarMultWnaf(xx, proof.sigmaRangeProof.T2))
+	right = SumElements(proof.at, new(ristretto255.Element).ScalarMultWnaf(c, T12))
+	return left.Equal(right) == 1
+}
+
+//ApplyMultiTransfer verifies a multi-recipient transfer, debits the available balance of y and credits
+//every recipient's pending balance. Nothing is applied unless every recipient is registered and the proof holds.
+func (sc *SmartContract) ApplyMultiTransfer(trans [64]byte, proof *MultiTransferProof, auth *SpendAuthorization, nullifier *Nullifier, y *ristretto255.Element) bool {
+	sc.Mu.Lock()
+	defer sc.Mu.Unlock()
+	if proof == nil || sc.rollOver(y) != nil || !sc.VerifyNullifier(trans, y, proof, nullifier) {
+		return false
+	}
+	for _, rc := range proof.Recipients {
+		if _, ok := sc.CommitmentMap[pkKey(rc.Recipient)]; !ok {
+			return false
+		}
+	}
+	if sc.getSpendKey(y) != nil {
+		if !sc.VerifyAuthorizedMultiTransferProof(trans, proof, auth, y) {
+			return false
+		}
+	} else if !sc.verifyMultiTransferProof(trans, proof, y) {
+		return false
+	}
+	sc.NullifierMap[pkKey(nullifier.U)] = sc.Epoch
+	key := pkKey(y)
+	sc.CommitmentMap[key] = new(Commitment).Sub(sc.CommitmentMap[key], proof.Debit())
+	for i := range proof.Recipients {
+		if err := sc.credit(proof.Recipients[i].Recipient, &proof.Recipients[i].CPrimeComm); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+//DecryptMultiTransfer returns the amount of a multi-recipient transfer to this account
+func (acc *Account) DecryptMultiTransfer(proof *MultiTransferProof) (uint64, error) {
+	for _, rc := range proof.Recipients {
+		if rc.Recipient.Equal(acc.Pk) == 1 {
+			return acc.decryptAmount(rc.CPrimeComm.Cl, rc.CPrimeComm.Cr)
+		}
+	}
+	return 0, errors.New("account is not a recipient")
+}
+
+const recipientCiphertextSize = 6*32 + 32
+
+func (rc *RecipientCiphertext) Serialization(sink *ZeroCopySink) {
+	sink.WriteFixedElement(rc.Recipient)
+	sink.WriteFixedElement(rc.CComm.Cl)
+	sink.WriteFixedElement(rc.CComm.Cr)
+	sink.WriteFixedElement(rc.CPrimeComm.Cl)
+	sink.WriteFixedElement(rc.ad)
+	sink.WriteFixedElement(rc.ayPrime)
+	sink.WriteFixedScalar(rc.sr)
+}
+
+func (rc *RecipientCiphertext) Deserialization(source *ZeroCopySource) error {
+	if err := nextFixedElements(source, &rc.Recipient, &rc.CComm.Cl, &rc.CComm.Cr, &rc.CPrimeComm.Cl, &rc.ad, &rc.ayPrime); err != nil {
+		return err
+	}
+	rc.CPrimeComm.Cr = DeepCopyElement(rc.CComm.Cr)
+	return nextFixedScalars(source, &rc.sr)
+}
+
+//the multi-recipient proof has a single fixed size layout, Serialize and SerializeCompact agree
+func (proof *MultiTransferProof) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint8(uint8(len(proof.Recipients)))
+	for i := range proof.Recipients {
+		proof.Recipients[i].Serialization(sink)
+	}
+	sink.WriteFixedElement(proof.ay)
+	sink.WriteFixedElement(proof.ab)
+	sink.WriteFixedElement(proof.at)
+	sink.WriteFixedScalar(proof.ssk)
+	sink.WriteFixedScalar(proof.sb)
+	sink.WriteFixedScalar(proof.stau)
+	proof.sigmaRangeProof.serializeCompact(sink)
+}
+
+func (proof *MultiTransferProof) Deserialization(source *ZeroCopySource) error {
+	k, eof := source.NextUint8()
+	if eof {
+		return ErrProofLength
+	}
+	if k == 0 || k > MaxRecipients {
+		return ErrTooManyRecipients
+	}
+	size := uint64(k)*recipientCiphertextSize + 6*32 + 7*32 + uint64(compactInnerProductSize(RANGEBITS*aggCount(int(k))))
+	if source.Len() != size {
+		return ErrProofLength
+	}
+	proof.Recipients = make([]RecipientCiphertext, k)
+	for i := range proof.Recipients {
+		if err := proof.Recipients[i].Deserialization(source); err != nil {
+			return err
+		}
+	}
+	if err := nextFixedElements(source, &proof.ay, &proof.ab, &proof.at); err != nil {
+		return err
+	}
+	if err := nextFixedScalars(source, &proof.ssk, &proof.sb, &proof.stau); err != nil {
+		return err
+	}
+	proof.sigmaRangeProof = new(SigmaRangeProof)
+	return proof.sigmaRangeProof.deserializeCompactN(source, RANGEBITS*aggCount(int(k)))
+}
+
+func (proof *MultiTransferProof) Serialize() []byte {
+	return serialize(proof)
+}
+
+func (proof *MultiTransferProof) Deserialize(b []byte) error {
+	return deserialize(proof, b)
+}
+
+func (proof *MultiTransferProof) SerializeCompact() []byte {
+	return proof.Serialize()
+}
+
+func (proof *MultiTransferProof) DeserializeCompact(b []byte) error {
+	return proof.Deserialize(b)
+}
@@ -0,0 +1,178 @@
+package confidential
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Epoch nullifiers. Every spend applied to the ledger carries U = sk*G_epoch, where G_epoch is hashed from
+//the epoch number, with a proof that log_G(y) = log_G_epoch(U) bound to the spend proof and transcript.
+//The ledger records U and rejects it a second time, so a proof can not be replayed and, as in Zether,
+//an account spends at most once per epoch. U of different epochs are unlinkable without sk.
+
+var ErrNullifierUsed = errors.New("nullifier already used")
+
+var nullifierDomain = []byte("xv-crypto nullifier")
+
+type Nullifier struct {
+	U    *ristretto255.Element
+	c, s *ristretto255.Scalar
+}
+
+//EpochBase returns the generator G_epoch, nobody knows its discrete log to G
+func EpochBase(epoch uint64) *ristretto255.Element {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], epoch)
+	h := sha512.Sum512(append(append([]byte{}, nullifierDomain...), buf[:]...))
+	return new(ristretto255.Element).FromUniformBytes(h[:])
+}
+
+func nullifierChallenge(gEpoch, y, u, a1, a2 *ristretto255.Element, msg []byte) *ristretto255.Scalar {
+	h := sha512.New()
+	h.Write(nullifierDomain)
+	for _, e := range []*ristretto255.Element{gEpoch, y, u, a1, a2} {
+		h.Write(e.Encode(nil))
+	}
+	h.Write(msg)
+	return new(ristretto255.Scalar).FromUniformBytes(h.Sum(nil))
+}
+
+func (nullifier *Nullifier) Serialization(sink *ZeroCopySink) {
+	sink.WriteFixedElement(nullifier.U)
+	sink.WriteFixedScalar(nullifier.c)
+	sink.WriteFixedScalar(nullifier.s)
+}
+
+func (nullifier *Nullifier) Deserialization(source *ZeroCopySource) error {
+	var err error
+	if nullifier.U, err = source.NextFixedElement(); err != nil {
+		return err
+	}
+	return nextFixedScalars(source, &nullifier.c, &nullifier.s)
+}
+
+func (nullifier *Nullifier) Serialize() []byte {
+	return serialize(nullifier)
+}
+
+func (nullifier *Nullifier) Deserialize(b []byte) error {
+	return deserialize(nullifier, b)
+}
+
+//GenNullifier returns the nullifier of the account for epoch, bound to a transfer or withdraw proof
+//generated with transcript trans
+func (acc *Account) GenNullifier(trans [64]byte, epoch uint64, proof Proof) *Nullifier {
+	gEpoch := EpochBase(epoch)
+	u := acc.mult(acc.sk, gEpoch)
+	k := acc.RandScalar()
+	c := nullifierChallenge(gEpoch, acc.Pk, u, acc.mult(k, acc.basePoint), acc.mult(k, gEpoch), spendMessage(trans, proof))
+	return &Nullifier{
+		U: u,
+		c: c,
+		s: SumScalars(k, Mul(c, acc.sk)),
+	}
+}
+
+func (sc *SmartContract) nullifierUsed(nullifier *Nullifier) bool {
+	_, ok := sc.NullifierMap[pkKey(nullifier.U)]
+	return ok
+}
+
+//VerifyNullifier checks that nullifier belongs to y in the current epoch, is bound to proof and was not used
+func (sc *SmartContract) VerifyNullifier(trans [64]byte, y *ristretto255.Element, proof Proof, nullifier *Nullifier) (result bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			result = false
+		}
+	}()
+
+	if nullifier == nil || sc.nullifierUsed(nullifier) {
+		return false
+	}
+	gEpoch := EpochBase(sc.Epoch)
+	negC := new(ristretto255.Scalar).Negate(nullifier.c)
+	a1 := new(ristretto255.Element).VarTimeMultiScalarMult([]*ristretto255.Scalar{nullifier.s, negC}, []*ristretto255.Element{sc.BasePoint, y})
+	a2 := new(ristretto255.Element).VarTimeMultiScalarMult([]*ristretto255.Scalar{nullifier.s, negC}, []*ristretto255.Element{gEpoch, nullifier.U})
+	return nullifierChallenge(gEpoch, y, nullifier.U, a1, a2, spendMessage(trans, proof)).Equal(nullifier.c) == 1
+}
+
+//MultisigNullifierRequest asks the signers of a multisig account for the nullifier of Proof. Shares holds
+//the decryption shares of G_epoch of every signer, their combination is U.
+type MultisigNullifierRequest struct {
+	Trans   [64]byte
+	Epoch   uint64
+	Proof   Proof
+	Signers []uint32
+	Shares  []*DecryptionShare
+}
+
+//EpochShare returns the share ski*G_epoch of the nullifier of epoch
+func (share *KeyShare) EpochShare(epoch uint64) *DecryptionShare {
+	return share.DecryptionShare(&Commitment{Cr: EpochBase(epoch)})
+}
+
+type nullifierStatement struct {
+	gEpoch, pk, u *ristretto255.Element
+	msg           []byte
+}
+
+func newNullifierStatement(key *ThresholdKey, request *MultisigNullifierRequest) (*nullifierStatement, error) {
+	gEpoch := EpochBase(request.Epoch)
+	u, err := key.combineShares(&Commitment{Cr: gEpoch}, request.Shares)
+	if err != nil {
+		return nil, err
+	}
+	return &nullifierStatement{
+		gEpoch: gEpoch,
+		pk:     key.Pk,
+		u:      u,
+		msg:    spendMessage(request.Trans, request.Proof),
+	}, nil
+}
+
+func (statement *nullifierStatement) bases() []*ristretto255.Element {
+	return []*ristretto255.Element{statement.gEpoch}
+}
+
+func (statement *nullifierStatement) challenges(kg, kb []*ristretto255.Element) ([][]*ristretto255.Scalar, []*ristretto255.Scalar) {
+	one, _ := InttoScalar(uint64(1))
+	c := nullifierChallenge(statement.gEpoch, statement.pk, statement.u, kg[0], kb[0], statement.msg)
+	return [][]*ristretto255.Scalar{{one}}, []*ristretto255.Scalar{c}
+}
+
+//NewMultisigNullifier prepares the nullifier of a proof of a multisig account, Finalize returns a *Nullifier
+func NewMultisigNullifier(key *ThresholdKey, request *MultisigNullifierRequest) (*MultisigCoordinator, error) {
+	if err := checkSigners(key, request.Signers); err != nil {
+		return nil, err
+	}
+	statement, err := newNullifierStatement(key, request)
+	if err != nil {
+		return nil, err
+	}
+	finish := func(kg, kb []*ristretto255.Element, responses []*ristretto255.Scalar) Codec {
+		_, f := statement.challenges(kg, kb)
+		return &Nullifier{
+			U: statement.u,
+			c: f[0],
+			s: responses[0],
+		}
+	}
+	return &MultisigCoordinator{
+		session: multisigSession{key: key, signers: request.Signers, statement: statement},
+		finish:  finish,
+	}, nil
+}
+
+//NewMultisigNullifierSigner returns the signer of share for the nullifier request
+func NewMultisigNullifierSigner(key *ThresholdKey, share *KeyShare, request *MultisigNullifierRequest) (*MultisigSigner, error) {
+	if request.Proof == nil {
+		return nil, ErrMultisigMessage
+	}
+	statement, err := newNullifierStatement(key, request)
+	if err != nil {
+		return nil, err
+	}
+	return newMultisigSigner(key, share, request.Signers, statement)
+}
@@ -0,0 +1,1280 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: proof.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Commitment struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cl []byte `protobuf:"bytes,1,opt,name=cl,proto3" json:"cl,omitempty"`
+	Cr []byte `protobuf:"bytes,2,opt,name=cr,proto3" json:"cr,omitempty"`
+}
+
+func (x *Commitment) Reset() {
+	*x = Commitment{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Commitment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Commitment) ProtoMessage() {}
+
+func (x *Commitment) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Commitment.ProtoReflect.Descriptor instead.
+func (*Commitment) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Commitment) GetCl() []byte {
+	if x != nil {
+		return x.Cl
+	}
+	return nil
+}
+
+func (x *Commitment) GetCr() []byte {
+	if x != nil {
+		return x.Cr
+	}
+	return nil
+}
+
+type InnerProductProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	A  []byte   `protobuf:"bytes,1,opt,name=a,proto3" json:"a,omitempty"`
+	B  []byte   `protobuf:"bytes,2,opt,name=b,proto3" json:"b,omitempty"`
+	Ls [][]byte `protobuf:"bytes,3,rep,name=ls,proto3" json:"ls,omitempty"`
+	Rs [][]byte `protobuf:"bytes,4,rep,name=rs,proto3" json:"rs,omitempty"`
+}
+
+func (x *InnerProductProof) Reset() {
+	*x = InnerProductProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InnerProductProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InnerProductProof) ProtoMessage() {}
+
+func (x *InnerProductProof) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InnerProductProof.ProtoReflect.Descriptor instead.
+func (*InnerProductProof) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *InnerProductProof) GetA() []byte {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *InnerProductProof) GetB() []byte {
+	if x != nil {
+		return x.B
+	}
+	return nil
+}
+
+func (x *InnerProductProof) GetLs() [][]byte {
+	if x != nil {
+		return x.Ls
+	}
+	return nil
+}
+
+func (x *InnerProductProof) GetRs() [][]byte {
+	if x != nil {
+		return x.Rs
+	}
+	return nil
+}
+
+type RangeProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	G          []byte             `protobuf:"bytes,1,opt,name=g,proto3" json:"g,omitempty"`
+	H          []byte             `protobuf:"bytes,2,opt,name=h,proto3" json:"h,omitempty"`
+	Taux       []byte             `protobuf:"bytes,3,opt,name=taux,proto3" json:"taux,omitempty"`
+	Mu         []byte             `protobuf:"bytes,4,opt,name=mu,proto3" json:"mu,omitempty"`
+	THat       []byte             `protobuf:"bytes,5,opt,name=t_hat,json=tHat,proto3" json:"t_hat,omitempty"`
+	T1         []byte             `protobuf:"bytes,6,opt,name=t1,proto3" json:"t1,omitempty"`
+	T2         []byte             `protobuf:"bytes,7,opt,name=t2,proto3" json:"t2,omitempty"`
+	A          []byte             `protobuf:"bytes,8,opt,name=a,proto3" json:"a,omitempty"`
+	S          []byte             `protobuf:"bytes,9,opt,name=s,proto3" json:"s,omitempty"`
+	InnerProof *InnerProductProof `protobuf:"bytes,10,opt,name=inner_proof,json=innerProof,proto3" json:"inner_proof,omitempty"`
+}
+
+func (x *RangeProof) Reset() {
+	*x = RangeProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RangeProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeProof) ProtoMessage() {}
+
+func (x *RangeProof) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangeProof.ProtoReflect.Descriptor instead.
+func (*RangeProof) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RangeProof) GetG() []byte {
+	if x != nil {
+		return x.G
+	}
+	return nil
+}
+
+func (x *RangeProof) GetH() []byte {
+	if x != nil {
+		return x.H
+	}
+	return nil
+}
+
+func (x *RangeProof) GetTaux() []byte {
+	if x != nil {
+		return x.Taux
+	}
+	return nil
+}
+
+func (x *RangeProof) GetMu() []byte {
+	if x != nil {
+		return x.Mu
+	}
+	return nil
+}
+
+func (x *RangeProof) GetTHat() []byte {
+	if x != nil {
+		return x.THat
+	}
+	return nil
+}
+
+func (x *RangeProof) GetT1() []byte {
+	if x != nil {
+		return x.T1
+	}
+	return nil
+}
+
+func (x *RangeProof) GetT2() []byte {
+	if x != nil {
+		return x.T2
+	}
+	return nil
+}
+
+func (x *RangeProof) GetA() []byte {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *RangeProof) GetS() []byte {
+	if x != nil {
+		return x.S
+	}
+	return nil
+}
+
+func (x *RangeProof) GetInnerProof() *InnerProductProof {
+	if x != nil {
+		return x.InnerProof
+	}
+	return nil
+}
+
+type SigmaRangeProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Taux       []byte             `protobuf:"bytes,1,opt,name=taux,proto3" json:"taux,omitempty"`
+	Mu         []byte             `protobuf:"bytes,2,opt,name=mu,proto3" json:"mu,omitempty"`
+	THat       []byte             `protobuf:"bytes,3,opt,name=t_hat,json=tHat,proto3" json:"t_hat,omitempty"`
+	T1         []byte             `protobuf:"bytes,4,opt,name=t1,proto3" json:"t1,omitempty"`
+	T2         []byte             `protobuf:"bytes,5,opt,name=t2,proto3" json:"t2,omitempty"`
+	A          []byte             `protobuf:"bytes,6,opt,name=a,proto3" json:"a,omitempty"`
+	S          []byte             `protobuf:"bytes,7,opt,name=s,proto3" json:"s,omitempty"`
+	InnerProof *InnerProductProof `protobuf:"bytes,8,opt,name=inner_proof,json=innerProof,proto3" json:"inner_proof,omitempty"`
+}
+
+func (x *SigmaRangeProof) Reset() {
+	*x = SigmaRangeProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SigmaRangeProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SigmaRangeProof) ProtoMessage() {}
+
+func (x *SigmaRangeProof) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SigmaRangeProof.ProtoReflect.Descriptor instead.
+func (*SigmaRangeProof) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SigmaRangeProof) GetTaux() []byte {
+	if x != nil {
+		return x.Taux
+	}
+	return nil
+}
+
+func (x *SigmaRangeProof) GetMu() []byte {
+	if x != nil {
+		return x.Mu
+	}
+	return nil
+}
+
+func (x *SigmaRangeProof) GetTHat() []byte {
+	if x != nil {
+		return x.THat
+	}
+	return nil
+}
+
+func (x *SigmaRangeProof) GetT1() []byte {
+	if x != nil {
+		return x.T1
+	}
+	return nil
+}
+
+func (x *SigmaRangeProof) GetT2() []byte {
+	if x != nil {
+		return x.T2
+	}
+	return nil
+}
+
+func (x *SigmaRangeProof) GetA() []byte {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *SigmaRangeProof) GetS() []byte {
+	if x != nil {
+		return x.S
+	}
+	return nil
+}
+
+func (x *SigmaRangeProof) GetInnerProof() *InnerProductProof {
+	if x != nil {
+		return x.InnerProof
+	}
+	return nil
+}
+
+type CommitmentProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ay  []byte `protobuf:"bytes,1,opt,name=ay,proto3" json:"ay,omitempty"`
+	Acr []byte `protobuf:"bytes,2,opt,name=acr,proto3" json:"acr,omitempty"`
+	Ssk []byte `protobuf:"bytes,3,opt,name=ssk,proto3" json:"ssk,omitempty"`
+	B   []byte `protobuf:"bytes,4,opt,name=b,proto3" json:"b,omitempty"`
+}
+
+func (x *CommitmentProof) Reset() {
+	*x = CommitmentProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CommitmentProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitmentProof) ProtoMessage() {}
+
+func (x *CommitmentProof) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitmentProof.ProtoReflect.Descriptor instead.
+func (*CommitmentProof) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CommitmentProof) GetAy() []byte {
+	if x != nil {
+		return x.Ay
+	}
+	return nil
+}
+
+func (x *CommitmentProof) GetAcr() []byte {
+	if x != nil {
+		return x.Acr
+	}
+	return nil
+}
+
+func (x *CommitmentProof) GetSsk() []byte {
+	if x != nil {
+		return x.Ssk
+	}
+	return nil
+}
+
+func (x *CommitmentProof) GetB() []byte {
+	if x != nil {
+		return x.B
+	}
+	return nil
+}
+
+type WithdrawProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RangeProof *RangeProof `protobuf:"bytes,1,opt,name=range_proof,json=rangeProof,proto3" json:"range_proof,omitempty"`
+	CommWd     *Commitment `protobuf:"bytes,2,opt,name=comm_wd,json=commWd,proto3" json:"comm_wd,omitempty"`
+	Ad         []byte      `protobuf:"bytes,3,opt,name=ad,proto3" json:"ad,omitempty"`
+	Ay         []byte      `protobuf:"bytes,4,opt,name=ay,proto3" json:"ay,omitempty"`
+	Ag         []byte      `protobuf:"bytes,5,opt,name=ag,proto3" json:"ag,omitempty"`
+	Ssk        []byte      `protobuf:"bytes,6,opt,name=ssk,proto3" json:"ssk,omitempty"`
+	Sr         []byte      `protobuf:"bytes,7,opt,name=sr,proto3" json:"sr,omitempty"`
+}
+
+func (x *WithdrawProof) Reset() {
+	*x = WithdrawProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WithdrawProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawProof) ProtoMessage() {}
+
+func (x *WithdrawProof) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawProof.ProtoReflect.Descriptor instead.
+func (*WithdrawProof) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WithdrawProof) GetRangeProof() *RangeProof {
+	if x != nil {
+		return x.RangeProof
+	}
+	return nil
+}
+
+func (x *WithdrawProof) GetCommWd() *Commitment {
+	if x != nil {
+		return x.CommWd
+	}
+	return nil
+}
+
+func (x *WithdrawProof) GetAd() []byte {
+	if x != nil {
+		return x.Ad
+	}
+	return nil
+}
+
+func (x *WithdrawProof) GetAy() []byte {
+	if x != nil {
+		return x.Ay
+	}
+	return nil
+}
+
+func (x *WithdrawProof) GetAg() []byte {
+	if x != nil {
+		return x.Ag
+	}
+	return nil
+}
+
+func (x *WithdrawProof) GetSsk() []byte {
+	if x != nil {
+		return x.Ssk
+	}
+	return nil
+}
+
+func (x *WithdrawProof) GetSr() []byte {
+	if x != nil {
+		return x.Sr
+	}
+	return nil
+}
+
+type TransferProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SigmaRangeProof *SigmaRangeProof     `protobuf:"bytes,1,opt,name=sigma_range_proof,json=sigmaRangeProof,proto3" json:"sigma_range_proof,omitempty"`
+	Ay              []byte               `protobuf:"bytes,2,opt,name=ay,proto3" json:"ay,omitempty"`
+	Ad              []byte               `protobuf:"bytes,3,opt,name=ad,proto3" json:"ad,omitempty"`
+	Ab              []byte               `protobuf:"bytes,4,opt,name=ab,proto3" json:"ab,omitempty"`
+	AyPrime         []byte               `protobuf:"bytes,5,opt,name=ay_prime,json=ayPrime,proto3" json:"ay_prime,omitempty"`
+	At              []byte               `protobuf:"bytes,6,opt,name=at,proto3" json:"at,omitempty"`
+	Ssk             []byte               `protobuf:"bytes,7,opt,name=ssk,proto3" json:"ssk,omitempty"`
+	Sr              []byte               `protobuf:"bytes,8,opt,name=sr,proto3" json:"sr,omitempty"`
+	Sb              []byte               `protobuf:"bytes,9,opt,name=sb,proto3" json:"sb,omitempty"`
+	Stau            []byte               `protobuf:"bytes,10,opt,name=stau,proto3" json:"stau,omitempty"`
+	CComm           *Commitment          `protobuf:"bytes,11,opt,name=c_comm,json=cComm,proto3" json:"c_comm,omitempty"`
+	CPrimeComm      *Commitment          `protobuf:"bytes,12,opt,name=c_prime_comm,json=cPrimeComm,proto3" json:"c_prime_comm,omitempty"`
+	Auditors        []*AuditorCiphertext `protobuf:"bytes,13,rep,name=auditors,proto3" json:"auditors,omitempty"`
+}
+
+func (x *TransferProof) Reset() {
+	*x = TransferProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TransferProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferProof) ProtoMessage() {}
+
+func (x *TransferProof) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferProof.ProtoReflect.Descriptor instead.
+func (*TransferProof) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TransferProof) GetSigmaRangeProof() *SigmaRangeProof {
+	if x != nil {
+		return x.SigmaRangeProof
+	}
+	return nil
+}
+
+func (x *TransferProof) GetAy() []byte {
+	if x != nil {
+		return x.Ay
+	}
+	return nil
+}
+
+func (x *TransferProof) GetAd() []byte {
+	if x != nil {
+		return x.Ad
+	}
+	return nil
+}
+
+func (x *TransferProof) GetAb() []byte {
+	if x != nil {
+		return x.Ab
+	}
+	return nil
+}
+
+func (x *TransferProof) GetAyPrime() []byte {
+	if x != nil {
+		return x.AyPrime
+	}
+	return nil
+}
+
+func (x *TransferProof) GetAt() []byte {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *TransferProof) GetSsk() []byte {
+	if x != nil {
+		return x.Ssk
+	}
+	return nil
+}
+
+func (x *TransferProof) GetSr() []byte {
+	if x != nil {
+		return x.Sr
+	}
+	return nil
+}
+
+func (x *TransferProof) GetSb() []byte {
+	if x != nil {
+		return x.Sb
+	}
+	return nil
+}
+
+func (x *TransferProof) GetStau() []byte {
+	if x != nil {
+		return x.Stau
+	}
+	return nil
+}
+
+func (x *TransferProof) GetCComm() *Commitment {
+	if x != nil {
+		return x.CComm
+	}
+	return nil
+}
+
+func (x *TransferProof) GetCPrimeComm() *Commitment {
+	if x != nil {
+		return x.CPrimeComm
+	}
+	return nil
+}
+
+func (x *TransferProof) GetAuditors() []*AuditorCiphertext {
+	if x != nil {
+		return x.Auditors
+	}
+	return nil
+}
+
+type FeeTransferProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Fee           uint64         `protobuf:"varint,1,opt,name=fee,proto3" json:"fee,omitempty"`
+	TransferProof *TransferProof `protobuf:"bytes,2,opt,name=transfer_proof,json=transferProof,proto3" json:"transfer_proof,omitempty"`
+}
+
+func (x *FeeTransferProof) Reset() {
+	*x = FeeTransferProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FeeTransferProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeeTransferProof) ProtoMessage() {}
+
+func (x *FeeTransferProof) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeeTransferProof.ProtoReflect.Descriptor instead.
+func (*FeeTransferProof) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *FeeTransferProof) GetFee() uint64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *FeeTransferProof) GetTransferProof() *TransferProof {
+	if x != nil {
+		return x.TransferProof
+	}
+	return nil
+}
+
+type AuditorCiphertext struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Auditor []byte `protobuf:"bytes,1,opt,name=auditor,proto3" json:"auditor,omitempty"`
+	Cl      []byte `protobuf:"bytes,2,opt,name=cl,proto3" json:"cl,omitempty"`
+	Ae      []byte `protobuf:"bytes,3,opt,name=ae,proto3" json:"ae,omitempty"`
+}
+
+func (x *AuditorCiphertext) Reset() {
+	*x = AuditorCiphertext{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AuditorCiphertext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditorCiphertext) ProtoMessage() {}
+
+func (x *AuditorCiphertext) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditorCiphertext.ProtoReflect.Descriptor instead.
+func (*AuditorCiphertext) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AuditorCiphertext) GetAuditor() []byte {
+	if x != nil {
+		return x.Auditor
+	}
+	return nil
+}
+
+func (x *AuditorCiphertext) GetCl() []byte {
+	if x != nil {
+		return x.Cl
+	}
+	return nil
+}
+
+func (x *AuditorCiphertext) GetAe() []byte {
+	if x != nil {
+		return x.Ae
+	}
+	return nil
+}
+
+type PublicKey struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pk []byte `protobuf:"bytes,1,opt,name=pk,proto3" json:"pk,omitempty"`
+}
+
+func (x *PublicKey) Reset() {
+	*x = PublicKey{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PublicKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublicKey) ProtoMessage() {}
+
+func (x *PublicKey) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublicKey.ProtoReflect.Descriptor instead.
+func (*PublicKey) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PublicKey) GetPk() []byte {
+	if x != nil {
+		return x.Pk
+	}
+	return nil
+}
+
+type StealthAddress struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ViewPk  []byte `protobuf:"bytes,1,opt,name=view_pk,json=viewPk,proto3" json:"view_pk,omitempty"`
+	SpendPk []byte `protobuf:"bytes,2,opt,name=spend_pk,json=spendPk,proto3" json:"spend_pk,omitempty"`
+}
+
+func (x *StealthAddress) Reset() {
+	*x = StealthAddress{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StealthAddress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StealthAddress) ProtoMessage() {}
+
+func (x *StealthAddress) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StealthAddress.ProtoReflect.Descriptor instead.
+func (*StealthAddress) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StealthAddress) GetViewPk() []byte {
+	if x != nil {
+		return x.ViewPk
+	}
+	return nil
+}
+
+func (x *StealthAddress) GetSpendPk() []byte {
+	if x != nil {
+		return x.SpendPk
+	}
+	return nil
+}
+
+type StealthOutput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	R         []byte `protobuf:"bytes,1,opt,name=r,proto3" json:"r,omitempty"`
+	OneTimePk []byte `protobuf:"bytes,2,opt,name=one_time_pk,json=oneTimePk,proto3" json:"one_time_pk,omitempty"`
+}
+
+func (x *StealthOutput) Reset() {
+	*x = StealthOutput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proof_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StealthOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StealthOutput) ProtoMessage() {}
+
+func (x *StealthOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_proof_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StealthOutput.ProtoReflect.Descriptor instead.
+func (*StealthOutput) Descriptor() ([]byte, []int) {
+	return file_proof_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StealthOutput) GetR() []byte {
+	if x != nil {
+		return x.R
+	}
+	return nil
+}
+
+func (x *StealthOutput) GetOneTimePk() []byte {
+	if x != nil {
+		return x.OneTimePk
+	}
+	return nil
+}
+
+var File_proof_proto protoreflect.FileDescriptor
+
+var file_proof_proto_rawDesc = []byte{
+	0x0a, 0x0b, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0c, 0x63,
+	0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x22, 0x2c, 0x0a, 0x0a, 0x43,
+	0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x63, 0x6c, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x63, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x63, 0x72, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x63, 0x72, 0x22, 0x4f, 0x0a, 0x11, 0x49, 0x6e, 0x6e,
+	0x65, 0x72, 0x50, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x0c,
+	0x0a, 0x01, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x61, 0x12, 0x0c, 0x0a, 0x01,
+	0x62, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x62, 0x12, 0x0e, 0x0a, 0x02, 0x6c, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x02, 0x6c, 0x73, 0x12, 0x0e, 0x0a, 0x02, 0x72, 0x73,
+	0x18, 0x04, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x02, 0x72, 0x73, 0x22, 0xdf, 0x01, 0x0a, 0x0a, 0x52,
+	0x61, 0x6e, 0x67, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x0c, 0x0a, 0x01, 0x67, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x67, 0x12, 0x0c, 0x0a, 0x01, 0x68, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x01, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x61, 0x75, 0x78, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x04, 0x74, 0x61, 0x75, 0x78, 0x12, 0x0e, 0x0a, 0x02, 0x6d, 0x75, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x6d, 0x75, 0x12, 0x13, 0x0a, 0x05, 0x74, 0x5f, 0x68,
+	0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x74, 0x48, 0x61, 0x74, 0x12, 0x0e,
+	0x0a, 0x02, 0x74, 0x31, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x74, 0x31, 0x12, 0x0e,
+	0x0a, 0x02, 0x74, 0x32, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x74, 0x32, 0x12, 0x0c,
+	0x0a, 0x01, 0x61, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x61, 0x12, 0x0c, 0x0a, 0x01,
+	0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x73, 0x12, 0x40, 0x0a, 0x0b, 0x69, 0x6e,
+	0x6e, 0x65, 0x72, 0x5f, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1f, 0x2e, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x2e, 0x49,
+	0x6e, 0x6e, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66,
+	0x52, 0x0a, 0x69, 0x6e, 0x6e, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x22, 0xc8, 0x01, 0x0a,
+	0x0f, 0x53, 0x69, 0x67, 0x6d, 0x61, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66,
+	0x12, 0x12, 0x0a, 0x04, 0x74, 0x61, 0x75, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04,
+	0x74, 0x61, 0x75, 0x78, 0x12, 0x0e, 0x0a, 0x02, 0x6d, 0x75, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x02, 0x6d, 0x75, 0x12, 0x13, 0x0a, 0x05, 0x74, 0x5f, 0x68, 0x61, 0x74, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x04, 0x74, 0x48, 0x61, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x31, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x74, 0x31, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x32, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x74, 0x32, 0x12, 0x0c, 0x0a, 0x01, 0x61, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x61, 0x12, 0x0c, 0x0a, 0x01, 0x73, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x01, 0x73, 0x12, 0x40, 0x0a, 0x0b, 0x69, 0x6e, 0x6e, 0x65, 0x72, 0x5f, 0x70,
+	0x72, 0x6f, 0x6f, 0x66, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x2e, 0x49, 0x6e, 0x6e, 0x65, 0x72, 0x50,
+	0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x52, 0x0a, 0x69, 0x6e, 0x6e,
+	0x65, 0x72, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x22, 0x53, 0x0a, 0x0f, 0x43, 0x6f, 0x6d, 0x6d, 0x69,
+	0x74, 0x6d, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x0e, 0x0a, 0x02, 0x61, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x61, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x63,
+	0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x61, 0x63, 0x72, 0x12, 0x10, 0x0a, 0x03,
+	0x73, 0x73, 0x6b, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x73, 0x73, 0x6b, 0x12, 0x0c,
+	0x0a, 0x01, 0x62, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x62, 0x22, 0xcf, 0x01, 0x0a,
+	0x0d, 0x57, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x39,
+	0x0a, 0x0b, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x61, 0x6c, 0x2e, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x52, 0x0a, 0x72,
+	0x61, 0x6e, 0x67, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x31, 0x0a, 0x07, 0x63, 0x6f, 0x6d,
+	0x6d, 0x5f, 0x77, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x2e, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74,
+	0x6d, 0x65, 0x6e, 0x74, 0x52, 0x06, 0x63, 0x6f, 0x6d, 0x6d, 0x57, 0x64, 0x12, 0x0e, 0x0a, 0x02,
+	0x61, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x61, 0x64, 0x12, 0x0e, 0x0a, 0x02,
+	0x61, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x61, 0x79, 0x12, 0x0e, 0x0a, 0x02,
+	0x61, 0x67, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x61, 0x67, 0x12, 0x10, 0x0a, 0x03,
+	0x73, 0x73, 0x6b, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x73, 0x73, 0x6b, 0x12, 0x0e,
+	0x0a, 0x02, 0x73, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x73, 0x72, 0x22, 0xa5,
+	0x03, 0x0a, 0x0d, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x6f, 0x66,
+	0x12, 0x49, 0x0a, 0x11, 0x73, 0x69, 0x67, 0x6d, 0x61, 0x5f, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x5f,
+	0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x2e, 0x53, 0x69, 0x67, 0x6d, 0x61,
+	0x52, 0x61, 0x6e, 0x67, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x52, 0x0f, 0x73, 0x69, 0x67, 0x6d,
+	0x61, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x0e, 0x0a, 0x02, 0x61,
+	0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x61, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x61,
+	0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x61, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x61,
+	0x62, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x61, 0x62, 0x12, 0x19, 0x0a, 0x08, 0x61,
+	0x79, 0x5f, 0x70, 0x72, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61,
+	0x79, 0x50, 0x72, 0x69, 0x6d, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x61, 0x74, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x02, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x73, 0x6b, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x03, 0x73, 0x73, 0x6b, 0x12, 0x0e, 0x0a, 0x02, 0x73, 0x72, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x73, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x73, 0x62, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x73, 0x62, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x74, 0x61, 0x75,
+	0x18, 0x0a, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x73, 0x74, 0x61, 0x75, 0x12, 0x2f, 0x0a, 0x06,
+	0x63, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x63,
+	0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x2e, 0x43, 0x6f, 0x6d, 0x6d,
+	0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x05, 0x63, 0x43, 0x6f, 0x6d, 0x6d, 0x12, 0x3a, 0x0a,
+	0x0c, 0x63, 0x5f, 0x70, 0x72, 0x69, 0x6d, 0x65, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x18, 0x0c, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x61, 0x6c, 0x2e, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x0a, 0x63,
+	0x50, 0x72, 0x69, 0x6d, 0x65, 0x43, 0x6f, 0x6d, 0x6d, 0x12, 0x3b, 0x0a, 0x08, 0x61, 0x75, 0x64,
+	0x69, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x2e, 0x41, 0x75, 0x64, 0x69, 0x74,
+	0x6f, 0x72, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x74, 0x65, 0x78, 0x74, 0x52, 0x08, 0x61, 0x75,
+	0x64, 0x69, 0x74, 0x6f, 0x72, 0x73, 0x22, 0x68, 0x0a, 0x10, 0x46, 0x65, 0x65, 0x54, 0x72, 0x61,
+	0x6e, 0x73, 0x66, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x10, 0x0a, 0x03, 0x66, 0x65,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x66, 0x65, 0x65, 0x12, 0x42, 0x0a, 0x0e,
+	0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x5f, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74,
+	0x69, 0x61, 0x6c, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x6f,
+	0x66, 0x52, 0x0d, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x6f, 0x66,
+	0x22, 0x4d, 0x0a, 0x11, 0x41, 0x75, 0x64, 0x69, 0x74, 0x6f, 0x72, 0x43, 0x69, 0x70, 0x68, 0x65,
+	0x72, 0x74, 0x65, 0x78, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x75, 0x64, 0x69, 0x74, 0x6f, 0x72,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61, 0x75, 0x64, 0x69, 0x74, 0x6f, 0x72, 0x12,
+	0x0e, 0x0a, 0x02, 0x63, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x63, 0x6c, 0x12,
+	0x0e, 0x0a, 0x02, 0x61, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x61, 0x65, 0x22,
+	0x1b, 0x0a, 0x09, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x0e, 0x0a, 0x02,
+	0x70, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x70, 0x6b, 0x22, 0x44, 0x0a, 0x0e,
+	0x53, 0x74, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x17,
+	0x0a, 0x07, 0x76, 0x69, 0x65, 0x77, 0x5f, 0x70, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x06, 0x76, 0x69, 0x65, 0x77, 0x50, 0x6b, 0x12, 0x19, 0x0a, 0x08, 0x73, 0x70, 0x65, 0x6e, 0x64,
+	0x5f, 0x70, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x73, 0x70, 0x65, 0x6e, 0x64,
+	0x50, 0x6b, 0x22, 0x3d, 0x0a, 0x0d, 0x53, 0x74, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x4f, 0x75, 0x74,
+	0x70, 0x75, 0x74, 0x12, 0x0c, 0x0a, 0x01, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01,
+	0x72, 0x12, 0x1e, 0x0a, 0x0b, 0x6f, 0x6e, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x70, 0x6b,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x6f, 0x6e, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x50,
+	0x6b, 0x42, 0x26, 0x5a, 0x24, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61,
+	0x6c, 0x2d, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64,
+	0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_proof_proto_rawDescOnce sync.Once
+	file_proof_proto_rawDescData = file_proof_proto_rawDesc
+)
+
+func file_proof_proto_rawDescGZIP() []byte {
+	file_proof_proto_rawDescOnce.Do(func() {
+		file_proof_proto_rawDescData = protoimpl.X.CompressGZIP(file_proof_proto_rawDescData)
+	})
+	return file_proof_proto_rawDescData
+}
+
+var file_proof_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_proof_proto_goTypes = []interface{}{
+	(*Commitment)(nil),        // 0: confidential.Commitment
+	(*InnerProductProof)(nil), // 1: confidential.InnerProductProof
+	(*RangeProof)(nil),        // 2: confidential.RangeProof
+	(*SigmaRangeProof)(nil),   // 3: confidential.SigmaRangeProof
+	(*CommitmentProof)(nil),   // 4: confidential.CommitmentProof
+	(*WithdrawProof)(nil),     // 5: confidential.WithdrawProof
+	(*TransferProof)(nil),     // 6: confidential.TransferProof
+	(*FeeTransferProof)(nil),  // 7: confidential.FeeTransferProof
+	(*AuditorCiphertext)(nil), // 8: confidential.AuditorCiphertext
+	(*PublicKey)(nil),         // 9: confidential.PublicKey
+	(*StealthAddress)(nil),    // 10: confidential.StealthAddress
+	(*StealthOutput)(nil),     // 11: confidential.StealthOutput
+}
+var file_proof_proto_depIdxs = []int32{
+	1, // 0: confidential.RangeProof.inner_proof:type_name -> confidential.InnerProductProof
+	1, // 1: confidential.SigmaRangeProof.inner_proof:type_name -> confidential.InnerProductProof
+	2, // 2: confidential.WithdrawProof.range_proof:type_name -> confidential.RangeProof
+	0, // 3: confidential.WithdrawProof.comm_wd:type_name -> confidential.Commitment
+	3, // 4: confidential.TransferProof.sigma_range_proof:type_name -> confidential.SigmaRangeProof
+	0, // 5: confidential.TransferProof.c_comm:type_name -> confidential.Commitment
+	0, // 6: confidential.TransferProof.c_prime_comm:type_name -> confidential.Commitment
+	8, // 7: confidential.TransferProof.auditors:type_name -> confidential.AuditorCiphertext
+	6, // 8: confidential.FeeTransferProof.transfer_proof:type_name -> confidential.TransferProof
+	9, // [9:9] is the sub-list for method output_type
+	9, // [9:9] is the sub-list for method input_type
+	9, // [9:9] is the sub-list for extension type_name
+	9, // [9:9] is the sub-list for extension extendee
+	0, // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_proof_proto_init() }
+func file_proof_proto_init() {
+	if File_proof_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_proof_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Commitment); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InnerProductProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RangeProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SigmaRangeProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CommitmentProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WithdrawProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TransferProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FeeTransferProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AuditorCiphertext); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PublicKey); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StealthAddress); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proof_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StealthOutput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proof_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proof_proto_goTypes,
+		DependencyIndexes: file_proof_proto_depIdxs,
+		MessageInfos:      file_proof_proto_msgTypes,
+	}.Build()
+	File_proof_proto = out.File
+	file_proof_proto_rawDesc = nil
+	file_proof_proto_goTypes = nil
+	file_proof_proto_depIdxs = nil
+}
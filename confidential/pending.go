@@ -0,0 +1,136 @@
+package confidential
+
+import (
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Pending balances. Credits to an account go to its pending commitment in PendingMap, while transfer and
+//withdraw proofs are generated and verified against the available commitment in CommitmentMap. The
+//pending commitment is rolled into the available one the first time the account is touched in a new
+//epoch, so incoming payments never invalidate a proof during the epoch it was generated in. A proof must
+//land before the epoch ends, the rollover changes the available commitment.
+
+//zeroCommitment encrypts zero with zero randomness, it is the neutral element of Commitment.Add
+func zeroCommitment() *Commitment {
+	return &Commitment{
+		Cl: new(ristretto255.Element).Zero(),
+		Cr: new(ristretto255.Element).Zero(),
+	}
+}
+
+func pkKey(pk *ristretto255.Element) [32]byte {
+	var key [32]byte
+	copy(key[:], pk.Encode(nil))
+	return key
+}
+
+//NextEpoch starts a new epoch and returns its number
+func (sc *SmartContract) NextEpoch() uint64 {
+	sc.Mu.Lock()
+	defer sc.Mu.Unlock()
+	sc.Epoch++
+	return sc.Epoch
+}
+
+//GetPending returns the credits of pk that are not yet spendable
+func (sc *SmartContract) GetPending(pk *ristretto255.Element) *Commitment {
+	return sc.PendingMap[pkKey(pk)]
+}
+
+//RollOver moves the pending commitment of pk into the available one if pk was not rolled over in the
+//current epoch. Wallets call it before generating a proof so that they prove against the final state of
+//the epoch.
+func (sc *SmartContract) RollOver(pk *ristretto255.Element) error {
+	sc.Mu.Lock()
+	defer sc.Mu.Unlock()
+	return sc.rollOver(pk)
+}
+
+func (sc *SmartContract) rollOver(pk *ristretto255.Element) error {
+	key := pkKey(pk)
+	comm, ok := sc.CommitmentMap[key]
+	if !ok {
+		return errors.New("pk not exist")
+	}
+	if sc.RolloverMap[key] == sc.Epoch {
+		return nil
+	}
+	if pending := sc.PendingMap[key]; pending != nil {
+		sc.CommitmentMap[key] = new(Commitment).Add(comm, pending)
+	}
+	sc.PendingMap[key] = zeroCommitment()
+	sc.RolloverMap[key] = sc.Epoch
+	return nil
+}
+
+//Credit adds comm to the pending commitment of pk
+func (sc *SmartContract) Credit(pk *ristretto255.Element, comm *Commitment) error {
+	sc.Mu.Lock()
+	defer sc.Mu.Unlock()
+	return sc.credit(pk, comm)
+}
+
+func (sc *SmartContract) credit(pk *ristretto255.Element, comm *Commitment) error {
+	key := pkKey(pk)
+	if _, ok := sc.CommitmentMap[key]; !ok {
+		return errors.New("pk not exist")
+	}
+	if err := sc.rollOver(pk); err != nil {
+		return err
+	}
+	sc.PendingMap[key] = new(Commitment).Add(sc.PendingMap[key], comm)
+	return nil
+}
+
+//ApplyTransfer verifies a transfer and applies it: CComm leaves the available balance of y and
+//CPrimeComm is credited to the pending balance of yPrime. The nullifier of the current epoch is
+//recorded, auth is required if y registered a spend key and may be nil otherwise.
+func (sc *SmartContract) ApplyTransfer(trans [64]byte, proof *TransferProof, auth *SpendAuthorization, nullifier *Nullifier, y, yPrime *ristretto255.Element) bool {
+	sc.Mu.Lock()
+	defer sc.Mu.Unlock()
+	return sc.applyTransfer(trans, proof, auth, nullifier, y, yPrime)
+}
+
+func (sc *SmartContract) applyTransfer(trans [64]byte, proof *TransferProof, auth *SpendAuthorization, nullifier *Nullifier, y, yPrime *ristretto255.Element) bool {
+	if sc.rollOver(y) != nil || sc.GetCommitment(yPrime) == nil || !sc.VerifyNullifier(trans, y, proof, nullifier) {
+		return false
+	}
+	if sc.getSpendKey(y) != nil {
+		if !sc.VerifyAuthorizedTransferProof(trans, proof, auth, y, yPrime) {
+			return false
+		}
+	} else if !sc.verifyTransferProof(trans, proof, y, yPrime) {
+		return false
+	}
+	sc.NullifierMap[pkKey(nullifier.U)] = sc.Epoch
+	key := pkKey(y)
+	sc.CommitmentMap[key] = new(Commitment).Sub(sc.CommitmentMap[key], &proof.CComm)
+	return sc.credit(yPrime, &proof.CPrimeComm) == nil
+}
+
+//ApplyWithdraw verifies a withdraw and moves amount from the available balance of y to its public balance
+func (sc *SmartContract) ApplyWithdraw(trans [64]byte, y *ristretto255.Element, amount uint64, proof *WithdrawProof, auth *SpendAuthorization, nullifier *Nullifier) bool {
+	sc.Mu.Lock()
+	defer sc.Mu.Unlock()
+	if sc.rollOver(y) != nil || !sc.VerifyNullifier(trans, y, proof, nullifier) {
+		return false
+	}
+	if sc.getSpendKey(y) != nil {
+		if !sc.VerifyAuthorizedWithdrawProof(trans, y, amount, proof, auth) {
+			return false
+		}
+	} else if !sc.verifyWithDrawProof(trans, y, amount, proof) {
+		return false
+	}
+	sc.NullifierMap[pkKey(nullifier.U)] = sc.Epoch
+	key := pkKey(y)
+	sc.CommitmentMap[key] = new(Commitment).Sub(sc.CommitmentMap[key], &proof.CommWD)
+	sc.PublicBalanceMap[key] += amount
+	return true
+}
+
+//PendingBalance decrypts the pending commitment of the account
+func (acc *Account) PendingBalance(pending *Commitment) (uint64, error) {
+	return acc.decryptAmount(pending.Cl, pending.Cr)
+}
@@ -0,0 +1,464 @@
+package confidential
+
+//go:generate protoc --go_out=pb --go_opt=paths=source_relative proof.proto
+
+import (
+	"confidential-account/confidential/pb"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+	"google.golang.org/protobuf/proto"
+)
+
+//MarshalProto/UnmarshalProto convert between the Go types and the messages of proof.proto. The messages
+//are generated into the pb package, the functions here only map fields and check that every point and
+//scalar is a canonical 32 bytes encoding.
+
+func protoFieldError(field, msg string) error {
+	return errors.New("protobuf field " + field + ": " + msg)
+}
+
+func elementProto(e *ristretto255.Element) []byte {
+	if e == nil {
+		return nil
+	}
+	b := ElementToBytes(e)
+	return b[:]
+}
+
+func scalarProto(s *ristretto255.Scalar) []byte {
+	if s == nil {
+		return nil
+	}
+	b := ScalarToBytes(s)
+	return b[:]
+}
+
+func marshalProto(m proto.Message) []byte {
+	b, _ := proto.Marshal(m)
+	return b
+}
+
+//protoDecoder keeps the first error, so a message is converted field by field without checking each one
+type protoDecoder struct {
+	err error
+}
+
+func (d *protoDecoder) fixed(field string, b []byte) [32]byte {
+	var buf [32]byte
+	if d.err != nil {
+		return buf
+	}
+	if len(b) == 0 {
+		d.err = protoFieldError(field, "missing")
+	} else if len(b) != 32 {
+		d.err = protoFieldError(field, "not 32 bytes")
+	}
+	copy(buf[:], b)
+	return buf
+}
+
+func (d *protoDecoder) element(field string, b []byte) *ristretto255.Element {
+	buf := d.fixed(field, b)
+	if d.err != nil {
+		return nil
+	}
+	e, err := ElementFromBytes(buf)
+	d.err = err
+	return e
+}
+
+func (d *protoDecoder) scalar(field string, b []byte) *ristretto255.Scalar {
+	buf := d.fixed(field, b)
+	if d.err != nil {
+		return nil
+	}
+	s, err := ScalarFromBytes(buf)
+	d.err = err
+	return s
+}
+
+//message records a missing sub message, the caller converts it only if d.err is nil
+func (d *protoDecoder) message(field string, present bool) {
+	if d.err == nil && !present {
+		d.err = protoFieldError(field, "missing")
+	}
+}
+
+func (comm *Commitment) toProto() *pb.Commitment {
+	return &pb.Commitment{Cl: elementProto(comm.Cl), Cr: elementProto(comm.Cr)}
+}
+
+func (comm *Commitment) fromProto(m *pb.Commitment) error {
+	var d protoDecoder
+	d.message("commitment", m != nil)
+	comm.Cl = d.element("cl", m.GetCl())
+	comm.Cr = d.element("cr", m.GetCr())
+	return d.err
+}
+
+func (comm *Commitment) MarshalProto() []byte {
+	return marshalProto(comm.toProto())
+}
+
+func (comm *Commitment) UnmarshalProto(b []byte) error {
+	var m pb.Commitment
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return comm.fromProto(&m)
+}
+
+func (self *InnerProductProof) toProto() *pb.InnerProductProof {
+	m := &pb.InnerProductProof{A: scalarProto(self.a), B: scalarProto(self.b)}
+	for i := range self.Ls {
+		m.Ls = append(m.Ls, elementProto(self.Ls[i]))
+		m.Rs = append(m.Rs, elementProto(self.Rs[i]))
+	}
+	return m
+}
+
+func (self *InnerProductProof) fromProto(m *pb.InnerProductProof) error {
+	var d protoDecoder
+	d.message("inner_proof", m != nil)
+	if d.err != nil {
+		return d.err
+	}
+	rounds := len(m.Ls)
+	if rounds != len(m.Rs) || rounds > maxInnerProductRounds {
+		return ErrProofLength
+	}
+	self.a = d.scalar("a", m.A)
+	self.b = d.scalar("b", m.B)
+	self.iteration = int32(rounds)
+	self.Ls = make([]*ristretto255.Element, rounds)
+	self.Rs = make([]*ristretto255.Element, rounds)
+	for i := 0; i < rounds; i++ {
+		self.Ls[i] = d.element("ls", m.Ls[i])
+		self.Rs[i] = d.element("rs", m.Rs[i])
+	}
+	return d.err
+}
+
+func (self *InnerProductProof) MarshalProto() []byte {
+	return marshalProto(self.toProto())
+}
+
+func (self *InnerProductProof) UnmarshalProto(b []byte) error {
+	var m pb.InnerProductProof
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return self.fromProto(&m)
+}
+
+func (proof *RangeProof) toProto() *pb.RangeProof {
+	return &pb.RangeProof{
+		G:          elementProto(proof.G),
+		H:          elementProto(proof.H),
+		Taux:       scalarProto(proof.Taux),
+		Mu:         scalarProto(proof.Mu),
+		THat:       scalarProto(proof.THat),
+		T1:         elementProto(proof.T1),
+		T2:         elementProto(proof.T2),
+		A:          elementProto(proof.A),
+		S:          elementProto(proof.S),
+		InnerProof: proof.InnerProof.toProto(),
+	}
+}
+
+//G and H are left nil when the message has neither, as for the compact encoding
+func (proof *RangeProof) fromProto(m *pb.RangeProof) error {
+	var d protoDecoder
+	d.message("range_proof", m != nil)
+	proof.G, proof.H = nil, nil
+	if len(m.GetG()) != 0 || len(m.GetH()) != 0 {
+		proof.G = d.element("g", m.G)
+		proof.H = d.element("h", m.H)
+	}
+	proof.Taux = d.scalar("taux", m.GetTaux())
+	proof.Mu = d.scalar("mu", m.GetMu())
+	proof.THat = d.scalar("t_hat", m.GetTHat())
+	proof.T1 = d.element("t1", m.GetT1())
+	proof.T2 = d.element("t2", m.GetT2())
+	proof.A = d.element("a", m.GetA())
+	proof.S = d.element("s", m.GetS())
+	if d.err != nil {
+		return d.err
+	}
+	return proof.InnerProof.fromProto(m.InnerProof)
+}
+
+func (proof *RangeProof) MarshalProto() []byte {
+	return marshalProto(proof.toProto())
+}
+
+func (proof *RangeProof) UnmarshalProto(b []byte) error {
+	var m pb.RangeProof
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return proof.fromProto(&m)
+}
+
+func (proof *SigmaRangeProof) toProto() *pb.SigmaRangeProof {
+	return &pb.SigmaRangeProof{
+		Taux:       scalarProto(proof.Taux),
+		Mu:         scalarProto(proof.Mu),
+		THat:       scalarProto(proof.THat),
+		T1:         elementProto(proof.T1),
+		T2:         elementProto(proof.T2),
+		A:          elementProto(proof.A),
+		S:          elementProto(proof.S),
+		InnerProof: proof.InnerProof.toProto(),
+	}
+}
+
+func (proof *SigmaRangeProof) fromProto(m *pb.SigmaRangeProof) error {
+	var d protoDecoder
+	d.message("sigma_range_proof", m != nil)
+	proof.Taux = d.scalar("taux", m.GetTaux())
+	proof.Mu = d.scalar("mu", m.GetMu())
+	proof.THat = d.scalar("t_hat", m.GetTHat())
+	proof.T1 = d.element("t1", m.GetT1())
+	proof.T2 = d.element("t2", m.GetT2())
+	proof.A = d.element("a", m.GetA())
+	proof.S = d.element("s", m.GetS())
+	if d.err != nil {
+		return d.err
+	}
+	return proof.InnerProof.fromProto(m.InnerProof)
+}
+
+func (proof *SigmaRangeProof) MarshalProto() []byte {
+	return marshalProto(proof.toProto())
+}
+
+func (proof *SigmaRangeProof) UnmarshalProto(b []byte) error {
+	var m pb.SigmaRangeProof
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return proof.fromProto(&m)
+}
+
+func (proof *CommitmentProof) MarshalProto() []byte {
+	return marshalProto(&pb.CommitmentProof{
+		Ay:  elementProto(proof.ay),
+		Acr: elementProto(proof.acr),
+		Ssk: scalarProto(proof.ssk),
+		B:   scalarProto(proof.B),
+	})
+}
+
+func (proof *CommitmentProof) UnmarshalProto(b []byte) error {
+	var m pb.CommitmentProof
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	var d protoDecoder
+	proof.ay = d.element("ay", m.Ay)
+	proof.acr = d.element("acr", m.Acr)
+	proof.ssk = d.scalar("ssk", m.Ssk)
+	proof.B = d.scalar("b", m.B)
+	return d.err
+}
+
+func (proof *WithdrawProof) MarshalProto() []byte {
+	return marshalProto(&pb.WithdrawProof{
+		RangeProof: proof.rangeProof.toProto(),
+		CommWd:     proof.CommWD.toProto(),
+		Ad:         elementProto(proof.ad),
+		Ay:         elementProto(proof.ay),
+		Ag:         elementProto(proof.ag),
+		Ssk:        scalarProto(proof.ssk),
+		Sr:         scalarProto(proof.sr),
+	})
+}
+
+func (proof *WithdrawProof) UnmarshalProto(b []byte) error {
+	var m pb.WithdrawProof
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	var rangeProof RangeProof
+	if err := rangeProof.fromProto(m.RangeProof); err != nil {
+		return err
+	}
+	if err := proof.CommWD.fromProto(m.CommWd); err != nil {
+		return err
+	}
+	var d protoDecoder
+	proof.ad = d.element("ad", m.Ad)
+	proof.ay = d.element("ay", m.Ay)
+	proof.ag = d.element("ag", m.Ag)
+	proof.ssk = d.scalar("ssk", m.Ssk)
+	proof.sr = d.scalar("sr", m.Sr)
+	if d.err != nil {
+		return d.err
+	}
+	proof.rangeProof = &rangeProof
+	return nil
+}
+
+func (proof *TransferProof) toProto() *pb.TransferProof {
+	m := &pb.TransferProof{
+		SigmaRangeProof: proof.sigmaRangeProof.toProto(),
+		Ay:              elementProto(proof.ay),
+		Ad:              elementProto(proof.ad),
+		Ab:              elementProto(proof.ab),
+		AyPrime:         elementProto(proof.ayPrime),
+		At:              elementProto(proof.at),
+		Ssk:             scalarProto(proof.ssk),
+		Sr:              scalarProto(proof.sr),
+		Sb:              scalarProto(proof.sb),
+		Stau:            scalarProto(proof.stau),
+		CComm:           proof.CComm.toProto(),
+		CPrimeComm:      proof.CPrimeComm.toProto(),
+	}
+	for i := range proof.Auditors {
+		m.Auditors = append(m.Auditors, proof.Auditors[i].toProto())
+	}
+	return m
+}
+
+func (proof *TransferProof) fromProto(m *pb.TransferProof) error {
+	var d protoDecoder
+	d.message("transfer_proof", m != nil)
+	if d.err != nil {
+		return d.err
+	}
+	var sigmaRangeProof SigmaRangeProof
+	if err := sigmaRangeProof.fromProto(m.SigmaRangeProof); err != nil {
+		return err
+	}
+	proof.ay = d.element("ay", m.Ay)
+	proof.ad = d.element("ad", m.Ad)
+	proof.ab = d.element("ab", m.Ab)
+	proof.ayPrime = d.element("ay_prime", m.AyPrime)
+	proof.at = d.element("at", m.At)
+	proof.ssk = d.scalar("ssk", m.Ssk)
+	proof.sr = d.scalar("sr", m.Sr)
+	proof.sb = d.scalar("sb", m.Sb)
+	proof.stau = d.scalar("stau", m.Stau)
+	if d.err != nil {
+		return d.err
+	}
+	if err := proof.CComm.fromProto(m.CComm); err != nil {
+		return err
+	}
+	if err := proof.CPrimeComm.fromProto(m.CPrimeComm); err != nil {
+		return err
+	}
+	if len(m.Auditors) > MaxAuditors {
+		return ErrTooManyAuditors
+	}
+	proof.Auditors = nil
+	for _, auditor := range m.Auditors {
+		var ct AuditorCiphertext
+		if err := ct.fromProto(auditor); err != nil {
+			return err
+		}
+		proof.Auditors = append(proof.Auditors, ct)
+	}
+	proof.sigmaRangeProof = &sigmaRangeProof
+	return nil
+}
+
+func (proof *TransferProof) MarshalProto() []byte {
+	return marshalProto(proof.toProto())
+}
+
+func (proof *TransferProof) UnmarshalProto(b []byte) error {
+	var m pb.TransferProof
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return proof.fromProto(&m)
+}
+
+//FeeTransferProof needs its own methods, the ones promoted from the embedded TransferProof drop the fee
+func (proof *FeeTransferProof) MarshalProto() []byte {
+	return marshalProto(&pb.FeeTransferProof{Fee: proof.Fee, TransferProof: proof.TransferProof.toProto()})
+}
+
+func (proof *FeeTransferProof) UnmarshalProto(b []byte) error {
+	var m pb.FeeTransferProof
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	if err := proof.TransferProof.fromProto(m.TransferProof); err != nil {
+		return err
+	}
+	proof.Fee = m.Fee
+	return nil
+}
+
+func (ct *AuditorCiphertext) toProto() *pb.AuditorCiphertext {
+	return &pb.AuditorCiphertext{Auditor: elementProto(ct.Auditor), Cl: elementProto(ct.Cl), Ae: elementProto(ct.ae)}
+}
+
+func (ct *AuditorCiphertext) fromProto(m *pb.AuditorCiphertext) error {
+	var d protoDecoder
+	d.message("auditors", m != nil)
+	ct.Auditor = d.element("auditor", m.GetAuditor())
+	ct.Cl = d.element("cl", m.GetCl())
+	ct.ae = d.element("ae", m.GetAe())
+	return d.err
+}
+
+func (ct *AuditorCiphertext) MarshalProto() []byte {
+	return marshalProto(ct.toProto())
+}
+
+func (ct *AuditorCiphertext) UnmarshalProto(b []byte) error {
+	var m pb.AuditorCiphertext
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return ct.fromProto(&m)
+}
+
+//MarshalPublicKeyProto encodes an account public key as a PublicKey message
+func MarshalPublicKeyProto(pk *ristretto255.Element) []byte {
+	return marshalProto(&pb.PublicKey{Pk: elementProto(pk)})
+}
+
+func UnmarshalPublicKeyProto(b []byte) (*ristretto255.Element, error) {
+	var m pb.PublicKey
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	var d protoDecoder
+	pk := d.element("pk", m.Pk)
+	return pk, d.err
+}
+
+func (addr *StealthAddress) MarshalProto() []byte {
+	return marshalProto(&pb.StealthAddress{ViewPk: elementProto(addr.ViewPk), SpendPk: elementProto(addr.SpendPk)})
+}
+
+func (addr *StealthAddress) UnmarshalProto(b []byte) error {
+	var m pb.StealthAddress
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	var d protoDecoder
+	addr.ViewPk = d.element("view_pk", m.ViewPk)
+	addr.SpendPk = d.element("spend_pk", m.SpendPk)
+	return d.err
+}
+
+func (out *StealthOutput) MarshalProto() []byte {
+	return marshalProto(&pb.StealthOutput{R: elementProto(out.R), OneTimePk: elementProto(out.OneTimePk)})
+}
+
+func (out *StealthOutput) UnmarshalProto(b []byte) error {
+	var m pb.StealthOutput
+	if err := proto.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	var d protoDecoder
+	out.R = d.element("r", m.R)
+	out.OneTimePk = d.element("one_time_pk", m.OneTimePk)
+	return d.err
+}
@@ -0,0 +1,551 @@
+package confidential
+
+import (
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Anonymous transfers. Sender and recipient hide in a ring of registered accounts y_i. The transfer
+//encrypts an amount b_i for every member under one randomness r, C_i = b_i*G + r*y_i and D = r*G, with
+//b_i = -b for the sender, b for the recipient and 0 for everyone else, and the ledger adds (C_i, D) to
+//the pending commitment of every member. Every member also gets a commitment B_i = beta_i*G + rho_i*H
+//to the bit beta_i, 1 for the sender and 0 for everyone else. The proof holds
+//  - range proofs of the Pedersen commitments Vb = b*G + taub*H and V = v*G + tau*H, v the balance of the
+//    sender after the transfer
+//  - for every member a one-out-of-many proof over three branches: sender (knows sk of y_i, the new
+//    balance of y_i opens V, C_i = r*y_i - b*G, U = sk*G_epoch, B_i - G = rho_i*H and the spend key if y_i
+//    has one), recipient (C_i = r*y_i + b*G, B_i = rho_i*H) or untouched (C_i = r*y_i, B_i = rho_i*H).
+//    Every branch opens D = r*G and the sender and recipient branches open Vb = b*G + taub*H, so r, b and
+//    taub are the same in all branches, a Pedersen commitment has a single known opening.
+//  - D = r*G, sum(C_i) = r*sum(y_i) and sum(B_i) - G = rho*H, proven once. The bits sum to one, so there
+//    is exactly one sender and U is bound to its key, and the amounts cancel, so there is exactly one
+//    recipient unless b is zero.
+//
+//The proof grows linearly with the ring like the ciphertexts it covers. Rings are powers of two, so the
+//ring size only reveals the anonymity level the sender chose. The nullifier U is the one of ordinary
+//spends, an account spends at most once per epoch either way.
+
+//MaxRingSize bounds the ring of an anonymous transfer
+const MaxRingSize = 64
+
+var ErrRingSize = errors.New("ring size must be a power of two between 2 and MaxRingSize")
+
+const (
+	ringSender = iota
+	ringRecipient
+	ringUntouched
+	ringBranches
+)
+
+type RingTransferProof struct {
+	Ring         []*ristretto255.Element
+	C            []*ristretto255.Element
+	B            []*ristretto255.Element //commitments to the sender bit of every member
+	D            *ristretto255.Element
+	V, Vb        *ristretto255.Element
+	U            *ristretto255.Element //nullifier of the sender
+	balanceProof *RangeProof
+	amountProof  *RangeProof
+	sr, srho     *ristretto255.Scalar //responses of the relations proven once
+	members      []ringMemberProof
+}
+
+//ringMemberProof holds the branch challenges, which sum to the challenge of the proof, and the branch responses
+type ringMemberProof struct {
+	c [ringBranches]*ristretto255.Scalar
+	s [ringBranches][]*ristretto255.Scalar
+}
+
+//RingState is the ledger state a ring transfer is proven and verified against
+type RingState struct {
+	Ring      []*ristretto255.Element
+	Comms     []*Commitment
+	SpendKeys []*ristretto255.Element //nil for members without a spend key
+	Epoch     uint64
+}
+
+//linearRelation states P = sum(x[j]*bases[j]) over the secrets x of a branch, nil bases do not take part
+type linearRelation struct {
+	P     *ristretto255.Element
+	bases []*ristretto255.Element
+}
+
+//commit returns sum(k[j]*bases[j]), the prover passes acc.constantTime since k holds nonces or the
+//responses of simulated branches
+func (rel linearRelation) commit(ct bool, k []*ristretto255.Scalar) *ristretto255.Element {
+	var scalars []*ristretto255.Scalar
+	var elements []*ristretto255.Element
+	for j, base := range rel.bases {
+		if base != nil {
+			scalars = append(scalars, k[j])
+			elements = append(elements, base)
+		}
+	}
+	if ct {
+		return ristretto255.NewElement().MultiScalarMult(scalars, elements)
+	}
+	return new(ristretto255.Element).VarTimeMultiScalarMult(scalars, elements)
+}
+
+//recompute returns sum(s[j]*bases[j]) - c*P, the commitment of a valid response
+func (rel linearRelation) recompute(ct bool, s []*ristretto255.Scalar, c *ristretto255.Scalar) *ristretto255.Element {
+	return new(ristretto255.Element).Add(rel.commit(ct, s), ScalarMultSelect(ct, new(ristretto255.Scalar).Negate(c), rel.P))
+}
+
+func isPowerOfTwo(n int) bool {
+	return n >= 2 && n&(n-1) == 0
+}
+
+//ringStatement collects what every branch of a ring transfer talks about
+type ringStatement struct {
+	state  *RingState
+	proof  *RingTransferProof
+	g, h   *ristretto255.Element
+	gEpoch *ristretto255.Element
+}
+
+//relations returns the relations of every branch of member i. Sender secrets are sk, tau, r, b, taub,
+//the spend key and rho_i, recipient secrets r, b, taub and rho_i and untouched secrets r and rho_i.
+func (statement *ringStatement) relations(i int) [ringBranches][]linearRelation {
+	proof := statement.proof
+	y := statement.state.Ring[i]
+	g, h := statement.g, statement.h
+	negG := new(ristretto255.Element).Negate(g)
+	negH := new(ristretto255.Element).Negate(h)
+	newBalance := new(Commitment).Add(statement.state.Comms[i], &Commitment{Cl: proof.C[i], Cr: proof.D})
+	sender := []linearRelation{
+		{P: y, bases: []*ristretto255.Element{g}},
+		{P: new(ristretto255.Element).Add(newBalance.Cl, new(ristretto255.Element).Negate(proof.V)),
+			bases: []*ristretto255.Element{newBalance.Cr, negH}},
+		{P: proof.D, bases: []*ristretto255.Element{nil, nil, g}},
+		{P: proof.C[i], bases: []*ristretto255.Element{nil, nil, y, negG}},
+		{P: proof.Vb, bases: []*ristretto255.Element{nil, nil, nil, g, h}},
+		{P: proof.U, bases: []*ristretto255.Element{statement.gEpoch}},
+		{P: new(ristretto255.Element).Add(proof.B[i], negG), bases: []*ristretto255.Element{nil, nil, nil, nil, nil, nil, h}},
+	}
+	if spendPk := statement.state.SpendKeys[i]; spendPk != nil {
+		sender = append(sender, linearRelation{P: spendPk, bases: []*ristretto255.Element{nil, nil, nil, nil, nil, g}})
+	}
+	return [ringBranches][]linearRelation{
+		ringSender: sender,
+		ringRecipient: {
+			{P: proof.D, bases: []*ristretto255.Element{g}},
+			{P: proof.C[i], bases: []*ristretto255.Element{y, g}},
+			{P: proof.Vb, bases: []*ristretto255.Element{nil, g, h}},
+			{P: proof.B[i], bases: []*ristretto255.Element{nil, nil, nil, h}},
+		},
+		ringUntouched: {
+			{P: proof.D, bases: []*ristretto255.Element{g}},
+			{P: proof.C[i], bases: []*ristretto255.Element{y}},
+			{P: proof.B[i], bases: []*ristretto255.Element{nil, h}},
+		},
+	}
+}
+
+//conservation states D = r*G, sum(C_i) = r*sum(y_i) and sum(B_i) - G = rho*H over the secrets r and rho
+func (statement *ringStatement) conservation() []linearRelation {
+	sumY := SumElements(statement.state.Ring...)
+	sumC := SumElements(statement.proof.C...)
+	sumB := new(ristretto255.Element).Add(SumElements(statement.proof.B...), new(ristretto255.Element).Negate(statement.g))
+	return []linearRelation{
+		{P: statement.proof.D, bases: []*ristretto255.Element{statement.g}},
+		{P: sumC, bases: []*ristretto255.Element{sumY}},
+		{P: sumB, bases: []*ristretto255.Element{nil, statement.h}},
+	}
+}
+
+var ringSecretCount = [ringBranches]int{ringSender: 7, ringRecipient: 4, ringUntouched: 2}
+
+//ringWitness is the role of a member and the secrets of its true branch
+type ringWitness struct {
+	role    int
+	secrets []*ristretto255.Scalar
+}
+
+//transcript binds the ring, its state, the ciphertexts and the branch commitments
+func (statement *ringStatement) challenge(trans [64]byte, commitments []*ristretto255.Element) *ristretto255.Scalar {
+	var elements []*ristretto255.Element
+	for i, y := range statement.state.Ring {
+		elements = append(elements, y, statement.state.Comms[i].Cl, statement.state.Comms[i].Cr, statement.proof.C[i], statement.proof.B[i])
+	}
+	trans, _ = UpdateTranscript(trans, elements...)
+	_, c := UpdateTranscript(trans, commitments...)
+	return c
+}
+
+//rangeTrans feeds the ciphertexts to the transcript and runs the range proofs on it
+func (proof *RingTransferProof) rangeTrans(trans [64]byte, gEpoch *ristretto255.Element) [64]byte {
+	trans, _ = UpdateTranscript(trans, proof.D, proof.V, proof.Vb, proof.U, gEpoch)
+	return trans
+}
+
+//RingState returns the state of ring as of the current epoch: members that were not rolled over yet
+//count with their pending commitment
+func (sc *SmartContract) RingState(ring []*ristretto255.Element) (*RingState, error) {
+	if !isPowerOfTwo(len(ring)) || len(ring) > MaxRingSize {
+		return nil, ErrRingSize
+	}
+	state := &RingState{Ring: ring, Epoch: sc.Epoch}
+	seen := make(map[[32]byte]bool)
+	for _, y := range ring {
+		key := pkKey(y)
+		comm, ok := sc.CommitmentMap[key]
+		if !ok || seen[key] {
+			return nil, errors.New("ring members must be distinct registered accounts")
+		}
+		seen[key] = true
+		if pending := sc.PendingMap[key]; pending != nil && sc.RolloverMap[key] != sc.Epoch {
+			comm = new(Commitment).Add(comm, pending)
+		}
+		state.Comms = append(state.Comms, comm)
+		state.SpendKeys = append(state.SpendKeys, sc.getSpendKey(y))
+	}
+	return state, nil
+}
+
+//GenRingTransferProof sends amount to state.Ring[recipient], the account must be a member of the ring
+func (acc *Account) GenRingTransferProof(trans [64]byte, state *RingState, recipient int, amount uint64) (*RingTransferProof, error) {
+	if acc.viewOnly {
+		return nil, ErrViewOnly
+	}
+	n := len(state.Ring)
+	if !isPowerOfTwo(n) || n > MaxRingSize || len(state.Comms) != n || len(state.SpendKeys) != n {
+		return nil, ErrRingSize
+	}
+	sender := -1
+	for i, y := range state.Ring {
+		if y.Equal(acc.Pk) == 1 {
+			sender = i
+		}
+	}
+	if sender < 0 || recipient < 0 || recipient >= n || recipient == sender {
+		return nil, errors.New("sender and recipient must be distinct ring members")
+	}
+	if state.SpendKeys[sender] != nil && (!acc.CanSpend() || state.SpendKeys[sender].Equal(acc.SpendPk) != 1) {
+		return nil, ErrViewOnly
+	}
+	balance, err := acc.decryptAmount(state.Comms[sender].Cl, state.Comms[sender].Cr)
+	if err != nil {
+		return nil, err
+	}
+	if amount > balance {
+		return nil, errors.New("amount exceeds the balance")
+	}
+	b, err := InttoScalar(amount)
+	if err != nil {
+		return nil, err
+	}
+	v, err := InttoScalar(balance - amount)
+	if err != nil {
+		return nil, err
+	}
+
+	g, h := acc.basePoint, acc.rangeProver.H
+	gEpoch := EpochBase(state.Epoch)
+	r := acc.RandScalar()
+	tau := acc.RandScalar()
+	taub := acc.RandScalar()
+	proof := &RingTransferProof{
+		Ring: state.Ring,
+		D:    acc.mult(r, g),
+		V:    SumElements(acc.mult(v, g), acc.mult(tau, h)),
+		Vb:   SumElements(acc.mult(b, g), acc.mult(taub, h)),
+		U:    acc.mult(acc.sk, gEpoch),
+	}
+	negB := new(ristretto255.Scalar).Negate(b)
+	witnesses := make([]ringWitness, n)
+	rho := new(ristretto255.Scalar).Zero()
+	for i, y := range state.Ring {
+		ci := acc.mult(r, y)
+		rhoi := acc.RandScalar()
+		bi := acc.mult(rhoi, h)
+		rho = SumScalars(rho, rhoi)
+		switch i {
+		case sender:
+			ci = SumElements(ci, acc.mult(negB, g))
+			bi = SumElements(bi, g)
+			witnesses[i] = ringWitness{ringSender, []*ristretto255.Scalar{acc.sk, tau, r, b, taub, nil, rhoi}}
+		case recipient:
+			ci = SumElements(ci, acc.mult(b, g))
+			witnesses[i] = ringWitness{ringRecipient, []*ristretto255.Scalar{r, b, taub, rhoi}}
+		default:
+			witnesses[i] = ringWitness{ringUntouched, []*ristretto255.Scalar{r, rhoi}}
+		}
+		proof.C = append(proof.C, ci)
+		proof.B = append(proof.B, bi)
+	}
+
+	//the range proofs draw their blinding values from a prover seeded by the account, so they never repeat
+	var seed [32]byte
+	acc.xof.Read(seed[:])
+	prover, err := NewRangeProver(RANGEBITS, seed)
+	if err != nil {
+		return nil, err
+	}
+	prover.ConstantTime = acc.constantTime
+	trans = proof.rangeTrans(trans, gEpoch)
+	trans, proof.balanceProof, err = prover.GenRangeProof(trans, ElgamalCommitment{g: g, h: h, v: v, gamma: tau, comm: proof.V})
+	if err != nil {
+		return nil, err
+	}
+	trans, proof.amountProof, err = prover.GenRangeProof(trans, ElgamalCommitment{g: g, h: h, v: b, gamma: taub, comm: proof.Vb})
+	if err != nil {
+		return nil, err
+	}
+
+	spendSk := new(ristretto255.Scalar).Zero()
+	if state.SpendKeys[sender] != nil {
+		spendSk = acc.spendSk
+	}
+	witnesses[sender].secrets[5] = spendSk
+	acc.proveRing(trans, &ringStatement{state: state, proof: proof, g: g, h: h, gEpoch: gEpoch}, witnesses, r, rho)
+	return proof, nil
+}
+
+//proveRing answers the relations proven once with r and rho, the true branch of every member with its
+//witness and simulates the other branches
+func (acc *Account) proveRing(trans [64]byte, statement *ringStatement, witnesses []ringWitness, r, rho *ristretto255.Scalar) {
+	proof := statement.proof
+	nonces := make([][]*ristretto255.Scalar, len(witnesses))
+	proof.members = make([]ringMemberProof, len(witnesses))
+	kr := acc.RandScalar()
+	krho := acc.RandScalar()
+	var commitments []*ristretto255.Element
+	for _, rel := range statement.conservation() {
+		commitments = append(commitments, rel.commit(acc.constantTime, []*ristretto255.Scalar{kr, krho}))
+	}
+	for i, witness := range witnesses {
+		member := &proof.members[i]
+		for branch, relations := range statement.relations(i) {
+			if branch == witness.role {
+				for range witness.secrets {
+					nonces[i] = append(nonces[i], acc.RandScalar())
+				}
+				for _, rel := range relations {
+					commitments = append(commitments, rel.commit(acc.constantTime, nonces[i]))
+				}
+				continue
+			}
+			//simulate the branches that are not true
+			member.c[branch] = acc.RandScalar()
+			for j := 0; j < ringSecretCount[branch]; j++ {
+				member.s[branch] = append(member.s[branch], acc.RandScalar())
+			}
+			for _, rel := range relations {
+				commitments = append(commitments, rel.recompute(acc.constantTime, member.s[branch], member.c[branch]))
+			}
+		}
+	}
+	c := statement.challenge(trans, commitments)
+	proof.sr = SumScalars(kr, Mul(c, r))
+	proof.srho = SumScalars(krho, Mul(c, rho))
+	for i, witness := range witnesses {
+		member := &proof.members[i]
+		cReal := c
+		for branch := 0; branch < ringBranches; branch++ {
+			if branch != witness.role {
+				cReal = new(ristretto255.Scalar).Subtract(cReal, member.c[branch])
+			}
+		}
+		member.c[witness.role] = cReal
+		for j, k := range nonces[i] {
+			member.s[witness.role] = append(member.s[witness.role], SumScalars(k, Mul(cReal, witness.secrets[j])))
+		}
+	}
+}
+
+//VerifyRingTransferProof checks proof against the ledger state of its ring in the current epoch
+func (sc *SmartContract) VerifyRingTransferProof(trans [64]byte, proof *RingTransferProof) bool {
+	state, err := sc.RingState(proof.Ring)
+	if err != nil {
+		return false
+	}
+	return sc.verifyRingTransferProof(trans, proof, state)
+}
+
+func (sc *SmartContract) verifyRingTransferProof(trans [64]byte, proof *RingTransferProof, state *RingState) (result bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			result = false
+		}
+	}()
+
+	n := len(state.Ring)
+	if len(proof.C) != n || len(proof.B) != n || len(proof.members) != n || proof.U.Equal(new(ristretto255.Element).Zero()) == 1 {
+		return false
+	}
+	if proof.balanceProof.H.Equal(sc.rangeProver.H) != 1 || proof.amountProof.H.Equal(sc.rangeProver.H) != 1 {
+		return false
+	}
+	gEpoch := EpochBase(state.Epoch)
+	trans = proof.rangeTrans(trans, gEpoch)
+	trans, result = sc.rangeProver.VerifyRangeProof(trans, proof.balanceProof, proof.V)
+	if !result {
+		return false
+	}
+	trans, result = sc.rangeProver.VerifyRangeProof(trans, proof.amountProof, proof.Vb)
+	if !result {
+		return false
+	}
+
+	statement := &ringStatement{state: state, proof: proof, g: sc.BasePoint, h: sc.rangeProver.H, gEpoch: gEpoch}
+	//the challenge of the conservation relations is the sum of the branch challenges of the first member
+	c := SumScalars(proof.members[0].c[:]...)
+	var commitments []*ristretto255.Element
+	for _, rel := range statement.conservation() {
+		commitments = append(commitments, rel.recompute(false, []*ristretto255.Scalar{proof.sr, proof.srho}, c))
+	}
+	for i := range proof.members {
+		member := &proof.members[i]
+		for branch, relations := range statement.relations(i) {
+			if len(member.s[branch]) != ringSecretCount[branch] {
+				return false
+			}
+			for _, rel := range relations {
+				commitments = append(commitments, rel.recompute(false, member.s[branch], member.c[branch]))
+			}
+		}
+	}
+	expected := statement.challenge(trans, commitments)
+	for _, member := range proof.members {
+		if SumScalars(member.c[:]...).Equal(expected) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+//ApplyRingTransfer verifies an anonymous transfer and adds (C_i, D) to the pending commitment of every
+//ring member. Members are rolled over first, so the proof is checked against the state RingState returned.
+func (sc *SmartContract) ApplyRingTransfer(trans [64]byte, proof *RingTransferProof) bool {
+	sc.Mu.Lock()
+	defer sc.Mu.Unlock()
+	if proof == nil || proof.U == nil {
+		return false
+	}
+	state, err := sc.RingState(proof.Ring)
+	if err != nil {
+		return false
+	}
+	if _, ok := sc.NullifierMap[pkKey(proof.U)]; ok {
+		return false
+	}
+	for _, y := range proof.Ring {
+		if sc.rollOver(y) != nil {
+			return false
+		}
+	}
+	if !sc.verifyRingTransferProof(trans, proof, state) {
+		return false
+	}
+	sc.NullifierMap[pkKey(proof.U)] = sc.Epoch
+	for i, y := range proof.Ring {
+		key := pkKey(y)
+		sc.PendingMap[key] = new(Commitment).Add(sc.PendingMap[key], &Commitment{Cl: proof.C[i], Cr: proof.D})
+	}
+	return true
+}
+
+//DecryptRingTransfer returns the amount a ring transfer credits to this account, zero if the account is
+//a decoy. The sender sees its debit as out of range.
+func (acc *Account) DecryptRingTransfer(proof *RingTransferProof) (uint64, error) {
+	for i, y := range proof.Ring {
+		if y.Equal(acc.Pk) == 1 {
+			return acc.decryptAmount(proof.C[i], proof.D)
+		}
+	}
+	return 0, errors.New("account is not a ring member")
+}
+
+func (member *ringMemberProof) Serialization(sink *ZeroCopySink) {
+	for branch := 0; branch < ringBranches; branch++ {
+		sink.WriteFixedScalar(member.c[branch])
+		for _, s := range member.s[branch] {
+			sink.WriteFixedScalar(s)
+		}
+	}
+}
+
+func (member *ringMemberProof) Deserialization(source *ZeroCopySource) error {
+	for branch := 0; branch < ringBranches; branch++ {
+		if err := nextFixedScalars(source, &member.c[branch]); err != nil {
+			return err
+		}
+		member.s[branch] = make([]*ristretto255.Scalar, ringSecretCount[branch])
+		for j := range member.s[branch] {
+			if err := nextFixedScalars(source, &member.s[branch][j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (proof *RingTransferProof) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint8(uint8(len(proof.Ring)))
+	for i, y := range proof.Ring {
+		sink.WriteFixedElement(y)
+		sink.WriteFixedElement(proof.C[i])
+		sink.WriteFixedElement(proof.B[i])
+	}
+	sink.WriteFixedElement(proof.D)
+	sink.WriteFixedElement(proof.V)
+	sink.WriteFixedElement(proof.Vb)
+	sink.WriteFixedElement(proof.U)
+	EncodeBytes(sink, proof.balanceProof.Serialize())
+	EncodeBytes(sink, proof.amountProof.Serialize())
+	sink.WriteFixedScalar(proof.sr)
+	sink.WriteFixedScalar(proof.srho)
+	for i := range proof.members {
+		proof.members[i].Serialization(sink)
+	}
+}
+
+func (proof *RingTransferProof) Deserialization(source *ZeroCopySource) error {
+	n, eof := source.NextUint8()
+	if eof {
+		return ErrProofLength
+	}
+	if !isPowerOfTwo(int(n)) || n > MaxRingSize {
+		return ErrRingSize
+	}
+	proof.Ring = make([]*ristretto255.Element, n)
+	proof.C = make([]*ristretto255.Element, n)
+	proof.B = make([]*ristretto255.Element, n)
+	for i := range proof.Ring {
+		if err := nextFixedElements(source, &proof.Ring[i], &proof.C[i], &proof.B[i]); err != nil {
+			return err
+		}
+	}
+	if err := nextFixedElements(source, &proof.D, &proof.V, &proof.Vb, &proof.U); err != nil {
+		return err
+	}
+	for _, rangeProof := range []**RangeProof{&proof.balanceProof, &proof.amountProof} {
+		b, err := DecodeBytes(source)
+		if err != nil {
+			return err
+		}
+		*rangeProof = new(RangeProof)
+		if err := (*rangeProof).Deserialize(b); err != nil {
+			return err
+		}
+	}
+	if err := nextFixedScalars(source, &proof.sr, &proof.srho); err != nil {
+		return err
+	}
+	proof.members = make([]ringMemberProof, n)
+	for i := range proof.members {
+		if err := proof.members[i].Deserialization(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (proof *RingTransferProof) Serialize() []byte {
+	return serialize(proof)
+}
+
+func (proof *RingTransferProof) Deserialize(b []byte) error {
+	return deserialize(proof, b)
+}
@@ -5,6 +5,10 @@ import (
 	"github.com/Evanesco-Labs/ristretto255"
 )
 const RANGEPROOFCOUNT = 2
+
+//bit length of balances and amounts proven by accounts and the smart contract
+const RANGEBITS = 32
+
 var GHXOFSeed = []byte("innerproduct rangeproof")
 var rangeProverXofSeed = "rangeprover"
 
@@ -34,6 +38,7 @@ type TransferProof struct {
 	ay, ad, ab, ayPrime, at *ristretto255.Element
 	ssk, sr, sb, stau       *ristretto255.Scalar
 	CComm, CPrimeComm       Commitment
+	Auditors                []AuditorCiphertext
 }
 
 type SigmaRangeProof struct {
@@ -67,12 +72,13 @@ type RangeProver struct {
 	table        []ristretto255.NafLookupTable8Pro
 	tableHalf    []ristretto255.NafLookupTable8Pro
 	xof          XofExpend
+	ConstantTime bool //use constant-time multiplication on witness values when proving
 }
 
 //rangeN and aggCount must be less than 64 also powers of 2.
 //randSeed can be encoded from publicKey
 func NewRangeProver(rangeN uint64, randSeed [32]byte) (*RangeProver, error) {
-	if rangeN > 64 {
+	if rangeN > MaxRangeBits {
 		return nil, errors.New("rangeN must be less than 64")
 	}
 
@@ -104,6 +110,11 @@ func NewRangeProver(rangeN uint64, randSeed [32]byte) (*RangeProver, error) {
 	return &prover, nil
 }
 
+//InnerProductRounds returns the round count of the inner product proof over aggregation values of N bits
+func (rangeProver *RangeProver) InnerProductRounds(aggregation uint64) (int, error) {
+	return InnerProductRounds(rangeProver.N * aggregation)
+}
+
 func generates(n int, seed []byte) ([]*ristretto255.Element, []*ristretto255.Element) {
 	var G, H []*ristretto255.Element
 	xofKey := [32]byte{}
@@ -137,11 +148,18 @@ func (self *RangeProver) GenRandScalar() *ristretto255.Scalar {
 
 //Use the precomputed table to acc multiscalarmult
 //Scalars have to be sort by (scalars...,G||H)
+//In constant-time mode the table is bypassed because its lookups depend on the scalars
 func (self *RangeProver) MultiScalarMult_GH(scalars []*ristretto255.Scalar) *ristretto255.Element {
+	if self.ConstantTime {
+		return ristretto255.NewElement().MultiScalarMult(scalars, append(DeepCopyElementList(self.GList), self.HList...))
+	}
 	return new(ristretto255.Element).MultiScalarMult_GH(scalars, self.table)
 }
 
 func (self *RangeProver) MultiScalarMult_GH_Half(scalars []*ristretto255.Scalar) *ristretto255.Element {
+	if self.ConstantTime {
+		return ristretto255.NewElement().MultiScalarMult(scalars, append(DeepCopyElementList(self.GList[:self.N]), self.HList[:self.N]...))
+	}
 	return new(ristretto255.Element).MultiScalarMult_GH(scalars, self.tableHalf)
 }
 
@@ -158,12 +176,14 @@ func (self *RangeProver) SumMultElements(a, b []*ristretto255.Scalar, G, H []*ri
 	elements = append(elements, G...)
 	elements = append(elements, H...)
 	elements = append(elements, u)
+	if self.ConstantTime {
+		return ristretto255.NewElement().MultiScalarMult(scalars, elements)
+	}
 	result := new(ristretto255.Element).VarTimeMultiScalarMult(scalars, elements)
 	return result
 }
 
 func (rangeProver *RangeProver) GenSigmaRangeProof(trans [64]byte, b, bPrime uint64, comm, commPrime ElgamalCommitment) (*SigmaRangeProof, *ristretto255.Scalar, [64]byte, error) {
-	scalarOne, _ := InttoScalar(uint64(1))
 	v, err := InttoScalar(b)
 	if err != nil {
 		return nil, nil, trans, err
@@ -178,16 +198,47 @@ func (rangeProver *RangeProver) GenSigmaRangeProof(trans [64]byte, b, bPrime uin
 	if vPrime.Equal(commPrime.v) != 1 {
 		return nil, nil, trans, errors.New("vPrime not right")
 	}
+	return rangeProver.genAggSigmaRangeProof(trans, []uint64{b, bPrime})
+}
 
-	bitLen := 2 * rangeProver.N
+//aggGenerators returns the bit generators for count values, beyond the precomputed lists they are
+//derived from the same seed so the lists only grow
+func (rangeProver *RangeProver) aggGenerators(count uint64) ([]*ristretto255.Element, []*ristretto255.Element) {
+	bitLen := count * rangeProver.N
+	if bitLen <= uint64(len(rangeProver.GList)) {
+		return DeepCopyElementList(rangeProver.GList[:bitLen]), DeepCopyElementList(rangeProver.HList[:bitLen])
+	}
+	G, H := generates(int(bitLen)+1, GHXOFSeed)
+	return G[1:], H[1:]
+}
 
-	G := DeepCopyElementList(rangeProver.GList)
-	H := DeepCopyElementList(rangeProver.HList)
+//multiScalarMultAgg commits to scalars over G||H, with the precomputed table for two values.
+//The constant-time MultiScalarMult accumulates into its receiver, so it has to start at the identity.
+func (rangeProver *RangeProver) multiScalarMultAgg(scalars []*ristretto255.Scalar, G, H []*ristretto255.Element) *ristretto255.Element {
+	if len(G) == len(rangeProver.GList) {
+		return rangeProver.MultiScalarMult_GH(scalars)
+	}
+	elements := append(DeepCopyElementList(G), H...)
+	if rangeProver.ConstantTime {
+		return ristretto255.NewElement().MultiScalarMult(scalars, elements)
+	}
+	return new(ristretto255.Element).VarTimeMultiScalarMult(scalars, elements)
+}
 
-	bitvector := GenBitVector(b, rangeProver.N)
-	bitvectorPrime := GenBitVector(bPrime, rangeProver.N)
+//genAggSigmaRangeProof proves every value is below 2^N, the count of values must be a power of two.
+//Value j is weighted with z^(j+2) in tHat.
+func (rangeProver *RangeProver) genAggSigmaRangeProof(trans [64]byte, values []uint64) (*SigmaRangeProof, *ristretto255.Scalar, [64]byte, error) {
+	scalarOne, _ := InttoScalar(uint64(1))
+	var err error
+	count := uint64(len(values))
+	bitLen := count * rangeProver.N
 
-	conBitVector := append(bitvector, bitvectorPrime...)
+	G, H := rangeProver.aggGenerators(count)
+
+	var conBitVector []uint64
+	for _, v := range values {
+		conBitVector = append(conBitVector, GenBitVector(v, rangeProver.N)...)
+	}
 	al := make([]*ristretto255.Scalar, bitLen, bitLen)
 	ar := make([]*ristretto255.Scalar, bitLen, bitLen)
 	for i, _ := range conBitVector {
@@ -204,8 +255,8 @@ func (rangeProver *RangeProver) GenSigmaRangeProof(trans [64]byte, b, bPrime uin
 	//commitment to al,ar
 	alpha := rangeProver.RandScalar()
 	aScalarList := append(al, ar...)
-	aCommit := rangeProver.MultiScalarMult_GH(aScalarList)
-	aCommit = new(ristretto255.Element).Add(aCommit, new(ristretto255.Element).ScalarMultWnaf(alpha, rangeProver.H))
+	aCommit := rangeProver.multiScalarMultAgg(aScalarList, G, H)
+	aCommit = new(ristretto255.Element).Add(aCommit, ScalarMultSelect(rangeProver.ConstantTime, alpha, rangeProver.H))
 
 	//commitment to sl sr
 	rho := rangeProver.RandScalar()
@@ -216,8 +267,8 @@ func (rangeProver *RangeProver) GenSigmaRangeProof(trans [64]byte, b, bPrime uin
 		sr[i] = rangeProver.RandScalar()
 	}
 	sScalarsList := append(sl, sr...)
-	sCommit := rangeProver.MultiScalarMult_GH(sScalarsList)
-	sCommit = new(ristretto255.Element).Add(sCommit, new(ristretto255.Element).ScalarMultWnaf(rho, rangeProver.H))
+	sCommit := rangeProver.multiScalarMultAgg(sScalarsList, G, H)
+	sCommit = new(ristretto255.Element).Add(sCommit, ScalarMultSelect(rangeProver.ConstantTime, rho, rangeProver.H))
 
 	//update transcript to get challenge y,z
 	trans, y := UpdateTranscript(trans, aCommit, sCommit)
@@ -232,8 +283,8 @@ func (rangeProver *RangeProver) GenSigmaRangeProof(trans [64]byte, b, bPrime uin
 	r1 := make([]*ristretto255.Scalar, bitLen, bitLen)
 
 	ita := make([]*ristretto255.Scalar, bitLen, bitLen)
-	powersOfZ := PowersList(z, uint64(RANGEPROOFCOUNT+2))
-	for i := 1; i < RANGEPROOFCOUNT+1; i++ {
+	powersOfZ := PowersList(z, count+2)
+	for i := 1; i < int(count)+1; i++ {
 		for j := uint64(0); j < rangeProver.N; j++ {
 			ita[uint64(i-1)*rangeProver.N+j] = Mul(powersOfZ[i+1], rangeProver.PowersOfTwo[j])
 		}
@@ -312,22 +363,51 @@ func (rangeProver *RangeProver) GenSigmaRangeProof(trans [64]byte, b, bPrime uin
 }
 
 func (rangeProver *RangeProver) GenRangeProof(trans [64]byte, comm ElgamalCommitment) (transRet [64]byte, proof *RangeProof, err error) {
+	witness, err := rangeProver.commitRangeProof(trans, comm.v, comm.h)
+	if err != nil {
+		return trans, nil, err
+	}
 
+	//commit to t1, t2
+	tau1 := rangeProver.RandScalar()
+	tau2 := rangeProver.RandScalar()
+	t1Commit := SumElements(ScalarMultSelect(rangeProver.ConstantTime, witness.t1, rangeProver.G),
+		ScalarMultSelect(rangeProver.ConstantTime, tau1, comm.h))
+	t2Commit := SumElements(ScalarMultSelect(rangeProver.ConstantTime, witness.t2, rangeProver.G),
+		ScalarMultSelect(rangeProver.ConstantTime, tau2, comm.h))
+
+	trans, proof, x := rangeProver.finishRangeProof(witness, t1Commit, t2Commit)
+	//get blinding value for tHat
+	proof.Taux = SumScalars(Mul(tau2, Mul(x, x)), Mul(tau1, x), Mul(Mul(witness.z, witness.z), comm.gamma))
+	return trans, proof, nil
+}
+
+//rangeProofWitness is the prover state of GenRangeProof before the T1, T2 commitments, the blinding
+//values tau1, tau2 and taux are left to the caller so they can be shared over several parties
+type rangeProofWitness struct {
+	h              *ristretto255.Element
+	alpha, rho     *ristretto255.Scalar
+	aCommit        *ristretto255.Element
+	sCommit        *ristretto255.Element
+	y, z           *ristretto255.Scalar
+	l0, l1, r0, r1 []*ristretto255.Scalar
+	t1, t2         *ristretto255.Scalar
+	trans          [64]byte
+}
+
+//commitRangeProof commits to the bits of v and to the blinding vectors with blinding base h
+func (rangeProver *RangeProver) commitRangeProof(trans [64]byte, vScalar *ristretto255.Scalar, h *ristretto255.Element) (*rangeProofWitness, error) {
 	n := rangeProver.N
-	G := DeepCopyElementList(rangeProver.GList[:n])
-	H := DeepCopyElementList(rangeProver.HList[:n])
 
 	scalarOne, err := InttoScalar(uint64(1))
 	if err != nil {
-		return trans, nil, err
+		return nil, err
 	}
 
-	vScalar := comm.v
 	v := ScalartoInt(vScalar)
-	gamma := comm.gamma
 
-	if len(G) != len(H) || len(G) != int(n) {
-		return trans, nil, errors.New("prover generator size error")
+	if len(rangeProver.GList) < int(n) || len(rangeProver.HList) < int(n) {
+		return nil, errors.New("prover generator size error")
 	}
 
 	//check cm correctness
@@ -339,7 +419,7 @@ func (rangeProver *RangeProver) GenRangeProof(trans [64]byte, comm ElgamalCommit
 	for i, _ := range alBitVector {
 		al[i], err = InttoScalar(alBitVector[i])
 		if err != nil {
-			return trans, nil, err
+			return nil, err
 		}
 	}
 
@@ -352,7 +432,7 @@ func (rangeProver *RangeProver) GenRangeProof(trans [64]byte, comm ElgamalCommit
 	alpha := rangeProver.RandScalar()
 	aScalarList := append(al, ar...)
 	aCommit := rangeProver.MultiScalarMult_GH_Half(aScalarList)
-	aCommit = new(ristretto255.Element).Add(aCommit, new(ristretto255.Element).ScalarMult(alpha, comm.h))
+	aCommit = new(ristretto255.Element).Add(aCommit, new(ristretto255.Element).ScalarMult(alpha, h))
 
 	//commitment to blinding vectors sl, sr
 	rho := rangeProver.RandScalar()
@@ -364,7 +444,7 @@ func (rangeProver *RangeProver) GenRangeProof(trans [64]byte, comm ElgamalCommit
 	}
 	sScalarList := append(sl, sr...)
 	sCommit := rangeProver.MultiScalarMult_GH_Half(sScalarList)
-	sCommit = new(ristretto255.Element).Add(sCommit, new(ristretto255.Element).ScalarMult(rho, comm.h))
+	sCommit = new(ristretto255.Element).Add(sCommit, new(ristretto255.Element).ScalarMult(rho, h))
 
 	//update transcript to get challenge y,z
 	trans, y := UpdateTranscript(trans, aCommit, sCommit)
@@ -396,31 +476,41 @@ func (rangeProver *RangeProver) GenRangeProof(trans [64]byte, comm ElgamalCommit
 	}
 
 	//t0 := SumScalars(t0List...) //check t0 correctness
-	t1 := SumScalars(t1List...)
-	t2 := SumScalars(t2List...)
+	return &rangeProofWitness{
+		h:       h,
+		alpha:   alpha,
+		rho:     rho,
+		aCommit: aCommit,
+		sCommit: sCommit,
+		y:       y,
+		z:       z,
+		l0:      l0,
+		l1:      l1,
+		r0:      r0,
+		r1:      r1,
+		t1:      SumScalars(t1List...),
+		t2:      SumScalars(t2List...),
+		trans:   trans,
+	}, nil
+}
 
-	//commit to t1, t2
-	tau1 := rangeProver.RandScalar()
-	tau2 := rangeProver.RandScalar()
-	t1Commit := SumElements(new(ristretto255.Element).ScalarMultWnaf(t1, rangeProver.G),
-		new(ristretto255.Element).ScalarMultWnaf(tau1, comm.h))
-	t2Commit := SumElements(new(ristretto255.Element).ScalarMultWnaf(t2, rangeProver.G),
-		new(ristretto255.Element).ScalarMultWnaf(tau2, comm.h))
+//finishRangeProof completes the proof for the T1, T2 commitments and returns the challenge x, Taux is left unset
+func (rangeProver *RangeProver) finishRangeProof(witness *rangeProofWitness, t1Commit, t2Commit *ristretto255.Element) ([64]byte, *RangeProof, *ristretto255.Scalar) {
+	n := rangeProver.N
+	G := DeepCopyElementList(rangeProver.GList[:n])
+	H := DeepCopyElementList(rangeProver.HList[:n])
 
 	//update transcript to get challenge x
-	trans, x := UpdateTranscript(trans, t1Commit, t2Commit)
+	trans, x := UpdateTranscript(witness.trans, t1Commit, t2Commit)
 
-	xx := Mul(x, x)
 	//l=l(x)
-	l := Substitute(l0, l1, x, uint64(n))
+	l := Substitute(witness.l0, witness.l1, x, uint64(n))
 	//r=r(x)
-	r := Substitute(r0, r1, x, uint64(n))
+	r := Substitute(witness.r0, witness.r1, x, uint64(n))
 	//tHat = <l,r>
 	tHat := InnerProduct(l, r)
-	//get blinding value for tHat
-	taux := SumScalars(Mul(tau2, xx), Mul(tau1, x), Mul(zz, gamma))
 	//get mu
-	mu := SumScalars(alpha, Mul(rho, x))
+	mu := SumScalars(witness.alpha, Mul(witness.rho, x))
 
 	//build innerproduct proof for <l,r>
 	trans, _ = UpdateTranscript(trans, t1Commit, t2Commit)
@@ -428,7 +518,7 @@ func (rangeProver *RangeProver) GenRangeProof(trans [64]byte, comm ElgamalCommit
 
 	//build innerproductproof
 	hPrime := make([]*ristretto255.Element, n, n)
-	invertY := new(ristretto255.Scalar).Invert(y)
+	invertY := new(ristretto255.Scalar).Invert(witness.y)
 	powersOfInverY := PowersList(invertY, uint64(n))
 	for i := uint64(0); i < n; i++ {
 		hPrime[i] = new(ristretto255.Element).ScalarMult(powersOfInverY[i], H[i])
@@ -438,17 +528,16 @@ func (rangeProver *RangeProver) GenRangeProof(trans [64]byte, comm ElgamalCommit
 
 	rangeProof := RangeProof{
 		G:          rangeProver.G,
-		H:          comm.h,
-		Taux:       taux,
+		H:          witness.h,
 		Mu:         mu,
 		THat:       tHat,
 		T1:         t1Commit,
 		T2:         t2Commit,
-		A:          aCommit,
-		S:          sCommit,
+		A:          witness.aCommit,
+		S:          witness.sCommit,
 		InnerProof: innerProof,
 	}
-	return trans, &rangeProof, nil
+	return trans, &rangeProof, x
 }
 
 func (self *RangeProver) GenInnerProductProof(trans [64]byte, round uint64, a, b []*ristretto255.Scalar, u *ristretto255.Element,
@@ -501,15 +590,20 @@ func (self *RangeProver) GenInnerProductProof(trans [64]byte, round uint64, a, b
 }
 
 func (rangeProver *RangeProver) VerifySigmaRangeProof(trans [64]byte, proof *SigmaRangeProof) (tran [64]byte, yRes, zRes, xRes *ristretto255.Scalar, result bool) {
+	return rangeProver.verifyAggSigmaRangeProof(trans, proof, RANGEPROOFCOUNT)
+}
+
+func (rangeProver *RangeProver) verifyAggSigmaRangeProof(trans [64]byte, proof *SigmaRangeProof, count uint64) (tran [64]byte, yRes, zRes, xRes *ristretto255.Scalar, result bool) {
 	defer func() {
 		if err := recover(); err != nil {
 			result = false
 		}
 	}()
-	count := uint64(2)
+	if rounds, err := rangeProver.InnerProductRounds(count); err != nil || len(proof.InnerProof.Ls) != rounds {
+		return trans, nil, nil, nil, false
+	}
 	bitLen := count * rangeProver.N
-	G := DeepCopyElementList(rangeProver.GList)
-	H := DeepCopyElementList(rangeProver.HList)
+	G, H := rangeProver.aggGenerators(count)
 	//build random params
 	trans, y := UpdateTranscript(trans, proof.A, proof.S)
 
@@ -559,6 +653,9 @@ func (rangeProver *RangeProver) VerifyRangeProof(trans [64]byte, proof *RangePro
 			result = false
 		}
 	}()
+	if rounds, err := rangeProver.InnerProductRounds(1); err != nil || len(proof.InnerProof.Ls) != rounds {
+		return trans, false
+	}
 	n := rangeProver.N
 	G := DeepCopyElementList(rangeProver.GList[:n])
 	H := DeepCopyElementList(rangeProver.HList[:n])
@@ -625,7 +722,7 @@ func (self *RangeProver) VerifyInnerProductProof(trans [64]byte, n uint64, p, u
 	}()
 	//k rounds of prove iteration
 	k := len(proof.Ls)
-	if k != len(proof.Rs) {
+	if k != len(proof.Rs) || uint64(1)<<uint(k) != n {
 		return false
 	}
 
@@ -14,14 +14,29 @@ type SmartContract struct {
 	BasePoint        *ristretto255.Element
 	CommitmentMap    map[[32]byte]*Commitment
 	PublicBalanceMap map[[32]byte]uint64
+	SpendKeyMap      map[[32]byte]*ristretto255.Element
+	AuditorMap       map[[32]byte]bool
+	PendingMap       map[[32]byte]*Commitment
+	RolloverMap      map[[32]byte]uint64 //epoch of the last rollover of each account
+	Epoch            uint64
+	NullifierMap     map[[32]byte]uint64   //epoch of every used nullifier
+	FeeAccount       *ristretto255.Element //public balance credited with transfer fees
+	StealthList      []*StealthOutput      //announced one-time keys in registration order
 	rangeProver      *RangeProver
 }
 
 func (sc *SmartContract) Init() {
 	randSeed := sha256.Sum256([]byte(rangeProverXofSeed))
-	sc.rangeProver, _ = NewRangeProver(32, randSeed)
+	sc.rangeProver, _ = NewRangeProver(RANGEBITS, randSeed)
 	sc.CommitmentMap = make(map[[32]byte]*Commitment)
 	sc.PublicBalanceMap = make(map[[32]byte]uint64)
+	sc.SpendKeyMap = make(map[[32]byte]*ristretto255.Element)
+	sc.AuditorMap = make(map[[32]byte]bool)
+	sc.PendingMap = make(map[[32]byte]*Commitment)
+	sc.RolloverMap = make(map[[32]byte]uint64)
+	sc.Epoch = 0
+	sc.NullifierMap = make(map[[32]byte]uint64)
+	sc.StealthList = nil
 	sc.BasePoint = sc.rangeProver.G
 }
 
@@ -41,6 +56,10 @@ func (sc *SmartContract) Register(pk *ristretto255.Element, comm *Commitment) {
 	copy(key[:], pk.Encode([]byte{}))
 	sc.CommitmentMap[key] = comm
 	sc.PublicBalanceMap[key] = uint64(0)
+	if _, ok := sc.PendingMap[key]; !ok {
+		sc.PendingMap[key] = zeroCommitment()
+		sc.RolloverMap[key] = sc.Epoch
+	}
 }
 
 func (sc *SmartContract) VeirfyCommitmentProof(pk *ristretto255.Element, comm Commitment, proof CommitmentProof) (result bool) {
@@ -78,7 +97,12 @@ func (sc *SmartContract) VeirfyCommitmentProof(pk *ristretto255.Element, comm Co
 	return true
 }
 
-func (sc *SmartContract) VerifyBurnProof(pk *ristretto255.Element, proof CommitmentProof) (result bool) {
+//VerifyBurnProof rejects accounts with a registered spend key, use VerifyAuthorizedBurnProof for them
+func (sc *SmartContract) VerifyBurnProof(pk *ristretto255.Element, proof CommitmentProof) bool {
+	return sc.getSpendKey(pk) == nil && sc.verifyBurnProof(pk, proof)
+}
+
+func (sc *SmartContract) verifyBurnProof(pk *ristretto255.Element, proof CommitmentProof) (result bool) {
 
 	defer func() {
 		if e := recover(); e != nil {
@@ -119,7 +143,17 @@ func (sc *SmartContract) VerifyBurnProof(pk *ristretto255.Element, proof Commitm
 	return true
 }
 
-func (sc *SmartContract) VerifyTransferProof(trans [64]byte, proof *TransferProof, y, yPrime *ristretto255.Element) (result bool) {
+//VerifyTransferProof rejects senders with a registered spend key, use VerifyAuthorizedTransferProof for them
+func (sc *SmartContract) VerifyTransferProof(trans [64]byte, proof *TransferProof, y, yPrime *ristretto255.Element) bool {
+	return sc.getSpendKey(y) == nil && sc.verifyTransferProof(trans, proof, y, yPrime)
+}
+
+func (sc *SmartContract) verifyTransferProof(trans [64]byte, proof *TransferProof, y, yPrime *ristretto255.Element) bool {
+	return sc.verifyTransferProofFrom(trans, proof, y, yPrime, sc.GetCommitment(y))
+}
+
+//verifyTransferProofFrom checks the transfer against the sender commitment cOld
+func (sc *SmartContract) verifyTransferProofFrom(trans [64]byte, proof *TransferProof, y, yPrime *ristretto255.Element, cOld *Commitment) (result bool) {
 
 	defer func() {
 		if e := recover(); e != nil {
@@ -132,12 +166,17 @@ func (sc *SmartContract) VerifyTransferProof(trans [64]byte, proof *TransferProo
 		return false
 	}
 
-	trans, challenge := UpdateTranscript(trans, proof.ay, proof.ad, proof.ab, proof.ayPrime, proof.at)
+	trans, challenge := UpdateTranscript(trans, append([]*ristretto255.Element{proof.ay, proof.ad, proof.ab, proof.ayPrime, proof.at},
+		auditorTranscript(proof.Auditors)...)...)
 
 	if proof.CComm.Cr.Equal(proof.CPrimeComm.Cr) != 1 {
 		return false
 	}
 
+	if !sc.verifyAuditors(proof, y, challenge) {
+		return false
+	}
+
 	sskG := new(ristretto255.Element).ScalarMultWnaf(proof.ssk, sc.BasePoint)
 	if sskG.Equal(new(ristretto255.Element).Add(proof.ay, new(ristretto255.Element).ScalarMultWnaf(challenge, y))) != 1 {
 		return false
@@ -148,7 +187,6 @@ func (sc *SmartContract) VerifyTransferProof(trans [64]byte, proof *TransferProo
 		return false
 	}
 
-	cOld := sc.GetCommitment(y)
 	cNew := new(Commitment).Sub(cOld, &proof.CComm)
 	zz := new(ristretto255.Scalar).Multiply(z, z)
 	zzz := new(ristretto255.Scalar).Multiply(zz, z)
@@ -187,7 +225,12 @@ func (sc *SmartContract) VerifyTransferProof(trans [64]byte, proof *TransferProo
 	return true
 }
 
-func (sc *SmartContract) VerifyWithDrawProof(trans [64]byte, y *ristretto255.Element, amount uint64, proof *WithdrawProof) (result bool) {
+//VerifyWithDrawProof rejects accounts with a registered spend key, use VerifyAuthorizedWithdrawProof for them
+func (sc *SmartContract) VerifyWithDrawProof(trans [64]byte, y *ristretto255.Element, amount uint64, proof *WithdrawProof) bool {
+	return sc.getSpendKey(y) == nil && sc.verifyWithDrawProof(trans, y, amount, proof)
+}
+
+func (sc *SmartContract) verifyWithDrawProof(trans [64]byte, y *ristretto255.Element, amount uint64, proof *WithdrawProof) (result bool) {
 
 	defer func() {
 		if e := recover(); e != nil {
@@ -198,11 +241,17 @@ func (sc *SmartContract) VerifyWithDrawProof(trans [64]byte, y *ristretto255.Ele
 	comm := sc.GetCommitment(y)
 	b, _ := InttoScalar(amount)
 	commNew := new(Commitment).Sub(comm, &proof.CommWD)
-	if commNew.Cr.Equal(proof.rangeProof.H) != 1 {
+	rangeProof := *proof.rangeProof
+	if rangeProof.H == nil {
+		//compact encoding, the generators are implied by the ledger state
+		rangeProof.G = sc.rangeProver.G
+		rangeProof.H = commNew.Cr
+	}
+	if commNew.Cr.Equal(rangeProof.H) != 1 {
 		return false
 	}
 
-	trans, result = sc.rangeProver.VerifyRangeProof(trans, proof.rangeProof, commNew.Cl)
+	trans, result = sc.rangeProver.VerifyRangeProof(trans, &rangeProof, commNew.Cl)
 	if !result {
 		return false
 	}
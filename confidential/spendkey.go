@@ -0,0 +1,287 @@
+package confidential
+
+import (
+	"crypto/sha512"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Spend and view keys. The account key sk is the view key: Pk = sk*G encrypts balances and incoming
+//transfers, so sk is all that is needed to decrypt them. Moving funds additionally needs a
+//SpendAuthorization, a schnorr signature with the spend key over the proof. Once the ledger knows the
+//spend key of an account through RegisterSpendKey, it only accepts proofs of that account through the
+//VerifyAuthorized* functions, so a view key holder can no longer move funds.
+//
+//A registration is signed by sk and by the new spend key, a rotation also by the registered spend key.
+//Anyone holding sk can make the first registration, so it has to happen before the view key is shared.
+//
+//Accounts without a registered spend key keep the single key behaviour.
+
+var ErrViewOnly = errors.New("account is view-only")
+
+var spendKeyDomain = []byte("xv-crypto spend key")
+
+var registrationDomain = []byte("xv-crypto spend key registration")
+
+var ErrSpendKeyRegistration = errors.New("spend key registration is not authorized")
+
+type SpendAuthorization struct {
+	R *ristretto255.Element
+	S *ristretto255.Scalar
+}
+
+func (auth *SpendAuthorization) Serialization(sink *ZeroCopySink) {
+	sink.WriteFixedElement(auth.R)
+	sink.WriteFixedScalar(auth.S)
+}
+
+func (auth *SpendAuthorization) Deserialization(source *ZeroCopySource) error {
+	var err error
+	if auth.R, err = source.NextFixedElement(); err != nil {
+		return err
+	}
+	auth.S, err = source.NextFixedScalar()
+	return err
+}
+
+func (auth *SpendAuthorization) Serialize() []byte {
+	return serialize(auth)
+}
+
+func (auth *SpendAuthorization) Deserialize(b []byte) error {
+	return deserialize(auth, b)
+}
+
+//spendMessage binds the authorization to the proof, its kind and the transcript it was generated with.
+//The compact encoding leaves out the generators the verifier fills in, so the message is the same for a
+//proof and its copy decoded from any encoding.
+func spendMessage(trans [64]byte, proof Proof) []byte {
+	msg := append(trans[:], byte(proof.Kind()))
+	return append(msg, proof.SerializeCompact()...)
+}
+
+func schnorrChallenge(domain []byte, r, spendPk *ristretto255.Element, msg []byte) *ristretto255.Scalar {
+	h := sha512.New()
+	h.Write(domain)
+	h.Write(r.Encode(nil))
+	h.Write(spendPk.Encode(nil))
+	h.Write(msg)
+	return new(ristretto255.Scalar).FromUniformBytes(h.Sum(nil))
+}
+
+//deriveSpendKey derives the spend key from the account seed, the view key does not reveal it
+func deriveSpendKey(seed []byte) *ristretto255.Scalar {
+	h := sha512.New()
+	h.Write(spendKeyDomain)
+	h.Write(seed)
+	return new(ristretto255.Scalar).FromUniformBytes(h.Sum(nil))
+}
+
+func (acc *Account) setSpendKey(spendSk *ristretto255.Scalar) {
+	acc.spendSk = spendSk
+	acc.SpendPk = acc.mult(spendSk, acc.basePoint)
+}
+
+//CanSpend reports whether the account holds a spend key
+func (acc *Account) CanSpend() bool {
+	return acc.spendSk != nil
+}
+
+//ExportViewKey returns the key that decrypts balances and incoming transfers
+func (acc *Account) ExportViewKey() [32]byte {
+	return acc.ExportSk()
+}
+
+func (acc *Account) ExportSpendKey() ([32]byte, error) {
+	if !acc.CanSpend() {
+		return [32]byte{}, ErrViewOnly
+	}
+	return ScalarToBytes(acc.spendSk), nil
+}
+
+//ImportViewKey creates a view-only account, it decrypts balances and transfers but refuses to generate
+//transfer and withdraw proofs
+func (acc *Account) ImportViewKey(viewKey [32]byte) error {
+	if err := acc.ImportSk(viewKey); err != nil {
+		return err
+	}
+	acc.viewOnly = true
+	return nil
+}
+
+//ImportSpendKey adds the spend key to an account restored with ImportSk or ImportViewKey
+func (acc *Account) ImportSpendKey(spendKey [32]byte) error {
+	spendSk, err := ScalarFromBytes(spendKey)
+	if err != nil {
+		return err
+	}
+	if spendSk.Equal(new(ristretto255.Scalar).Zero()) == 1 {
+		return errors.New("spend key is zero")
+	}
+	acc.setSpendKey(spendSk)
+	acc.viewOnly = false
+	return nil
+}
+
+//AuthorizeSpend signs a transfer, withdraw or burn proof with the spend key. trans is the transcript
+//the proof was generated with, burn proofs use a zero transcript.
+func (acc *Account) AuthorizeSpend(trans [64]byte, proof Proof) (*SpendAuthorization, error) {
+	if !acc.CanSpend() {
+		return nil, ErrViewOnly
+	}
+	return acc.schnorrSign(spendKeyDomain, acc.spendSk, acc.SpendPk, spendMessage(trans, proof)), nil
+}
+
+func (acc *Account) schnorrSign(domain []byte, sk *ristretto255.Scalar, pk *ristretto255.Element, msg []byte) *SpendAuthorization {
+	k := acc.RandScalar()
+	r := acc.mult(k, acc.basePoint)
+	c := schnorrChallenge(domain, r, pk, msg)
+	return &SpendAuthorization{
+		R: r,
+		S: SumScalars(k, Mul(c, sk)),
+	}
+}
+
+//SpendKeyRegistration registers SpendPk for an account. Account is signed by the account key and Spend by
+//the new spend key, Rotation by the spend key registered so far and nil for the first registration.
+type SpendKeyRegistration struct {
+	SpendPk  *ristretto255.Element
+	Account  *SpendAuthorization
+	Spend    *SpendAuthorization
+	Rotation *SpendAuthorization
+}
+
+//registrationMessage binds the account, the spend key it replaces, nil for none, and the new spend key
+func registrationMessage(pk, current, spendPk *ristretto255.Element) []byte {
+	msg := pk.Encode(nil)
+	if current != nil {
+		msg = current.Encode(msg)
+	} else {
+		msg = append(msg, make([]byte, 32)...)
+	}
+	return spendPk.Encode(msg)
+}
+
+//GenSpendKeyRegistration signs the first registration of the spend key of the account
+func (acc *Account) GenSpendKeyRegistration() (*SpendKeyRegistration, error) {
+	if !acc.CanSpend() || acc.viewOnly {
+		return nil, ErrViewOnly
+	}
+	msg := registrationMessage(acc.Pk, nil, acc.SpendPk)
+	return &SpendKeyRegistration{
+		SpendPk: acc.SpendPk,
+		Account: acc.schnorrSign(registrationDomain, acc.sk, acc.Pk, msg),
+		Spend:   acc.schnorrSign(registrationDomain, acc.spendSk, acc.SpendPk, msg),
+	}, nil
+}
+
+//GenSpendKeyRotation signs the replacement of the registered spend key of the account with spendKey,
+//the account keeps its current spend key until ImportSpendKey is called with the new one
+func (acc *Account) GenSpendKeyRotation(spendKey [32]byte) (*SpendKeyRegistration, error) {
+	if !acc.CanSpend() || acc.viewOnly {
+		return nil, ErrViewOnly
+	}
+	spendSk, err := ScalarFromBytes(spendKey)
+	if err != nil {
+		return nil, err
+	}
+	if spendSk.Equal(new(ristretto255.Scalar).Zero()) == 1 {
+		return nil, errors.New("spend key is zero")
+	}
+	spendPk := acc.mult(spendSk, acc.basePoint)
+	msg := registrationMessage(acc.Pk, acc.SpendPk, spendPk)
+	return &SpendKeyRegistration{
+		SpendPk:  spendPk,
+		Account:  acc.schnorrSign(registrationDomain, acc.sk, acc.Pk, msg),
+		Spend:    acc.schnorrSign(registrationDomain, spendSk, spendPk, msg),
+		Rotation: acc.schnorrSign(registrationDomain, acc.spendSk, acc.SpendPk, msg),
+	}, nil
+}
+
+//DecryptTransfer returns the amount of a transfer to this account, it only needs the view key
+func (acc *Account) DecryptTransfer(proof *TransferProof) (uint64, error) {
+	return acc.decryptAmount(proof.CPrimeComm.Cl, proof.CPrimeComm.Cr)
+}
+
+func (acc *Account) decryptAmount(cl, cr *ristretto255.Element) (uint64, error) {
+	vEncrypt := new(ristretto255.Element).Add(cl, new(ristretto255.Element).Negate(acc.mult(acc.sk, cr)))
+	var v *ristretto255.Scalar
+	if acc.constantTime {
+		v = GuessValueConstantTime(vEncrypt, acc.basePoint, acc.upper)
+	} else {
+		v = GuessValue(vEncrypt, acc.basePoint, acc.upper)
+	}
+	if v == nil {
+		return 0, errors.New("ciphertext is not addressed to this account or the amount is out of range")
+	}
+	return ScalartoInt(v), nil
+}
+
+//RegisterSpendKey makes the spend key of reg mandatory for every transfer, withdraw and burn proof of pk.
+//The first registration needs the signatures of pk and the spend key, replacing a registered spend key
+//also needs the signature of the registered one.
+func (sc *SmartContract) RegisterSpendKey(pk *ristretto255.Element, reg *SpendKeyRegistration) error {
+	if reg == nil || reg.SpendPk == nil || reg.SpendPk.Equal(new(ristretto255.Element).Zero()) == 1 {
+		return ErrSpendKeyRegistration
+	}
+	current := sc.getSpendKey(pk)
+	msg := registrationMessage(pk, current, reg.SpendPk)
+	if !sc.verifySchnorr(registrationDomain, pk, msg, reg.Account) || !sc.verifySchnorr(registrationDomain, reg.SpendPk, msg, reg.Spend) {
+		return ErrSpendKeyRegistration
+	}
+	if current != nil && !sc.verifySchnorr(registrationDomain, current, msg, reg.Rotation) {
+		return ErrSpendKeyRegistration
+	}
+	var key [32]byte
+	copy(key[:], pk.Encode(nil))
+	sc.SpendKeyMap[key] = reg.SpendPk
+	return nil
+}
+
+func (sc *SmartContract) getSpendKey(pk *ristretto255.Element) *ristretto255.Element {
+	var key [32]byte
+	copy(key[:], pk.Encode(nil))
+	return sc.SpendKeyMap[key]
+}
+
+func (sc *SmartContract) verifySpendAuthorization(trans [64]byte, pk *ristretto255.Element, proof Proof, auth *SpendAuthorization) (result bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			result = false
+		}
+	}()
+
+	spendPk := sc.getSpendKey(pk)
+	if spendPk == nil {
+		return false
+	}
+	return sc.verifySchnorr(spendKeyDomain, spendPk, spendMessage(trans, proof), auth)
+}
+
+func (sc *SmartContract) verifySchnorr(domain []byte, pk *ristretto255.Element, msg []byte, auth *SpendAuthorization) (result bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			result = false
+		}
+	}()
+
+	if auth == nil || auth.R == nil || auth.S == nil {
+		return false
+	}
+	c := schnorrChallenge(domain, auth.R, pk, msg)
+	left := new(ristretto255.Element).ScalarMultWnaf(auth.S, sc.BasePoint)
+	right := SumElements(auth.R, new(ristretto255.Element).ScalarMultWnaf(c, pk))
+	return left.Equal(right) == 1
+}
+
+func (sc *SmartContract) VerifyAuthorizedTransferProof(trans [64]byte, proof *TransferProof, auth *SpendAuthorization, y, yPrime *ristretto255.Element) bool {
+	return sc.verifySpendAuthorization(trans, y, proof, auth) && sc.verifyTransferProof(trans, proof, y, yPrime)
+}
+
+func (sc *SmartContract) VerifyAuthorizedWithdrawProof(trans [64]byte, y *ristretto255.Element, amount uint64, proof *WithdrawProof, auth *SpendAuthorization) bool {
+	return sc.verifySpendAuthorization(trans, y, proof, auth) && sc.verifyWithDrawProof(trans, y, amount, proof)
+}
+
+func (sc *SmartContract) VerifyAuthorizedBurnProof(pk *ristretto255.Element, proof CommitmentProof, auth *SpendAuthorization) bool {
+	return sc.verifySpendAuthorization([64]byte{}, pk, &proof, auth) && sc.verifyBurnProof(pk, proof)
+}
@@ -0,0 +1,154 @@
+package confidential
+
+import (
+	"crypto/sha512"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Stealth one-time keys. A stealth address is the pair (Pk, SpendPk) of an account. The sender picks an
+//ephemeral key e, publishes R = e*G and pays to the one-time key P = h*G + SpendPk with h = H(e*Pk, R).
+//The view key finds the payments, h = H(sk*R) and P = h*G + SpendPk, while only the spend key recovers
+//the one-time secret key h + spendSk. The ledger registers P when the first transfer to it is applied,
+//so payments to the same address are not linkable on the ledger.
+//The amount of a stealth transfer is encrypted under P, which only the spend key opens. The sender also
+//seals a hint of the amount to the view key, SealAmountHint(addr.ViewPk, ...), so a view-only wallet
+//reads what it received with OpenStealthAmount.
+
+var ErrNotStealthRecipient = errors.New("stealth output does not belong to this account")
+
+var stealthDomain = []byte("xv-crypto stealth")
+
+type StealthAddress struct {
+	ViewPk  *ristretto255.Element
+	SpendPk *ristretto255.Element
+}
+
+//StealthOutput announces a one-time key, R is published with every stealth transfer
+type StealthOutput struct {
+	R         *ristretto255.Element
+	OneTimePk *ristretto255.Element
+}
+
+func stealthScalar(shared, r *ristretto255.Element) *ristretto255.Scalar {
+	h := sha512.New()
+	h.Write(stealthDomain)
+	h.Write(shared.Encode(nil))
+	h.Write(r.Encode(nil))
+	return new(ristretto255.Scalar).FromUniformBytes(h.Sum(nil))
+}
+
+//StealthAddress returns the address senders derive one-time keys of this account from
+func (acc *Account) StealthAddress() (*StealthAddress, error) {
+	if acc.SpendPk == nil {
+		return nil, ErrViewOnly
+	}
+	return &StealthAddress{ViewPk: acc.Pk, SpendPk: acc.SpendPk}, nil
+}
+
+//GenStealthOutput derives a fresh one-time key of addr, transfers are then made to OneTimePk
+func (acc *Account) GenStealthOutput(addr *StealthAddress) *StealthOutput {
+	e := acc.RandScalar()
+	r := acc.mult(e, acc.basePoint)
+	h := stealthScalar(acc.mult(e, addr.ViewPk), r)
+	return &StealthOutput{
+		R:         r,
+		OneTimePk: new(ristretto255.Element).Add(acc.mult(h, acc.basePoint), addr.SpendPk),
+	}
+}
+
+func (acc *Account) stealthScalar(out *StealthOutput) (*ristretto255.Scalar, bool) {
+	if acc.SpendPk == nil {
+		return nil, false
+	}
+	h := stealthScalar(acc.mult(acc.sk, out.R), out.R)
+	p := new(ristretto255.Element).Add(acc.mult(h, acc.basePoint), acc.SpendPk)
+	return h, p.Equal(out.OneTimePk) == 1
+}
+
+//ScanStealth reports whether out pays this account. It only needs the view key and SpendPk, a view-only
+//account scans once SpendPk is set to the one of the address.
+func (acc *Account) ScanStealth(out *StealthOutput) bool {
+	_, ok := acc.stealthScalar(out)
+	return ok
+}
+
+//OpenStealthAmount returns the amount of a hint sealed to the view key for a transfer to out with the
+//ciphertext comm. The view key can not check the amount against comm, Cl - h*Cr still hides r*SpendPk,
+//so it is only as honest as the sender. The spend key confirms it with DecryptCommitment of the account
+//returned by StealthAccount.
+func (acc *Account) OpenStealthAmount(out *StealthOutput, comm *Commitment, hint *AmountHint) (uint64, bool) {
+	if !acc.ScanStealth(out) {
+		return 0, false
+	}
+	return acc.hintAmount(comm, hint)
+}
+
+//StealthAccount recovers the account of the one-time key, it generates transfer and withdraw proofs
+//like any other account. Comm is a commitment of zero and has to be replaced with the ledger state.
+func (acc *Account) StealthAccount(out *StealthOutput) (*Account, error) {
+	if !acc.CanSpend() {
+		return nil, ErrViewOnly
+	}
+	h, ok := acc.stealthScalar(out)
+	if !ok {
+		return nil, ErrNotStealthRecipient
+	}
+	oneTime := new(Account)
+	if err := oneTime.ImportSk(ScalarToBytes(SumScalars(h, acc.spendSk))); err != nil {
+		return nil, err
+	}
+	oneTime.SetConstantTime(acc.constantTime)
+	oneTime.SetDecryptionBound(acc.upper)
+	return oneTime, nil
+}
+
+//StealthOutputs returns the announced outputs in the order they were registered, wallets scan them
+func (sc *SmartContract) StealthOutputs() []*StealthOutput {
+	sc.Mu.RLock()
+	defer sc.Mu.RUnlock()
+	return append([]*StealthOutput{}, sc.StealthList...)
+}
+
+//ApplyStealthTransfer applies a transfer to out.OneTimePk like ApplyTransfer. A one-time key seen for
+//the first time is registered with a zero balance and announced, nothing is registered if the transfer
+//is rejected.
+func (sc *SmartContract) ApplyStealthTransfer(trans [64]byte, proof *TransferProof, auth *SpendAuthorization, nullifier *Nullifier, y *ristretto255.Element, out *StealthOutput) bool {
+	sc.Mu.Lock()
+	defer sc.Mu.Unlock()
+	if out == nil || out.R == nil || out.OneTimePk == nil {
+		return false
+	}
+	key := pkKey(out.OneTimePk)
+	if _, ok := sc.CommitmentMap[key]; ok {
+		return sc.applyTransfer(trans, proof, auth, nullifier, y, out.OneTimePk)
+	}
+	sc.Register(out.OneTimePk, zeroCommitment())
+	if !sc.applyTransfer(trans, proof, auth, nullifier, y, out.OneTimePk) {
+		delete(sc.CommitmentMap, key)
+		delete(sc.PublicBalanceMap, key)
+		delete(sc.PendingMap, key)
+		delete(sc.RolloverMap, key)
+		return false
+	}
+	sc.StealthList = append(sc.StealthList, out)
+	return true
+}
+
+//R||OneTimePk
+func (out *StealthOutput) Serialization(sink *ZeroCopySink) {
+	sink.WriteFixedElement(out.R)
+	sink.WriteFixedElement(out.OneTimePk)
+}
+
+func (out *StealthOutput) Deserialization(source *ZeroCopySource) error {
+	return nextFixedElements(source, &out.R, &out.OneTimePk)
+}
+
+func (out *StealthOutput) Serialize() []byte {
+	return serialize(out)
+}
+
+func (out *StealthOutput) Deserialize(b []byte) error {
+	return deserialize(out, b)
+}
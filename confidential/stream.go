@@ -0,0 +1,125 @@
+package confidential
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+)
+
+//MaxProofPayloadSize bounds the payload of a single envelope in a stream, the largest proof,
+//a legacy TransferProof, is about 1.1KB
+const MaxProofPayloadSize = 1 << 12
+
+var ErrProofTooLarge = errors.New("proof payload exceeds MaxProofPayloadSize")
+
+//ProofStreamError reports the byte offset of the envelope that failed to decode
+type ProofStreamError struct {
+	Offset uint64
+	Err    error
+}
+
+func (e *ProofStreamError) Error() string {
+	return "proof at offset " + strconv.FormatUint(e.Offset, 10) + ": " + e.Err.Error()
+}
+
+//ProofReader decodes a sequence of proof envelopes, as written by EncodeProof, one at a time.
+//Only one payload is buffered at any time, so a block of proofs can be streamed from disk or network.
+type ProofReader struct {
+	r       *bufio.Reader
+	offset  uint64
+	payload []byte
+}
+
+func NewProofReader(r io.Reader) *ProofReader {
+	return &ProofReader{
+		r:       bufio.NewReader(r),
+		payload: make([]byte, 0, MaxProofPayloadSize),
+	}
+}
+
+//Offset returns the number of bytes consumed so far, the offset of the next envelope
+func (self *ProofReader) Offset() uint64 {
+	return self.offset
+}
+
+//Next returns the next proof, or io.EOF once the stream ends on an envelope boundary.
+//Any other error is a *ProofStreamError, the reader can not be used after it.
+func (self *ProofReader) Next() (Proof, error) {
+	start := self.offset
+	proof, err := self.next()
+	if err == io.EOF && self.offset == start {
+		return nil, io.EOF
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, &ProofStreamError{Offset: start, Err: err}
+	}
+	return proof, nil
+}
+
+func (self *ProofReader) next() (Proof, error) {
+	var head [14]byte
+	if err := self.readFull(head[:]); err != nil {
+		return nil, err
+	}
+	header, err := readProofHeader(NewZeroCopySource(head[:]))
+	if err != nil {
+		return nil, err
+	}
+	if err := header.check(); err != nil {
+		return nil, err
+	}
+	size, err := self.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	if size > MaxProofPayloadSize {
+		return nil, ErrProofTooLarge
+	}
+	self.payload = self.payload[:size]
+	if err := self.readFull(self.payload); err != nil {
+		return nil, err
+	}
+	return decodePayload(header, self.payload)
+}
+
+func (self *ProofReader) readFull(b []byte) error {
+	n, err := io.ReadFull(self.r, b)
+	self.offset += uint64(n)
+	if err == io.ErrUnexpectedEOF {
+		return io.EOF
+	}
+	return err
+}
+
+//readVarUint reads the length prefix written by ZeroCopySink.WriteVarUint
+func (self *ProofReader) readVarUint() (uint64, error) {
+	var fb [1]byte
+	if err := self.readFull(fb[:]); err != nil {
+		return 0, err
+	}
+	var size int
+	switch fb[0] {
+	case 0xFD:
+		size = 2
+	case 0xFE:
+		size = 4
+	case 0xFF:
+		size = 8
+	default:
+		return uint64(fb[0]), nil
+	}
+	var buf [8]byte
+	if err := self.readFull(buf[:size]); err != nil {
+		return 0, err
+	}
+	value := binary.LittleEndian.Uint64(buf[:])
+	if getVarUintSize(value) != uint64(size+1) {
+		return 0, ErrIrregularData
+	}
+	return value, nil
+}
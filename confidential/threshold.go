@@ -0,0 +1,283 @@
+package confidential
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+	"io"
+	"sort"
+)
+
+//Threshold decryption. An auditor secret key is shamir shared over n parties, any t of them decrypt a
+//Commitment together: party i publishes Di = ski*Cr with a DLEQ proof that log_G(VKi) = log_Cr(Di),
+//and the Lagrange combination of t valid shares is sk*Cr. No coalition below t learns sk or the amounts.
+
+//MaxThresholdParties bounds n, share indexes are 1..n
+const MaxThresholdParties = 255
+
+var (
+	ErrThresholdParams = errors.New("threshold must satisfy 1 <= t <= n <= MaxThresholdParties")
+	ErrNotEnoughShares = errors.New("not enough valid decryption shares")
+)
+
+var dleqDomain = []byte("xv-crypto dleq")
+
+//ThresholdKey is the public part of a shared key, VerificationKeys[i-1] = ski*G for party i
+type ThresholdKey struct {
+	T, N             int
+	Pk               *ristretto255.Element
+	VerificationKeys []*ristretto255.Element
+}
+
+//KeyShare is the secret share of party Index
+type KeyShare struct {
+	Index uint32
+	sk    *ristretto255.Scalar
+}
+
+type DecryptionShare struct {
+	Index uint32
+	D     *ristretto255.Element //ski*Cr
+	Proof DLEQProof
+}
+
+//DLEQProof shows log_g1(h1) = log_g2(h2) without revealing it
+type DLEQProof struct {
+	c, s *ristretto255.Scalar
+}
+
+func randomScalar(random io.Reader) (*ristretto255.Scalar, error) {
+	buf := make([]byte, 64)
+	if _, err := io.ReadFull(random, buf); err != nil {
+		return nil, err
+	}
+	return new(ristretto255.Scalar).FromUniformBytes(buf), nil
+}
+
+//evalPolynomial returns coeffs[0] + coeffs[1]*x + ... + coeffs[t-1]*x^(t-1)
+func evalPolynomial(coeffs []*ristretto255.Scalar, x uint32) *ristretto255.Scalar {
+	xScalar, _ := InttoScalar(uint64(x))
+	result := new(ristretto255.Scalar).Zero()
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		result = SumScalars(Mul(result, xScalar), coeffs[i])
+	}
+	return result
+}
+
+//lagrangeCoefficient returns the Lagrange basis polynomial of index i over indexes, evaluated at 0
+func lagrangeCoefficient(indexes []uint32, i uint32) *ristretto255.Scalar {
+	num, _ := InttoScalar(uint64(1))
+	den, _ := InttoScalar(uint64(1))
+	iScalar, _ := InttoScalar(uint64(i))
+	for _, j := range indexes {
+		if j == i {
+			continue
+		}
+		jScalar, _ := InttoScalar(uint64(j))
+		num = Mul(num, jScalar)
+		den = Mul(den, new(ristretto255.Scalar).Subtract(jScalar, iScalar))
+	}
+	return Mul(num, new(ristretto255.Scalar).Invert(den))
+}
+
+func dleqChallenge(g1, h1, g2, h2, a1, a2 *ristretto255.Element) *ristretto255.Scalar {
+	_, c := UpdateTranscript(sha512.Sum512(dleqDomain), g1, h1, g2, h2, a1, a2)
+	return c
+}
+
+//proveDLEQ proves h1 = x*g1 and h2 = x*g2, the nonce is derived from x and the statement
+func proveDLEQ(x *ristretto255.Scalar, g1, h1, g2, h2 *ristretto255.Element) DLEQProof {
+	h := sha512.New()
+	h.Write(dleqDomain)
+	h.Write(x.Encode(nil))
+	for _, e := range []*ristretto255.Element{g1, h1, g2, h2} {
+		h.Write(e.Encode(nil))
+	}
+	k := new(ristretto255.Scalar).FromUniformBytes(h.Sum(nil))
+	a1 := new(ristretto255.Element).ScalarMult(k, g1)
+	a2 := new(ristretto255.Element).ScalarMult(k, g2)
+	c := dleqChallenge(g1, h1, g2, h2, a1, a2)
+	return DLEQProof{
+		c: c,
+		s: SumScalars(k, Mul(c, x)),
+	}
+}
+
+func verifyDLEQ(proof DLEQProof, g1, h1, g2, h2 *ristretto255.Element) (result bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			result = false
+		}
+	}()
+
+	negC := new(ristretto255.Scalar).Negate(proof.c)
+	a1 := new(ristretto255.Element).VarTimeMultiScalarMult([]*ristretto255.Scalar{proof.s, negC}, []*ristretto255.Element{g1, h1})
+	a2 := new(ristretto255.Element).VarTimeMultiScalarMult([]*ristretto255.Scalar{proof.s, negC}, []*ristretto255.Element{g2, h2})
+	return dleqChallenge(g1, h1, g2, h2, a1, a2).Equal(proof.c) == 1
+}
+
+func (proof *DLEQProof) Serialization(sink *ZeroCopySink) {
+	sink.WriteFixedScalar(proof.c)
+	sink.WriteFixedScalar(proof.s)
+}
+
+func (proof *DLEQProof) Deserialization(source *ZeroCopySource) error {
+	return nextFixedScalars(source, &proof.c, &proof.s)
+}
+
+//SplitKey shamir shares secret into n shares, any t of them decrypt. The dealer learns every share, use
+//the distributed key generation when no single party may know the key.
+func SplitKey(secret [32]byte, t, n int) (*ThresholdKey, []*KeyShare, error) {
+	sk, err := ScalarFromBytes(secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	return splitKey(sk, t, n, rand.Reader)
+}
+
+func splitKey(sk *ristretto255.Scalar, t, n int, random io.Reader) (*ThresholdKey, []*KeyShare, error) {
+	if t < 1 || t > n || n > MaxThresholdParties {
+		return nil, nil, ErrThresholdParams
+	}
+	coeffs := []*ristretto255.Scalar{sk}
+	for i := 1; i < t; i++ {
+		coeff, err := randomScalar(random)
+		if err != nil {
+			return nil, nil, err
+		}
+		coeffs = append(coeffs, coeff)
+	}
+	basePoint := hdBasePoint()
+	key := &ThresholdKey{
+		T:  t,
+		N:  n,
+		Pk: new(ristretto255.Element).ScalarMult(sk, basePoint),
+	}
+	shares := make([]*KeyShare, n)
+	for i := range shares {
+		shares[i] = &KeyShare{Index: uint32(i + 1), sk: evalPolynomial(coeffs, uint32(i+1))}
+		key.VerificationKeys = append(key.VerificationKeys, new(ristretto255.Element).ScalarMult(shares[i].sk, basePoint))
+	}
+	return key, shares, nil
+}
+
+func (share *KeyShare) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint32(share.Index)
+	sink.WriteFixedScalar(share.sk)
+}
+
+func (share *KeyShare) Deserialization(source *ZeroCopySource) error {
+	var eof bool
+	share.Index, eof = source.NextUint32()
+	if eof {
+		return ErrProofLength
+	}
+	if share.Index == 0 || share.Index > MaxThresholdParties {
+		return errors.New("invalid share index")
+	}
+	var err error
+	share.sk, err = source.NextFixedScalar()
+	return err
+}
+
+func (share *KeyShare) Serialize() []byte {
+	return serialize(share)
+}
+
+func (share *KeyShare) Deserialize(b []byte) error {
+	return deserialize(share, b)
+}
+
+//DecryptionShare returns Di = ski*Cr and its DLEQ proof against the verification key of the share
+func (share *KeyShare) DecryptionShare(comm *Commitment) *DecryptionShare {
+	basePoint := hdBasePoint()
+	d := new(ristretto255.Element).ScalarMult(share.sk, comm.Cr)
+	vk := new(ristretto255.Element).ScalarMult(share.sk, basePoint)
+	return &DecryptionShare{
+		Index: share.Index,
+		D:     d,
+		Proof: proveDLEQ(share.sk, basePoint, vk, comm.Cr, d),
+	}
+}
+
+func (share *DecryptionShare) Serialization(sink *ZeroCopySink) {
+	sink.WriteUint32(share.Index)
+	sink.WriteFixedElement(share.D)
+	share.Proof.Serialization(sink)
+}
+
+func (share *DecryptionShare) Deserialization(source *ZeroCopySource) error {
+	var eof bool
+	share.Index, eof = source.NextUint32()
+	if eof {
+		return ErrProofLength
+	}
+	var err error
+	if share.D, err = source.NextFixedElement(); err != nil {
+		return err
+	}
+	return share.Proof.Deserialization(source)
+}
+
+func (share *DecryptionShare) Serialize() []byte {
+	return serialize(share)
+}
+
+func (share *DecryptionShare) Deserialize(b []byte) error {
+	return deserialize(share, b)
+}
+
+//VerifyShare checks the DLEQ proof of a decryption share of comm
+func (key *ThresholdKey) VerifyShare(comm *Commitment, share *DecryptionShare) bool {
+	if share == nil || share.D == nil || share.Index == 0 || int(share.Index) > len(key.VerificationKeys) {
+		return false
+	}
+	return verifyDLEQ(share.Proof, hdBasePoint(), key.VerificationKeys[share.Index-1], comm.Cr, share.D)
+}
+
+//Combine decrypts comm from at least T shares, invalid and duplicate shares are skipped. The amount is
+//searched below upper like GetCommitmentBalance.
+func (key *ThresholdKey) Combine(comm *Commitment, shares []*DecryptionShare, upper uint64) (uint64, error) {
+	skCr, err := key.combineShares(comm, shares)
+	if err != nil {
+		return 0, err
+	}
+	vEncrypt := new(ristretto255.Element).Add(comm.Cl, new(ristretto255.Element).Negate(skCr))
+	v := GuessValue(vEncrypt, hdBasePoint(), upper)
+	if v == nil {
+		return 0, errors.New("amount is out of range")
+	}
+	return ScalartoInt(v), nil
+}
+
+//combineShares returns sk*Cr from the first T valid shares, nil shares are skipped
+func (key *ThresholdKey) combineShares(comm *Commitment, shares []*DecryptionShare) (*ristretto255.Element, error) {
+	valid := make(map[uint32]*DecryptionShare)
+	for _, share := range shares {
+		if share == nil {
+			continue
+		}
+		if _, ok := valid[share.Index]; !ok && key.VerifyShare(comm, share) {
+			valid[share.Index] = share
+		}
+		if len(valid) == key.T {
+			break
+		}
+	}
+	if len(valid) < key.T {
+		return nil, ErrNotEnoughShares
+	}
+	indexes := make([]uint32, 0, len(valid))
+	for index := range valid {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	var coeffs []*ristretto255.Scalar
+	var ds []*ristretto255.Element
+	for _, index := range indexes {
+		coeffs = append(coeffs, lagrangeCoefficient(indexes, index))
+		ds = append(ds, valid[index].D)
+	}
+	return new(ristretto255.Element).VarTimeMultiScalarMult(coeffs, ds), nil
+}
@@ -132,6 +132,14 @@ func ScalarMultArray(scalar *ristretto255.Scalar, elements []*ristretto255.Eleme
 	return result
 }
 
+//ScalarMultSelect computes s*p in constant time when ct is set, otherwise with the faster variable-time wnaf method.
+func ScalarMultSelect(ct bool, s *ristretto255.Scalar, p *ristretto255.Element) *ristretto255.Element {
+	if ct {
+		return new(ristretto255.Element).ScalarMult(s, p)
+	}
+	return new(ristretto255.Element).ScalarMultWnaf(s, p)
+}
+
 func Square(s *ristretto255.Scalar) *ristretto255.Scalar {
 	return new(ristretto255.Scalar).Multiply(s, s)
 }
@@ -220,20 +228,18 @@ func UpdateTranscript(trans [64]byte, pt ...*ristretto255.Element) ([64]byte, *r
 	return trans, new(ristretto255.Scalar).FromUniformBytes(trans[:])
 }
 
+//GenBitVector returns the l low bits of n, least significant first. The range provers decompose secret
+//amounts with it, so it must not branch on the bits.
 func GenBitVector(n, l uint64) []uint64 {
 	bitVector := make([]uint64, l, l)
-	temp := uint64(1)
 	for i := uint64(0); i < l; i++ {
-		if n&temp == uint64(0) {
-			bitVector[i] = 0
-		} else {
-			bitVector[i] = 1
-		}
-		temp = temp << 1
+		bitVector[i] = (n >> i) & 1
 	}
 	return bitVector
 }
 
+//The canonical check of Decode only compares the top limb, which is zero for every uint64, so
+//InttoScalar never fails and takes the same time for every n.
 func InttoScalar(n uint64) (*ristretto255.Scalar, error) {
 	encode := Uint64ToBytes(n)
 	scalar := new(ristretto255.Scalar)
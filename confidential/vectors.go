@@ -0,0 +1,275 @@
+package confidential
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"github.com/Evanesco-Labs/ristretto255"
+)
+
+//Known-answer vectors for other implementations. Every seed is read from one XofExpend keyed
+//with vectorSeed, and Init accounts derive all their randomness from their seed, including the
+//nonces of their range prover, so the generator output is byte-exact and stable as long as the
+//proof systems do not change.
+
+var vectorSeed = []byte("xv-crypto known answer vectors")
+
+const (
+	VectorTransfer = "transfer"
+	VectorWithdraw = "withdraw"
+	VectorBurn     = "burn"
+	VectorRange    = "range"
+)
+
+type TestVector struct {
+	Name         string            `json:"name"`
+	SenderSeed   string            `json:"sender_seed"`
+	ReceiverSeed string            `json:"receiver_seed,omitempty"`
+	Balance      uint64            `json:"balance"`
+	Amount       uint64            `json:"amount"`
+	SenderSk     string            `json:"sender_sk"`
+	SenderPk     string            `json:"sender_pk"`
+	ReceiverPk   string            `json:"receiver_pk,omitempty"`
+	SenderComm   *Commitment       `json:"sender_comm,omitempty"`
+	ReceiverComm *Commitment       `json:"receiver_comm,omitempty"`
+	Transcript   string            `json:"transcript"`
+	Transcripts  map[string]string `json:"transcripts"`
+	Challenges   map[string]string `json:"challenges"`
+	Proof        string            `json:"proof"`
+	ProofCompact string            `json:"proof_compact"`
+}
+
+//GenerateTestVectors builds one vector for each of GenTransferProof, GenWithdrawProof,
+//GenBurnProof and RangeProver.GenRangeProof.
+func GenerateTestVectors() ([]TestVector, error) {
+	xof := NewXofExpend(64, sha256.Sum256(vectorSeed))
+	nextSeed := func() [32]byte {
+		var seed [32]byte
+		xof.Read(seed[:])
+		return seed
+	}
+	var vectors []TestVector
+	for _, name := range []string{VectorTransfer, VectorWithdraw, VectorBurn, VectorRange} {
+		vector, err := generateTestVector(name, nextSeed(), nextSeed(), uint64(1000), uint64(250))
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+func generateTestVector(name string, senderSeed, receiverSeed [32]byte, balance, amount uint64) (TestVector, error) {
+	var sender Account
+	sender.Init(senderSeed)
+	v, _ := InttoScalar(balance)
+	_, comm := sender.Commit(v)
+	sender.Comm = &comm
+	trans := sha512.Sum512(senderSeed[:])
+
+	vector := TestVector{
+		Name:        name,
+		SenderSeed:  hex.EncodeToString(senderSeed[:]),
+		Balance:     balance,
+		Amount:      amount,
+		SenderSk:    scalarToHex(sender.sk),
+		SenderPk:    elementToHex(sender.Pk),
+		SenderComm:  &comm,
+		Transcript:  hex.EncodeToString(trans[:]),
+		Transcripts: make(map[string]string),
+		Challenges:  make(map[string]string),
+	}
+
+	var proof Proof
+	switch name {
+	case VectorTransfer:
+		var receiver Account
+		receiver.Init(receiverSeed)
+		vector.ReceiverSeed = hex.EncodeToString(receiverSeed[:])
+		vector.ReceiverPk = elementToHex(receiver.Pk)
+		vector.ReceiverComm = receiver.Comm
+		transferProof, err := sender.GenTransferProof(trans, amount, receiver.Pk)
+		if err != nil {
+			return vector, err
+		}
+		after, y, z, x := rangeTranscript(trans, transferProof.sigmaRangeProof.A, transferProof.sigmaRangeProof.S,
+			transferProof.sigmaRangeProof.T1, transferProof.sigmaRangeProof.T2)
+		final, c := UpdateTranscript(after, transferProof.ay, transferProof.ad, transferProof.ab, transferProof.ayPrime, transferProof.at)
+		vector.setChallenges(after, final, y, z, x, c)
+		proof = transferProof
+	case VectorWithdraw:
+		withdrawProof, err := sender.GenWithdrawProof(trans, amount)
+		if err != nil {
+			return vector, err
+		}
+		rangeProof := withdrawProof.rangeProof
+		after, y, z, x := rangeTranscript(trans, rangeProof.A, rangeProof.S, rangeProof.T1, rangeProof.T2)
+		final, c := UpdateTranscript(after, withdrawProof.ad, withdrawProof.ay, withdrawProof.ag)
+		vector.setChallenges(after, final, y, z, x, c)
+		proof = withdrawProof
+	case VectorBurn:
+		burnProof := sender.GenBurnProof()
+		vector.Amount = balance
+		vector.Challenges["c"] = scalarToHex(commitmentChallenge(burnProof.ay, burnProof.acr))
+		proof = &burnProof
+	case VectorRange:
+		//Cl = v*G + sk*Cr, so the sender commitment is a pedersen commitment over G and Cr
+		pedComm := ElgamalCommitment{
+			g:     sender.basePoint,
+			h:     comm.Cr,
+			v:     v,
+			gamma: sender.sk,
+			comm:  comm.Cl,
+		}
+		_, rangeProof, err := sender.rangeProver.GenRangeProof(trans, pedComm)
+		if err != nil {
+			return vector, err
+		}
+		vector.Amount = balance
+		after, y, z, x := rangeTranscript(trans, rangeProof.A, rangeProof.S, rangeProof.T1, rangeProof.T2)
+		vector.Transcripts["range"] = hex.EncodeToString(after[:])
+		vector.Challenges["y"] = scalarToHex(y)
+		vector.Challenges["z"] = scalarToHex(z)
+		vector.Challenges["x"] = scalarToHex(x)
+		proof = rangeProof
+	default:
+		return vector, errors.New("unknown vector " + name)
+	}
+	vector.Proof = hex.EncodeToString(proof.Serialize())
+	vector.ProofCompact = hex.EncodeToString(proof.SerializeCompact())
+	return vector, nil
+}
+
+func (vector *TestVector) setChallenges(after, final [64]byte, y, z, x, c *ristretto255.Scalar) {
+	vector.Transcripts["range"] = hex.EncodeToString(after[:])
+	vector.Transcripts["final"] = hex.EncodeToString(final[:])
+	vector.Challenges["y"] = scalarToHex(y)
+	vector.Challenges["z"] = scalarToHex(z)
+	vector.Challenges["x"] = scalarToHex(x)
+	vector.Challenges["c"] = scalarToHex(c)
+}
+
+//rangeTranscript replays the verifier transcript of a (sigma) range proof up to the inner product proof
+func rangeTranscript(trans [64]byte, a, s, t1, t2 *ristretto255.Element) ([64]byte, *ristretto255.Scalar, *ristretto255.Scalar, *ristretto255.Scalar) {
+	trans, y := UpdateTranscript(trans, a, s)
+	trans, z := UpdateTranscript(trans, a, s)
+	trans, x := UpdateTranscript(trans, t1, t2)
+	trans, _ = UpdateTranscript(trans, t1, t2)
+	return trans, y, z, x
+}
+
+func commitmentChallenge(ay, acr *ristretto255.Element) *ristretto255.Scalar {
+	seed := sha256.Sum256(append(ay.Encode(nil), acr.Encode(nil)...))
+	transcript := NewXofExpend(64, seed)
+	buf := make([]byte, 64)
+	transcript.Read(buf)
+	return new(ristretto255.Scalar).FromUniformBytes(buf)
+}
+
+//CheckTestVectors regenerates every vector from its seeds, compares the result byte by byte,
+//and verifies the recorded proof bytes against a fresh SmartContract.
+func CheckTestVectors(vectors []TestVector) error {
+	for _, vector := range vectors {
+		if err := checkTestVector(vector); err != nil {
+			return errors.New(vector.Name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func checkTestVector(vector TestVector) error {
+	senderSeed, err := fixedFromHex(vector.SenderSeed)
+	if err != nil {
+		return err
+	}
+	var receiverSeed [32]byte
+	if vector.ReceiverSeed != "" {
+		if receiverSeed, err = fixedFromHex(vector.ReceiverSeed); err != nil {
+			return err
+		}
+	}
+	regenerated, err := generateTestVector(vector.Name, senderSeed, receiverSeed, vector.Balance, vector.Amount)
+	if err != nil {
+		return err
+	}
+	if regenerated.Proof != vector.Proof || regenerated.ProofCompact != vector.ProofCompact {
+		return errors.New("regenerated proof differs")
+	}
+	if regenerated.SenderPk != vector.SenderPk || regenerated.SenderSk != vector.SenderSk {
+		return errors.New("regenerated keys differ")
+	}
+	for k, c := range regenerated.Challenges {
+		if vector.Challenges[k] != c {
+			return errors.New("challenge " + k + " differs")
+		}
+	}
+	for k, trans := range regenerated.Transcripts {
+		if vector.Transcripts[k] != trans {
+			return errors.New("transcript " + k + " differs")
+		}
+	}
+
+	proofBytes, err := hex.DecodeString(vector.Proof)
+	if err != nil {
+		return err
+	}
+	compactBytes, err := hex.DecodeString(vector.ProofCompact)
+	if err != nil {
+		return err
+	}
+	transBytes, err := hex.DecodeString(vector.Transcript)
+	if err != nil {
+		return err
+	}
+	var trans [64]byte
+	copy(trans[:], transBytes)
+	senderPk, err := elementFromHex(vector.SenderPk)
+	if err != nil {
+		return err
+	}
+	var ledger SmartContract
+	ledger.Init()
+	if vector.SenderComm == nil {
+		return errors.New("missing sender commitment")
+	}
+	ledger.Register(senderPk, vector.SenderComm)
+
+	proof, err := newProof(map[string]ProofKind{
+		VectorTransfer: KindTransferProof,
+		VectorWithdraw: KindWithdrawProof,
+		VectorBurn:     KindCommitmentProof,
+		VectorRange:    KindRangeProof,
+	}[vector.Name])
+	if err != nil {
+		return err
+	}
+	if err := proof.Deserialize(proofBytes); err != nil {
+		return err
+	}
+	if !bytes.Equal(proof.SerializeCompact(), compactBytes) {
+		return errors.New("compact encoding differs")
+	}
+
+	var ok bool
+	switch p := proof.(type) {
+	case *TransferProof:
+		receiverPk, err := elementFromHex(vector.ReceiverPk)
+		if err != nil {
+			return err
+		}
+		ledger.Register(receiverPk, vector.ReceiverComm)
+		ok = ledger.VerifyTransferProof(trans, p, senderPk, receiverPk)
+	case *WithdrawProof:
+		ok = ledger.VerifyWithDrawProof(trans, senderPk, vector.Amount, p)
+	case *CommitmentProof:
+		ok = ledger.VerifyBurnProof(senderPk, *p)
+	case *RangeProof:
+		_, ok = ledger.rangeProver.VerifyRangeProof(trans, p, vector.SenderComm.Cl)
+	}
+	if !ok {
+		return errors.New("proof does not verify")
+	}
+	return nil
+}
@@ -75,8 +75,10 @@ func (self *ZeroCopySink) WriteUint8(data uint8) {
 	buf[0] = data
 }
 
-func (self *ZeroCopySink) WriteByte(c byte) {
+// WriteByte implements the io.ByteWriter interface.
+func (self *ZeroCopySink) WriteByte(c byte) error {
 	self.WriteUint8(c)
+	return nil
 }
 
 func (self *ZeroCopySink) WriteBool(data bool) {
@@ -159,6 +161,18 @@ func (self *ZeroCopySink) WriteElement(e *ristretto255.Element) {
 	self.WriteVarBytes(b[:])
 }
 
+//WriteFixedScalar writes the 32 bytes encoding without a length prefix
+func (self *ZeroCopySink) WriteFixedScalar(s *ristretto255.Scalar) {
+	b := ScalarToBytes(s)
+	self.WriteBytes(b[:])
+}
+
+//WriteFixedElement writes the 32 bytes encoding without a length prefix
+func (self *ZeroCopySink) WriteFixedElement(e *ristretto255.Element) {
+	b := ElementToBytes(e)
+	self.WriteBytes(b[:])
+}
+
 // NewReader returns a new ZeroCopySink reading from b.
 func NewZeroCopySink(b []byte) *ZeroCopySink {
 	if b == nil {
@@ -338,9 +352,12 @@ func (self *ZeroCopySource) NextScalar() (*ristretto255.Scalar, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(b) != 32 {
+		return nil, ErrInvalidScalar
+	}
 	var buf [32]byte
 	copy(buf[:], b)
-	return ScalarFromBytes(buf), nil
+	return ScalarFromBytes(buf)
 }
 
 func (self *ZeroCopySource) NextElement() (*ristretto255.Element, error) {
@@ -348,9 +365,32 @@ func (self *ZeroCopySource) NextElement() (*ristretto255.Element, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(b) != 32 {
+		return nil, ErrInvalidElement
+	}
+	var buf [32]byte
+	copy(buf[:], b)
+	return ElementFromBytes(buf)
+}
+
+func (self *ZeroCopySource) NextFixedScalar() (*ristretto255.Scalar, error) {
+	b, eof := self.NextBytes(32)
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	var buf [32]byte
+	copy(buf[:], b)
+	return ScalarFromBytes(buf)
+}
+
+func (self *ZeroCopySource) NextFixedElement() (*ristretto255.Element, error) {
+	b, eof := self.NextBytes(32)
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
 	var buf [32]byte
 	copy(buf[:], b)
-	return ElementFromBytes(buf), nil
+	return ElementFromBytes(buf)
 }
 
 func (self *ZeroCopySource) NextString() (data string, size uint64, irregular bool, eof bool) {
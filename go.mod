@@ -5,5 +5,7 @@ go 1.14
 require (
 	github.com/Evanesco-Labs/ristretto255 v0.1.3-0.20210329031646-0877656ce61a
 	github.com/magiconair/properties v1.8.4
+	github.com/tyler-smith/go-bip39 v1.1.0
 	golang.org/x/crypto v0.0.0-20210314154223-e6e6c4f2bb5b
+	google.golang.org/protobuf v1.33.0
 )